@@ -4,7 +4,8 @@ $headers = @{
     "ce-specversion" = "1.0";
     "ce-id" = "166419";
     "ce-source" = "https://hz-01.vmware.corp";
-    "ce-type" = "com.vmware.event.router/horizon.vlsi_userlogin_rest_failed.v0";
+    "ce-type" = "com.vmware.event.router/horizon";
+    "ce-subject" = "VLSI_USERLOGIN_REST_FAILED";
     "ce-time" = "2021-09-03T16:00:28Z";
 }
 
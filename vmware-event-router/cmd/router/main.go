@@ -6,13 +6,20 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
 	"knative.dev/pkg/signals"
 
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/generator"
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/horizon"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/kubernetes"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/nats"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/replay"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/snmp"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/syslog"
 
 	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
@@ -24,6 +31,9 @@ import (
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/vcenter"
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/vcsim"
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/webhook"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/record"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/reply"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/simulator"
 )
 
 var (
@@ -51,11 +61,15 @@ func main() {
 		configPath string
 		logLevel   string
 		logJSON    bool
+		simulate   bool
+		simulateIv time.Duration
 	)
 
 	flag.StringVar(&configPath, "config", defaultConfigPath, "path to configuration file")
 	flag.StringVar(&logLevel, "log-level", "info", "set log level (debug,info,warn,error)")
 	flag.BoolVar(&logJSON, "log-json", false, "print JSON-formatted logs")
+	flag.BoolVar(&simulate, "simulate", false, "use an embedded vCenter simulator running an event scenario as event provider")
+	flag.DurationVar(&simulateIv, "simulate-interval", 5*time.Second, "interval between simulator scenario steps")
 	flag.Usage = func() {
 		fmt.Printf("Usage of %s:\n\n", os.Args[0])
 		flag.PrintDefaults()
@@ -103,6 +117,22 @@ func main() {
 
 	ctx := signals.NewContext()
 
+	var sim *simulator.Simulator
+	if simulate {
+		sim, err = simulator.New(logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not start vCenter simulator: %v", err)
+		}
+		defer sim.Close()
+
+		// replaces the configured event provider
+		cfg.EventProvider = config.Provider{
+			Type:    config.ProviderVCenter,
+			Name:    "vcenter-simulator",
+			VCenter: sim.ProviderConfig(""),
+		}
+	}
+
 	// set up event provider
 	switch cfg.EventProvider.Type {
 	case config.ProviderVCenter:
@@ -127,7 +157,55 @@ func main() {
 			log.Fatalf("could not connect to Horizon API server: %v", err)
 		}
 
-		log.Infow("connected to Horizon API server", "address", cfg.EventProvider.Horizon.Address)
+		log.Infow("connected to Horizon API server", "address", cfg.EventProvider.Horizon.Address, "addresses", cfg.EventProvider.Horizon.Addresses)
+
+	case config.ProviderSyslog:
+		prov, err = syslog.NewServer(ctx, cfg.EventProvider.Syslog, ms, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create syslog server: %v", err)
+		}
+
+		log.Infow("starting syslog listener", "address", prov.(*syslog.Server).Address(), "protocol", cfg.EventProvider.Syslog.Protocol)
+
+	case config.ProviderSNMP:
+		prov, err = snmp.NewServer(ctx, cfg.EventProvider.SNMP, ms, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create snmp trap receiver: %v", err)
+		}
+
+		log.Infow("starting snmp trap listener", "address", prov.(*snmp.Server).Address())
+
+	case config.ProviderReplay:
+		prov, err = replay.NewReplayer(ctx, cfg.EventProvider.Replay, ms, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create replay provider: %v", err)
+		}
+
+		log.Infow("replaying events from files", "path", cfg.EventProvider.Replay.Path, "files", len(prov.(*replay.Replayer).Files()))
+
+	case config.ProviderGenerator:
+		prov, err = generator.NewGenerator(ctx, cfg.EventProvider.Generator, ms, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create event generator: %v", err)
+		}
+
+		log.Infow("generating synthetic events", "rate", cfg.EventProvider.Generator.Rate)
+
+	case config.ProviderKubernetes:
+		prov, err = kubernetes.NewEventStream(ctx, cfg.EventProvider.Kubernetes, ms, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create Kubernetes event provider: %v", err)
+		}
+
+		log.Infow("watching Kubernetes events", "address", prov.(*kubernetes.EventStream).Source())
+
+	case config.ProviderNATS:
+		prov, err = nats.NewConsumer(ctx, cfg.EventProvider.NATS, ms, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not connect to NATS: %v", err)
+		}
+
+		log.Infow("connected to NATS server", "address", prov.(*nats.Consumer).Address(), "subjects", cfg.EventProvider.NATS.Subjects)
 
 	case config.ProviderVCSIM:
 		log.Warn("%s is deprecated and will be removed in future versions", config.ProviderVCSIM)
@@ -142,10 +220,26 @@ func main() {
 		log.Fatalf("invalid type specified: %q", cfg.EventProvider.Type)
 	}
 
+	// feed reply events of the event processor back into the router
+	var (
+		replies     *reply.Router
+		ofOpts      []openfaas.Option
+		knativeOpts []knative.Option
+	)
+	if cfg.Replies != nil {
+		replies, err = reply.NewRouter(cfg.Replies, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create reply router: %v", err)
+		}
+
+		ofOpts = append(ofOpts, openfaas.WithReplyHandler(replies.Reply))
+		knativeOpts = append(knativeOpts, knative.WithReplyHandler(replies.Reply))
+	}
+
 	// set up event processor
 	switch cfg.EventProcessor.Type {
 	case config.ProcessorOpenFaaS:
-		proc, err = openfaas.NewProcessor(ctx, cfg.EventProcessor.OpenFaaS, ms, logger.Sugar())
+		proc, err = openfaas.NewProcessor(ctx, cfg.EventProcessor.OpenFaaS, ms, logger.Sugar(), ofOpts...)
 		if err != nil {
 			log.Fatalf("could not connect to OpenFaaS: %v", err)
 		}
@@ -153,6 +247,10 @@ func main() {
 		log.Infow("connected to OpenFaaS gateway", "address", cfg.EventProcessor.OpenFaaS.Address, "async", cfg.EventProcessor.OpenFaaS.Async)
 
 	case config.ProcessorEventBridge:
+		if replies != nil {
+			log.Fatalf("reply events are not supported by the %s processor", config.ProcessorEventBridge)
+		}
+
 		proc, err = aws.NewEventBridgeProcessor(ctx, cfg.EventProcessor.EventBridge, ms, logger.Sugar())
 		if err != nil {
 			log.Fatalf("could not connect to AWS EventBridge: %v", err)
@@ -161,7 +259,7 @@ func main() {
 		log.Infow("connected to AWS EventBridge", "ruleARN", cfg.EventProcessor.EventBridge.RuleARN)
 
 	case config.ProcessorKnative:
-		proc, err = knative.NewProcessor(ctx, cfg.EventProcessor.Knative, ms, logger.Sugar())
+		proc, err = knative.NewProcessor(ctx, cfg.EventProcessor.Knative, ms, logger.Sugar(), knativeOpts...)
 		if err != nil {
 			log.Fatalf("could not create Knative processor: %v", err)
 		}
@@ -172,6 +270,22 @@ func main() {
 		log.Fatalf("invalid type specified: %q", cfg.EventProcessor.Type)
 	}
 
+	// record events emitted by the event provider
+	if cfg.Record != nil {
+		proc, err = record.NewRecorder(cfg.Record, proc, logger.Sugar())
+		if err != nil {
+			log.Fatalf("could not create event recorder: %v", err)
+		}
+
+		log.Infow("recording events", "dir", cfg.Record.Dir)
+	}
+
+	// reply events are processed like events emitted by the event provider
+	if replies != nil {
+		replies.Forward(proc)
+		log.Infow("feeding reply events back into the router", "maxHops", replies.MaxHops())
+	}
+
 	// set up metrics provider (only supporting default for now)
 	switch cfg.MetricsProvider.Type {
 	case config.MetricsProviderDefault:
@@ -206,6 +320,20 @@ func main() {
 		return prov.Stream(egCtx, proc)
 	})
 
+	// reply events
+	if replies != nil {
+		eg.Go(func() error {
+			return replies.Run(egCtx)
+		})
+	}
+
+	// simulator scenario
+	if sim != nil {
+		eg.Go(func() error {
+			return sim.Run(egCtx, simulateIv)
+		})
+	}
+
 	// shutdown handling
 	eg.Go(func() error {
 		<-egCtx.Done()
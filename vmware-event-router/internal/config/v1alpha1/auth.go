@@ -10,18 +10,23 @@ const (
 	AWSAccessKeyAuth AuthMethodType = "aws_access_key"
 	// 	ActiveDirectory represents the MS Active Directory domain/user/password scheme
 	ActiveDirectory AuthMethodType = "active_directory"
+	// HMACSignatureAuth represents request signature verification using a shared
+	// secret (HMAC)
+	HMACSignatureAuth AuthMethodType = "hmac_signature"
 )
 
 // AuthMethod configures authentication data
 type AuthMethod struct {
 	// Type sets the authentication method
-	Type AuthMethodType `yaml:"type" json:"type" jsonschema:"enum=basic_auth,enum=aws_access_key,enum=active_directory,default=basic_auth,description=The authentication method to use"`
+	Type AuthMethodType `yaml:"type" json:"type" jsonschema:"enum=basic_auth,enum=aws_access_key,enum=active_directory,enum=hmac_signature,default=basic_auth,description=The authentication method to use"`
 	// +optional
 	BasicAuth *BasicAuthMethod `yaml:"basicAuth,omitempty" json:"basicAuth,omitempty" jsonschema:"oneof_required=basicAuth,description=Basic authentication with username and password"`
 	// +optional
 	AWSAccessKeyAuth *AWSAccessKeyAuthMethod `yaml:"awsAccessKeyAuth,omitempty" json:"awsAccessKeyAuth,omitempty" jsonschema:"oneof_required=awsAccessKeyAuth,description=AWS authentication with access and secret key"`
 	// +optional
 	ActiveDirectoryAuth *ActiveDirectoryAuthMethod `yaml:"activeDirectoryAuth,omitempty" json:"activeDirectoryAuth,omitempty" jsonschema:"oneof_required=activeDirectoryAuth,description=Active Directory authentication with domain, username and password"`
+	// +optional
+	HMACSignatureAuth *HMACSignatureAuthMethod `yaml:"hmacSignatureAuth,omitempty" json:"hmacSignatureAuth,omitempty" jsonschema:"oneof_required=hmacSignatureAuth,description=Request signature verification using a shared secret (HMAC)"`
 }
 
 // BasicAuthMethod configures authentication data for basic_auth
@@ -43,3 +48,34 @@ type ActiveDirectoryAuthMethod struct {
 	Username string `yaml:"username" json:"username" jsonschema:"required"`
 	Password string `yaml:"password" json:"password" jsonschema:"required"`
 }
+
+// HMACSignatureAuthMethod configures request signature verification for
+// hmac_signature. By default the signature is computed over the request
+// timestamp and the request body joined by ".", e.g. "1629200000.{...}", and
+// requests outside the tolerance window are rejected. If replay protection is
+// disabled the signature is computed over the request body only.
+type HMACSignatureAuthMethod struct {
+	// Header is the HTTP header containing the hex-encoded signature. An optional
+	// algorithm prefix, e.g. "sha256=", is ignored (defaults to X-Signature)
+	// +optional
+	Header string `yaml:"header,omitempty" json:"header,omitempty" jsonschema:"description=HTTP header containing the hex-encoded signature,default=X-Signature"`
+	// Algorithm is the HMAC hash algorithm (defaults to sha256)
+	// +optional
+	Algorithm string `yaml:"algorithm,omitempty" json:"algorithm,omitempty" jsonschema:"enum=sha256,enum=sha512,default=sha256"`
+	// Secret is the shared secret used to sign requests
+	Secret string `yaml:"secret" json:"secret" jsonschema:"required"`
+	// TimestampHeader is the HTTP header containing the request timestamp (seconds
+	// since unix epoch) used to reject replayed requests (defaults to
+	// X-Signature-Timestamp)
+	// +optional
+	TimestampHeader string `yaml:"timestampHeader,omitempty" json:"timestampHeader,omitempty" jsonschema:"description=HTTP header containing the request timestamp (seconds since unix epoch),default=X-Signature-Timestamp"`
+	// ToleranceSeconds is the maximum allowed difference between the request
+	// timestamp and the current time
+	// +optional
+	ToleranceSeconds int `yaml:"toleranceSeconds,omitempty" json:"toleranceSeconds,omitempty" jsonschema:"description=Maximum allowed difference in seconds between request timestamp and current time,default=300"`
+	// DisableReplayProtection verifies the signature over the request body only
+	// without a timestamp for senders which do not sign a timestamp, e.g.
+	// GitHub. Signed requests can be replayed in this mode.
+	// +optional
+	DisableReplayProtection bool `yaml:"disableReplayProtection,omitempty" json:"disableReplayProtection,omitempty" jsonschema:"description=Verify the signature over the request body only without timestamp (allows replayed requests),default=false"`
+}
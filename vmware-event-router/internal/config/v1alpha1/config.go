@@ -45,6 +45,14 @@ type RouterConfig struct {
 	// Certificates contains configuration information to define certificates. This
 	// section is currently only used by the vCenter event provider.
 	Certificates Certificates `yaml:"certificates,omitempty" json:"certificates,omitempty" jsonschema:""`
+	// Record enables recording of all events emitted by the event provider
+	// (optional)
+	// +optional
+	Record *Record `yaml:"record,omitempty" json:"record,omitempty" jsonschema:"description=Record all events emitted by the event provider into JSONL files"`
+	// Replies enables feeding reply events returned by the sinks of the event
+	// processor back into the router (optional)
+	// +optional
+	Replies *Replies `yaml:"replies,omitempty" json:"replies,omitempty" jsonschema:"description=Feed reply events returned by event processor sinks back into the router"`
 }
 
 // Parse parses a given configuration and returns a RouterConfig
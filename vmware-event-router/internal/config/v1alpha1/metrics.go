@@ -26,4 +26,7 @@ type MetricsProviderConfigDefault struct {
 	// metrics provider. Only basic_auth is supported.
 	// +optional
 	Auth *AuthMethod `yaml:"auth,omitempty" json:"auth,omitempty" jsonschema:"description=Authentication configuration for this section"`
+	// TLS enables TLS for the http endpoint of the metrics provider
+	// +optional
+	TLS *TLSConfig `yaml:"tls,omitempty" json:"tls,omitempty" jsonschema:"description=TLS configuration for the metrics http endpoint"`
 }
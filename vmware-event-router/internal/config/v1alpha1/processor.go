@@ -45,6 +45,105 @@ type ProcessorConfigOpenFaaS struct {
 	// Auth sets the OpenFaaS authentication credentials (optional). Only basic_auth
 	// is supported. +optional
 	Auth *AuthMethod `yaml:"auth,omitempty" json:"auth,omitempty" jsonschema:"description=Authentication configuration for this section"`
+	// Encoding sets the cloud event encoding type of function invocations
+	// (defaults to structured, i.e. the JSON-encoded event as body)
+	// +optional
+	Encoding string `yaml:"encoding,omitempty" json:"encoding,omitempty" jsonschema:"enum=structured,enum=binary,description=CloudEvent encoding of function invocations,default=structured"`
+	// Retry configures retries of failed synchronous function invocations
+	// (optional). Defaults to 3 retries with exponential backoff between 1s and
+	// 5s. +optional
+	Retry *OpenFaaSRetry `yaml:"retry,omitempty" json:"retry,omitempty" jsonschema:"description=Retry configuration for failed function invocations"`
+	// Callback configures a receiver for the results of asynchronous function
+	// invocations (optional). Requires async mode. +optional
+	Callback *OpenFaaSCallback `yaml:"callback,omitempty" json:"callback,omitempty" jsonschema:"description=Callback receiver configuration for async function invocations"`
+	// DrainTimeoutSeconds is the time to wait for inflight function
+	// invocations to finish during shutdown before they are aborted (defaults
+	// to 5). +optional
+	DrainTimeoutSeconds int `yaml:"drainTimeoutSeconds,omitempty" json:"drainTimeoutSeconds,omitempty" jsonschema:"description=Time in seconds to wait for inflight function invocations during shutdown,default=5"`
+	// Functions configures concurrency limits and timeouts of individual
+	// functions (optional). Settings take precedence over the limits set with
+	// function annotations. Requires sync mode. +optional
+	Functions []OpenFaaSFunction `yaml:"functions,omitempty" json:"functions,omitempty" jsonschema:"description=Concurrency limits and timeouts of individual functions"`
+}
+
+// OpenFaaSQueuePolicy defines how function invocations exceeding the
+// concurrency limit of a function are handled
+type OpenFaaSQueuePolicy string
+
+const (
+	// QueuePolicyWait waits until an inflight invocation of the function has
+	// finished
+	QueuePolicyWait OpenFaaSQueuePolicy = "wait"
+	// QueuePolicyReject fails the invocation without retries
+	QueuePolicyReject OpenFaaSQueuePolicy = "reject"
+)
+
+// OpenFaaSFunction configures the concurrency limit and timeout of an OpenFaaS
+// function
+type OpenFaaSFunction struct {
+	// Name is the name of the function. Functions in a namespace are named
+	// "<function>.<namespace>".
+	Name string `yaml:"name" json:"name" jsonschema:"required,description=Function name (<function>.<namespace> for functions in a namespace),default=my-function"`
+	// MaxConcurrency is the maximum number of concurrent invocations of the
+	// function (defaults to 0, i.e. unlimited)
+	// +optional
+	MaxConcurrency int `yaml:"maxConcurrency,omitempty" json:"maxConcurrency,omitempty" jsonschema:"description=Maximum number of concurrent invocations (0 is unlimited),default=0"`
+	// TimeoutSeconds is the timeout of an invocation of the function
+	// (defaults to 15)
+	// +optional
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty" json:"timeoutSeconds,omitempty" jsonschema:"description=Timeout of a function invocation in seconds,default=15"`
+	// QueuePolicy defines how invocations exceeding MaxConcurrency are handled
+	// (defaults to wait)
+	// +optional
+	QueuePolicy OpenFaaSQueuePolicy `yaml:"queuePolicy,omitempty" json:"queuePolicy,omitempty" jsonschema:"enum=wait,enum=reject,description=Wait for or reject invocations exceeding the concurrency limit,default=wait"`
+}
+
+// OpenFaaSCallback configures the receiver for the results of asynchronous
+// OpenFaaS function invocations which are reported by the OpenFaaS queue
+// worker via the X-Callback-Url of an invocation
+type OpenFaaSCallback struct {
+	// BindAddress is the address where the callback receiver listens
+	BindAddress string `yaml:"bindAddress" json:"bindAddress" jsonschema:"required,description=TCP/IP socket and port to listen on for callbacks,default=0.0.0.0:8081"`
+	// URL is the callback URL passed to OpenFaaS which must be reachable by
+	// the OpenFaaS queue worker
+	URL string `yaml:"url" json:"url" jsonschema:"required,description=Callback URL passed to OpenFaaS,default=http://vmware-event-router.vmware:8081/callback"`
+	// TimeoutSeconds is the time after which an invocation without callback
+	// is considered failed (defaults to 300)
+	// +optional
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty" json:"timeoutSeconds,omitempty" jsonschema:"description=Time in seconds after which an invocation without callback is considered failed,default=300"`
+	// Retry enables retries of failed asynchronous invocations using the
+	// configured retry policy
+	// +optional
+	Retry bool `yaml:"retry,omitempty" json:"retry,omitempty" jsonschema:"description=Retry failed async function invocations using the retry policy,default=false"`
+}
+
+// OpenFaaSRetry configures retries of failed OpenFaaS function invocations
+type OpenFaaSRetry struct {
+	// Attempts is the maximum number of retries per function invocation. Set
+	// to 0 to disable retries (defaults to 3)
+	// +optional
+	Attempts *int `yaml:"attempts,omitempty" json:"attempts,omitempty" jsonschema:"description=Maximum number of retries per function invocation (0 disables retries),default=3"`
+	// DelayMilliseconds is the initial delay between retries which is doubled
+	// with every retry (defaults to 1000)
+	// +optional
+	DelayMilliseconds int `yaml:"delayMilliseconds,omitempty" json:"delayMilliseconds,omitempty" jsonschema:"description=Initial delay between retries in milliseconds,default=1000"`
+	// MaxDelayMilliseconds is the maximum backoff delay between retries,
+	// including jitter. Longer Retry-After delays are honored unless they
+	// exceed the maximum delay of all remaining retries (defaults to 5000)
+	// +optional
+	MaxDelayMilliseconds int `yaml:"maxDelayMilliseconds,omitempty" json:"maxDelayMilliseconds,omitempty" jsonschema:"description=Maximum delay between retries in milliseconds,default=5000"`
+	// JitterMilliseconds is the maximum random delay added to every retry
+	// delay (defaults to 0)
+	// +optional
+	JitterMilliseconds int `yaml:"jitterMilliseconds,omitempty" json:"jitterMilliseconds,omitempty" jsonschema:"description=Maximum random jitter added to the delay between retries in milliseconds,default=0"`
+	// IgnoreRetryAfter disables using the Retry-After response header as the
+	// delay before the next retry
+	// +optional
+	IgnoreRetryAfter bool `yaml:"ignoreRetryAfter,omitempty" json:"ignoreRetryAfter,omitempty" jsonschema:"description=Do not use the Retry-After response header as delay before the next retry,default=false"`
+	// StatusCodes are the HTTP response status codes which are retried. If
+	// empty, 429 and 5xx (except 501) status codes are retried
+	// +optional
+	StatusCodes []int `yaml:"statusCodes,omitempty" json:"statusCodes,omitempty" jsonschema:"description=Retryable HTTP response status codes (defaults to 429 and 5xx except 501)"`
 }
 
 // ProcessorConfigEventBridge configures the AWS Event Bridge event processor
@@ -72,4 +171,9 @@ type ProcessorConfigKnative struct {
 	// Auth sets the Knative authentication credentials for the specified destination
 	// +optional
 	Auth *AuthMethod `yaml:"auth,omitempty" json:"auth,omitempty" jsonschema:"description=Authentication configuration for this section"`
+	// Kubeconfig is the path to a kubeconfig file used to resolve a Ref
+	// destination (defaults to the in-cluster configuration). Not used if the
+	// destination is a URI.
+	// +optional
+	Kubeconfig string `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty" jsonschema:"description=Path to a kubeconfig file to resolve destination references (in-cluster configuration if empty)"`
 }
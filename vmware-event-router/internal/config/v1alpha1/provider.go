@@ -10,16 +10,22 @@ type ProviderType string
 
 const (
 	// ProviderVCenter represents the vCenter event provider
-	ProviderVCenter ProviderType = "vcenter"
-	ProviderVCSIM   ProviderType = "vcsim"
-	ProviderWebhook ProviderType = "webhook"
-	ProviderHorizon ProviderType = "horizon"
+	ProviderVCenter    ProviderType = "vcenter"
+	ProviderVCSIM      ProviderType = "vcsim"
+	ProviderWebhook    ProviderType = "webhook"
+	ProviderHorizon    ProviderType = "horizon"
+	ProviderSyslog     ProviderType = "syslog"
+	ProviderSNMP       ProviderType = "snmp"
+	ProviderReplay     ProviderType = "replay"
+	ProviderGenerator  ProviderType = "generator"
+	ProviderKubernetes ProviderType = "kubernetes"
+	ProviderNATS       ProviderType = "nats"
 )
 
 // Provider configures the event provider
 type Provider struct {
 	// Type sets the event provider
-	Type ProviderType `yaml:"type" json:"type" jsonschema:"enum=vcenter,enum=webhook,enum=vcsim,enum=horizon"`
+	Type ProviderType `yaml:"type" json:"type" jsonschema:"enum=vcenter,enum=webhook,enum=vcsim,enum=horizon,enum=syslog,enum=snmp,enum=replay,enum=generator,enum=kubernetes,enum=nats"`
 	// Name is an identifier for the configured event provider
 	Name string `yaml:"name" json:"name" jsonschema:"required"`
 	// VCenter configuration settings
@@ -35,6 +41,24 @@ type Provider struct {
 	// 	Horizon configuration settings
 	// +optional
 	Horizon *ProviderConfigHorizon `yaml:"horizon,omitempty" json:"horizon,omitempty" jsonschema:"oneof_required=horizon"`
+	// Syslog configuration settings
+	// +optional
+	Syslog *ProviderConfigSyslog `yaml:"syslog,omitempty" json:"syslog,omitempty" jsonschema:"oneof_required=syslog"`
+	// SNMP trap receiver configuration settings
+	// +optional
+	SNMP *ProviderConfigSNMP `yaml:"snmp,omitempty" json:"snmp,omitempty" jsonschema:"oneof_required=snmp"`
+	// Replay configuration settings
+	// +optional
+	Replay *ProviderConfigReplay `yaml:"replay,omitempty" json:"replay,omitempty" jsonschema:"oneof_required=replay"`
+	// Synthetic event generator configuration settings
+	// +optional
+	Generator *ProviderConfigGenerator `yaml:"generator,omitempty" json:"generator,omitempty" jsonschema:"oneof_required=generator"`
+	// Kubernetes events configuration settings
+	// +optional
+	Kubernetes *ProviderConfigKubernetes `yaml:"kubernetes,omitempty" json:"kubernetes,omitempty" jsonschema:"oneof_required=kubernetes"`
+	// NATS configuration settings
+	// +optional
+	NATS *ProviderConfigNATS `yaml:"nats,omitempty" json:"nats,omitempty" jsonschema:"oneof_required=nats"`
 }
 
 // ProviderConfigVCenter configures the vCenter event provider
@@ -71,18 +95,413 @@ type ProviderConfigWebhook struct {
 	// Path is the relative URL path to accept incoming webhook CloudEvents
 	Path string `yaml:"path" json:"path" jsonschema:"required,default=/webhook"`
 	// Auth sets the webhook authentication credentials for incoming requests
-	// (optional). Only basic_auth is supported
+	// (optional). Only basic_auth and hmac_signature are supported
 	Auth *AuthMethod `yaml:"auth,omitempty" json:"auth,omitempty" jsonschema:"description=Authentication configuration for this section"`
-	// 	TODO: concurrency (goroutines), inbound/outbound rate limit
+	// TLS enables TLS for the webhook http server (optional)
+	// +optional
+	TLS *TLSConfig `yaml:"tls,omitempty" json:"tls,omitempty" jsonschema:"description=TLS configuration for the webhook http server"`
+	// JSONMapping enables an additional endpoint accepting arbitrary JSON
+	// payloads which are converted into CloudEvents using the configured mapping
+	// rules (optional)
+	// +optional
+	JSONMapping *WebhookJSONMapping `yaml:"jsonMapping,omitempty" json:"jsonMapping,omitempty" jsonschema:"description=Accept arbitrary JSON payloads and map them into CloudEvents"`
+	// PollConcurrency is the number of goroutines processing incoming events
+	// (defaults to 1)
+	// +optional
+	PollConcurrency int `yaml:"pollConcurrency,omitempty" json:"pollConcurrency,omitempty" jsonschema:"description=Number of goroutines processing incoming events,default=1"`
+	// AllowedRate is the request rate per minute advertised to senders in the
+	// WebHook-Allowed-Rate header of OPTIONS responses (defaults to 1000)
+	// +optional
+	AllowedRate int `yaml:"allowedRate,omitempty" json:"allowedRate,omitempty" jsonschema:"description=Request rate per minute advertised to senders in OPTIONS responses,default=1000"`
+	// RateLimit enforces a request rate limit per client (optional)
+	// +optional
+	RateLimit *WebhookRateLimit `yaml:"rateLimit,omitempty" json:"rateLimit,omitempty" jsonschema:"description=Request rate limit per client"`
+	// MaxBodyBytes is the maximum accepted request body size in bytes. Requests
+	// with larger bodies are rejected. 0 disables the limit.
+	// +optional
+	MaxBodyBytes int64 `yaml:"maxBodyBytes,omitempty" json:"maxBodyBytes,omitempty" jsonschema:"description=Maximum accepted request body size in bytes (0 disables the limit),default=0"`
+	// Async enables asynchronous processing. Incoming events are acknowledged
+	// with 202 once persisted to a queue directory and processed in the
+	// background (optional)
+	// +optional
+	Async *WebhookAsync `yaml:"async,omitempty" json:"async,omitempty" jsonschema:"description=Acknowledge events once queued and process them in the background"`
+}
+
+// WebhookAsync configures asynchronous processing of incoming events
+type WebhookAsync struct {
+	// QueueDir is the directory where queued events are persisted until they
+	// have been processed. Pending events are processed after a restart.
+	QueueDir string `yaml:"queueDir" json:"queueDir" jsonschema:"required,default=./queue"`
+	// MaxQueueSize is the maximum number of queued events. Requests are rejected
+	// with 503 if the queue is full (defaults to 1000)
+	// +optional
+	MaxQueueSize int `yaml:"maxQueueSize,omitempty" json:"maxQueueSize,omitempty" jsonschema:"description=Maximum number of queued events,default=1000"`
+	// Workers is the number of goroutines processing queued events (defaults
+	// to 1). Events are processed in order only with a single worker.
+	// +optional
+	Workers int `yaml:"workers,omitempty" json:"workers,omitempty" jsonschema:"description=Number of goroutines processing queued events,default=1"`
+	// StatusPath is the relative URL path to query the delivery status of an
+	// event by its ID and optional source, e.g. GET
+	// /webhook/status/<id>?source=<source>
+	// +optional
+	StatusPath string `yaml:"statusPath,omitempty" json:"statusPath,omitempty" jsonschema:"description=Path to query the delivery status of an event by ID,default=/webhook/status"`
+}
+
+// WebhookRateLimit configures a token bucket rate limiter per client. Requests
+// are limited before authentication. Clients are identified by their basic
+// auth user if valid credentials are provided and by their IP address
+// otherwise.
+type WebhookRateLimit struct {
+	// RequestsPerSecond is the sustained request rate per client
+	RequestsPerSecond float64 `yaml:"requestsPerSecond" json:"requestsPerSecond" jsonschema:"required,default=10"`
+	// Burst is the maximum number of requests per client allowed at once
+	// (defaults to RequestsPerSecond rounded up)
+	// +optional
+	Burst int `yaml:"burst,omitempty" json:"burst,omitempty" jsonschema:"description=Maximum number of requests per client allowed at once (defaults to requestsPerSecond rounded up)"`
+	// TrustedProxies are the IP addresses or CIDR ranges of reverse proxies,
+	// e.g. an ingress controller, whose X-Forwarded-For and X-Real-IP headers
+	// are used to identify the client IP address
+	// +optional
+	TrustedProxies []string `yaml:"trustedProxies,omitempty" json:"trustedProxies,omitempty" jsonschema:"description=IP addresses or CIDR ranges of reverse proxies whose X-Forwarded-For and X-Real-IP headers identify the client"`
+}
+
+// WebhookJSONMapping configures an endpoint accepting arbitrary JSON payloads.
+// The CloudEvent attributes are built from the configured mapping rules and the
+// JSON payload is used as the CloudEvent data.
+type WebhookJSONMapping struct {
+	// Path is the relative URL path to accept incoming JSON payloads. Must differ
+	// from the CloudEvents webhook path.
+	Path string `yaml:"path" json:"path" jsonschema:"required,default=/webhook/json"`
+	// Type sets the CloudEvent type
+	Type WebhookMappingRule `yaml:"type" json:"type" jsonschema:"required,description=Mapping rule for the CloudEvent type"`
+	// Source sets the CloudEvent source (defaults to the request URL)
+	// +optional
+	Source *WebhookMappingRule `yaml:"source,omitempty" json:"source,omitempty" jsonschema:"description=Mapping rule for the CloudEvent source (defaults to the request URL)"`
+	// Subject sets the CloudEvent subject
+	// +optional
+	Subject *WebhookMappingRule `yaml:"subject,omitempty" json:"subject,omitempty" jsonschema:"description=Mapping rule for the CloudEvent subject"`
+	// ID sets the CloudEvent id (defaults to a random UUID)
+	// +optional
+	ID *WebhookMappingRule `yaml:"id,omitempty" json:"id,omitempty" jsonschema:"description=Mapping rule for the CloudEvent id (defaults to a random UUID)"`
+	// Time sets the CloudEvent time (defaults to the time the request was
+	// received). The value must be an RFC3339 timestamp or seconds/milliseconds
+	// since unix epoch.
+	// +optional
+	Time *WebhookMappingRule `yaml:"time,omitempty" json:"time,omitempty" jsonschema:"description=Mapping rule for the CloudEvent time (defaults to the time the request was received)"`
+}
+
+// WebhookMappingRule retrieves a CloudEvent attribute value from the incoming
+// request. The first non-empty value of header, jsonPath and value (in that
+// order) is used.
+type WebhookMappingRule struct {
+	// Header is the name of an HTTP request header
+	// +optional
+	Header string `yaml:"header,omitempty" json:"header,omitempty" jsonschema:"description=HTTP request header containing the value (e.g. X-Event-Type)"`
+	// JSONPath is the path to a value in the JSON payload, e.g. alerts[0].labels.severity
+	// +optional
+	JSONPath string `yaml:"jsonPath,omitempty" json:"jsonPath,omitempty" jsonschema:"description=Path to the value in the JSON payload (e.g. $.alerts[0].labels.alertname)"`
+	// Value is a static value used if no header or JSON path value was found
+	// +optional
+	Value string `yaml:"value,omitempty" json:"value,omitempty" jsonschema:"description=Static (fallback) value"`
 }
 
 // ProviderConfigHorizon configures the Horizon event provider
 type ProviderConfigHorizon struct {
 	// Address is the address of the Horizon API server
-	Address string `yaml:"address" json:"address" jsonschema:"required,default=https://api.myhorizon.domain.local"`
+	Address string `yaml:"address,omitempty" json:"address,omitempty" jsonschema:"description=Horizon API server address (required if addresses is not set),default=https://api.myhorizon.domain.local"`
+	// Addresses is a list of Horizon connection server addresses. The event
+	// stream fails over to the next server in the list if the active server
+	// becomes unavailable. If Address is also set, it is used as the first server
+	// in the list.
+	// +optional
+	Addresses []string `yaml:"addresses,omitempty" json:"addresses,omitempty" jsonschema:"description=List of Horizon connection server addresses used for failover"`
 	// InsecureSSL enables/disables TLS certificate validation
 	InsecureSSL bool `yaml:"insecureSSL" json:"insecureSSL" jsonschema:"required,default=false"`
 	// Auth sets the Horizon API authentication credentials. Only active_directory is
 	// supported.
 	Auth *AuthMethod `yaml:"auth,omitempty" json:"auth,omitempty" jsonschema:"oneof_required=auth,description=Authentication configuration for this section"`
+	// Filter sets optional server-side filters for the retrieved audit events
+	// +optional
+	Filter *HorizonEventFilter `yaml:"filter,omitempty" json:"filter,omitempty" jsonschema:"description=Server-side filters for retrieved audit events"`
+}
+
+// HorizonEventFilter configures server-side audit event filters. All non-empty
+// fields are combined into a logical AND filter.
+type HorizonEventFilter struct {
+	// Severity only retrieves events with the given severity
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty" jsonschema:"enum=INFO,enum=WARNING,enum=ERROR,enum=AUDIT_SUCCESS,enum=AUDIT_FAIL,enum=UNKNOWN,description=Only retrieve events with the given severity"`
+	// Module only retrieves events logged by the given Horizon component
+	Module string `yaml:"module,omitempty" json:"module,omitempty" jsonschema:"description=Only retrieve events logged by the given Horizon component (e.g. Broker)"`
+	// Type only retrieves events of the given type
+	Type string `yaml:"type,omitempty" json:"type,omitempty" jsonschema:"description=Only retrieve events of the given type (e.g. VLSI_USERLOGGEDIN)"`
+	// DesktopPool only retrieves events associated with the given desktop pool
+	DesktopPool string `yaml:"desktopPool,omitempty" json:"desktopPool,omitempty" jsonschema:"description=Only retrieve events associated with the given desktop pool"`
+}
+
+// SyslogProtocol represents a supported syslog transport protocol
+type SyslogProtocol string
+
+const (
+	// SyslogUDP receives syslog messages via UDP (RFC 5426)
+	SyslogUDP SyslogProtocol = "udp"
+	// SyslogTCP receives syslog messages via TCP (RFC 6587)
+	SyslogTCP SyslogProtocol = "tcp"
+	// SyslogTLS receives syslog messages via TLS (RFC 5425)
+	SyslogTLS SyslogProtocol = "tls"
+)
+
+// SyslogFormat represents a supported syslog message format
+type SyslogFormat string
+
+const (
+	// SyslogFormatAuto detects the message format per message
+	SyslogFormatAuto SyslogFormat = "auto"
+	// SyslogFormatRFC5424 parses messages as RFC 5424
+	SyslogFormatRFC5424 SyslogFormat = "rfc5424"
+	// SyslogFormatRFC3164 parses messages as RFC 3164 (BSD syslog)
+	SyslogFormatRFC3164 SyslogFormat = "rfc3164"
+)
+
+// ProviderConfigSyslog configures the syslog event provider
+type ProviderConfigSyslog struct {
+	// BindAddress is the address where the syslog server will listen for
+	// messages
+	BindAddress string `yaml:"bindAddress" json:"bindAddress" jsonschema:"required,default=0.0.0.0:514"`
+	// Protocol is the syslog transport protocol
+	Protocol SyslogProtocol `yaml:"protocol" json:"protocol" jsonschema:"required,enum=udp,enum=tcp,enum=tls,default=udp"`
+	// Format is the syslog message format (defaults to auto)
+	// +optional
+	Format SyslogFormat `yaml:"format,omitempty" json:"format,omitempty" jsonschema:"enum=auto,enum=rfc5424,enum=rfc3164,default=auto,description=Syslog message format"`
+	// TLS configures the server certificate for protocol tls
+	// +optional
+	TLS *TLSConfig `yaml:"tls,omitempty" json:"tls,omitempty" jsonschema:"description=TLS configuration (required for protocol tls)"`
+}
+
+// SNMPAuthProtocol represents a supported SNMPv3 authentication protocol
+type SNMPAuthProtocol string
+
+const (
+	SNMPAuthNone   SNMPAuthProtocol = "none"
+	SNMPAuthMD5    SNMPAuthProtocol = "md5"
+	SNMPAuthSHA    SNMPAuthProtocol = "sha"
+	SNMPAuthSHA224 SNMPAuthProtocol = "sha224"
+	SNMPAuthSHA256 SNMPAuthProtocol = "sha256"
+	SNMPAuthSHA384 SNMPAuthProtocol = "sha384"
+	SNMPAuthSHA512 SNMPAuthProtocol = "sha512"
+)
+
+// SNMPPrivProtocol represents a supported SNMPv3 privacy (encryption) protocol
+type SNMPPrivProtocol string
+
+const (
+	SNMPPrivNone    SNMPPrivProtocol = "none"
+	SNMPPrivDES     SNMPPrivProtocol = "des"
+	SNMPPrivAES     SNMPPrivProtocol = "aes"
+	SNMPPrivAES192  SNMPPrivProtocol = "aes192"
+	SNMPPrivAES256  SNMPPrivProtocol = "aes256"
+	SNMPPrivAES192C SNMPPrivProtocol = "aes192c"
+	SNMPPrivAES256C SNMPPrivProtocol = "aes256c"
+)
+
+// ProviderConfigSNMP configures the SNMP trap receiver event provider
+type ProviderConfigSNMP struct {
+	// BindAddress is the UDP address where the trap receiver will listen for
+	// traps
+	BindAddress string `yaml:"bindAddress" json:"bindAddress" jsonschema:"required,default=0.0.0.0:162"`
+	// Communities are the accepted SNMPv2c community strings
+	// +optional
+	Communities []string `yaml:"communities,omitempty" json:"communities,omitempty" jsonschema:"description=Accepted SNMPv2c community strings"`
+	// Users are the accepted SNMPv3 users
+	// +optional
+	Users []SNMPUser `yaml:"users,omitempty" json:"users,omitempty" jsonschema:"description=Accepted SNMPv3 users"`
+	// MIBMappings are files mapping OIDs to names used to resolve trap and
+	// variable OIDs
+	// +optional
+	MIBMappings []string `yaml:"mibMappings,omitempty" json:"mibMappings,omitempty" jsonschema:"description=Files mapping OIDs to names (YAML/JSON or snmptranslate -Tz output)"`
+}
+
+// SNMPUser configures an SNMPv3 user (User-based Security Model)
+type SNMPUser struct {
+	// Username is the SNMPv3 security name
+	Username string `yaml:"username" json:"username" jsonschema:"required"`
+	// EngineID is the hex-encoded authoritative engine ID of the trap sender
+	EngineID string `yaml:"engineID" json:"engineID" jsonschema:"required,description=Hex-encoded engine ID of the trap sender"`
+	// AuthProtocol is the authentication protocol (defaults to none)
+	// +optional
+	AuthProtocol SNMPAuthProtocol `yaml:"authProtocol,omitempty" json:"authProtocol,omitempty" jsonschema:"enum=none,enum=md5,enum=sha,enum=sha224,enum=sha256,enum=sha384,enum=sha512,default=none"`
+	// AuthPassphrase is the authentication passphrase
+	// +optional
+	AuthPassphrase string `yaml:"authPassphrase,omitempty" json:"authPassphrase,omitempty"`
+	// PrivProtocol is the privacy protocol (defaults to none)
+	// +optional
+	PrivProtocol SNMPPrivProtocol `yaml:"privProtocol,omitempty" json:"privProtocol,omitempty" jsonschema:"enum=none,enum=des,enum=aes,enum=aes192,enum=aes256,enum=aes192c,enum=aes256c,default=none"`
+	// PrivPassphrase is the privacy passphrase
+	// +optional
+	PrivPassphrase string `yaml:"privPassphrase,omitempty" json:"privPassphrase,omitempty"`
+}
+
+// ReplayTiming represents a supported replay timing
+type ReplayTiming string
+
+const (
+	// ReplayTimingOriginal replays events using the time between the original
+	// events divided by the configured speed
+	ReplayTimingOriginal ReplayTiming = "original"
+	// ReplayTimingFast replays events as fast as possible
+	ReplayTimingFast ReplayTiming = "fast"
+)
+
+// ProviderConfigReplay configures the replay event provider which reads
+// recorded events from files
+type ProviderConfigReplay struct {
+	// Path is a file or directory containing CloudEvents or vSphere events in
+	// JSON format (optionally gzipped). Files in a directory are replayed in
+	// lexical order.
+	Path string `yaml:"path" json:"path" jsonschema:"required,default=/var/lib/vmware-event-router/replay"`
+	// Timing sets whether the time between events is preserved (defaults to
+	// original)
+	// +optional
+	Timing ReplayTiming `yaml:"timing,omitempty" json:"timing,omitempty" jsonschema:"enum=original,enum=fast,default=original,description=Preserve the time between events or replay as fast as possible"`
+	// Speed is the replay speed multiplier for timing original, e.g. 2 replays
+	// twice as fast (defaults to 1)
+	// +optional
+	Speed float64 `yaml:"speed,omitempty" json:"speed,omitempty" jsonschema:"description=Replay speed multiplier for timing original,default=1"`
+	// Source sets the CloudEvent source for vSphere events (defaults to the file
+	// URI)
+	// +optional
+	Source string `yaml:"source,omitempty" json:"source,omitempty" jsonschema:"description=CloudEvent source for vSphere events (defaults to the file URI),default=https://my-vcenter01.domain.local/sdk"`
+}
+
+// ProviderConfigGenerator configures the synthetic event generator provider
+// which emits vSphere events at a target rate, e.g. for load testing
+type ProviderConfigGenerator struct {
+	// Rate is the target number of events per second
+	Rate float64 `yaml:"rate" json:"rate" jsonschema:"required,default=10"`
+	// Concurrency is the number of goroutines invoking the event processor
+	// (defaults to 1)
+	// +optional
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty" jsonschema:"description=Number of goroutines invoking the event processor,default=1"`
+	// MaxEvents stops generating events after the given number of events (0
+	// generates events until the router is stopped)
+	// +optional
+	MaxEvents int `yaml:"maxEvents,omitempty" json:"maxEvents,omitempty" jsonschema:"description=Stop after the given number of events (0 for unlimited),default=0"`
+	// Burst emits additional events at once in a fixed interval (optional)
+	// +optional
+	Burst *GeneratorBurst `yaml:"burst,omitempty" json:"burst,omitempty" jsonschema:"description=Emit additional events at once in a fixed interval"`
+	// Events is the mix of generated vSphere event types (defaults to a mix of
+	// common virtual machine, host and session events)
+	// +optional
+	Events []GeneratorEvent `yaml:"events,omitempty" json:"events,omitempty" jsonschema:"description=Mix of generated vSphere event types"`
+	// Entities is the number of distinct randomized names per inventory object
+	// type, e.g. virtual machines (defaults to 100)
+	// +optional
+	Entities int `yaml:"entities,omitempty" json:"entities,omitempty" jsonschema:"description=Number of distinct names per inventory object type,default=100"`
+	// Seed initializes the random number generator for reproducible event
+	// sequences (0 uses a random seed)
+	// +optional
+	Seed int64 `yaml:"seed,omitempty" json:"seed,omitempty" jsonschema:"description=Random seed for reproducible event sequences (0 for a random seed)"`
+	// Source sets the CloudEvent source (defaults to
+	// https://generator.vmware-event-router.local/sdk)
+	// +optional
+	Source string `yaml:"source,omitempty" json:"source,omitempty" jsonschema:"description=CloudEvent source,default=https://generator.vmware-event-router.local/sdk"`
+}
+
+// GeneratorBurst configures bursts of generated events
+type GeneratorBurst struct {
+	// Size is the number of events emitted at once
+	Size int `yaml:"size" json:"size" jsonschema:"required,default=100"`
+	// IntervalSeconds is the time between bursts in seconds
+	IntervalSeconds int `yaml:"intervalSeconds" json:"intervalSeconds" jsonschema:"required,default=60"`
+}
+
+// GeneratorEvent configures a generated vSphere event type
+type GeneratorEvent struct {
+	// Type is the vSphere event type, e.g. VmPoweredOnEvent, EventEx or
+	// ExtendedEvent
+	Type string `yaml:"type" json:"type" jsonschema:"required,default=VmPoweredOnEvent"`
+	// EventTypeID sets the event type ID for types EventEx and ExtendedEvent,
+	// e.g. com.vmware.vc.HA.DasHostFailedEvent
+	// +optional
+	EventTypeID string `yaml:"eventTypeID,omitempty" json:"eventTypeID,omitempty" jsonschema:"description=Event type ID (required for EventEx and ExtendedEvent)"`
+	// Weight is the relative frequency of this event type in the mix (defaults
+	// to 1)
+	// +optional
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty" jsonschema:"description=Relative frequency of this event type,default=1"`
+}
+
+// KubernetesEventAPI represents the Kubernetes API group used to watch events
+type KubernetesEventAPI string
+
+const (
+	// KubernetesEventAPICore watches core/v1 events
+	KubernetesEventAPICore KubernetesEventAPI = "core"
+	// KubernetesEventAPIEvents watches events.k8s.io/v1beta1 events
+	KubernetesEventAPIEvents KubernetesEventAPI = "events"
+)
+
+// ProviderConfigKubernetes configures the Kubernetes event provider which
+// watches cluster events, e.g. pod evictions or failing volume claims
+type ProviderConfigKubernetes struct {
+	// Kubeconfig is the path to a kubeconfig file (defaults to the in-cluster
+	// configuration)
+	// +optional
+	Kubeconfig string `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty" jsonschema:"description=Path to a kubeconfig file (in-cluster configuration if empty)"`
+	// API sets the API group used to watch events (defaults to core)
+	// +optional
+	API KubernetesEventAPI `yaml:"api,omitempty" json:"api,omitempty" jsonschema:"enum=core,enum=events,default=core,description=API group used to watch events (core/v1 or events.k8s.io)"`
+	// Namespaces only emits events from the given namespaces (defaults to all
+	// namespaces)
+	// +optional
+	Namespaces []string `yaml:"namespaces,omitempty" json:"namespaces,omitempty" jsonschema:"description=Only emit events from the given namespaces (all namespaces if empty)"`
+	// Reasons only emits events with the given reasons, e.g. Evicted (defaults
+	// to all reasons)
+	// +optional
+	Reasons []string `yaml:"reasons,omitempty" json:"reasons,omitempty" jsonschema:"description=Only emit events with the given reasons (all reasons if empty)"`
+	// Checkpoint enables/disables resuming from the last processed resource
+	// version after a restart
+	// +optional
+	Checkpoint bool `yaml:"checkpoint,omitempty" json:"checkpoint,omitempty" jsonschema:"description=Enable checkpointing of the last processed resource version to resume after a restart"`
+	// CheckpointDir sets the directory for persisting checkpoints (optional)
+	// +optional
+	CheckpointDir string `yaml:"checkpointDir,omitempty" json:"checkpointDir,omitempty" jsonschema:"description=Directory where to persist checkpoints if enabled,default=./checkpoints"`
+}
+
+// ProviderConfigNATS configures the NATS event provider which subscribes to
+// NATS subjects or a durable JetStream consumer
+type ProviderConfigNATS struct {
+	// Address of the NATS server (URI), multiple servers can be comma-separated
+	Address string `yaml:"address" json:"address" jsonschema:"required,default=nats://nats.vmware-system:4222"`
+	// Subjects to subscribe to, wildcards are supported
+	Subjects []string `yaml:"subjects" json:"subjects" jsonschema:"required,description=Subjects to subscribe to (exactly one with JetStream)"`
+	// QueueGroup distributes messages across event router instances using the
+	// same queue group (optional)
+	// +optional
+	QueueGroup string `yaml:"queueGroup,omitempty" json:"queueGroup,omitempty" jsonschema:"description=Queue group to distribute messages across event router instances"`
+	// JetStream consumes messages from a durable JetStream consumer with
+	// at-least-once delivery (optional)
+	// +optional
+	JetStream *NATSJetStream `yaml:"jetStream,omitempty" json:"jetStream,omitempty" jsonschema:"description=Consume messages from a durable JetStream consumer"`
+	// Source sets the CloudEvent source of messages which are not CloudEvents
+	// (defaults to the address)
+	// +optional
+	Source string `yaml:"source,omitempty" json:"source,omitempty" jsonschema:"description=CloudEvent source of messages which are not CloudEvents (defaults to the address)"`
+	// Auth sets the NATS authentication credentials. Only basic_auth is
+	// supported (optional)
+	// +optional
+	Auth *AuthMethod `yaml:"auth,omitempty" json:"auth,omitempty" jsonschema:"description=Authentication configuration for this section"`
+}
+
+// NATSJetStream configures a durable JetStream consumer
+type NATSJetStream struct {
+	// Stream is the name of the stream (defaults to the stream containing the
+	// subject)
+	// +optional
+	Stream string `yaml:"stream,omitempty" json:"stream,omitempty" jsonschema:"description=Stream name (defaults to the stream containing the subject)"`
+	// Durable is the name of the durable consumer, created if it does not
+	// exist
+	Durable string `yaml:"durable" json:"durable" jsonschema:"required,description=Durable consumer name"`
+	// MaxDeliver limits the number of delivery attempts per message (0 for
+	// unlimited)
+	// +optional
+	MaxDeliver int `yaml:"maxDeliver,omitempty" json:"maxDeliver,omitempty" jsonschema:"description=Maximum number of delivery attempts per message (0 for unlimited),default=0"`
 }
@@ -0,0 +1,16 @@
+package v1alpha1
+
+// Record configures recording of all events emitted by the event provider into
+// rotating JSONL files, e.g. to replay them with the replay event provider
+type Record struct {
+	// Dir is the directory where recordings are written
+	Dir string `yaml:"dir" json:"dir" jsonschema:"required,default=./recordings"`
+	// MaxFileSize is the maximum size of a recording file in bytes before a new
+	// file is started (defaults to 10MiB)
+	// +optional
+	MaxFileSize int64 `yaml:"maxFileSize,omitempty" json:"maxFileSize,omitempty" jsonschema:"description=Maximum size of a recording file in bytes,default=10485760"`
+	// MaxFiles is the maximum number of recording files kept in Dir. The oldest
+	// files are deleted first (defaults to 10)
+	// +optional
+	MaxFiles int `yaml:"maxFiles,omitempty" json:"maxFiles,omitempty" jsonschema:"description=Maximum number of recording files to keep,default=10"`
+}
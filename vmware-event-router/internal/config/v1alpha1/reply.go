@@ -0,0 +1,15 @@
+package v1alpha1
+
+// Replies configures feeding reply events, i.e. CloudEvents returned by the
+// sinks of the event processor, back into the router. Reply events are
+// processed like events emitted by the event provider.
+type Replies struct {
+	// MaxHops is the maximum number of times events of a reply chain are fed
+	// back into the router (defaults to 3)
+	// +optional
+	MaxHops int `yaml:"maxHops,omitempty" json:"maxHops,omitempty" jsonschema:"description=Maximum number of times events of a reply chain are fed back,default=3"`
+	// QueueSize is the maximum number of reply events waiting to be processed.
+	// Reply events exceeding the queue size are dropped (defaults to 100)
+	// +optional
+	QueueSize int `yaml:"queueSize,omitempty" json:"queueSize,omitempty" jsonschema:"description=Maximum number of reply events waiting to be processed,default=100"`
+}
@@ -0,0 +1,32 @@
+package v1alpha1
+
+// TLSVersion represents a supported minimum TLS version
+type TLSVersion string
+
+const (
+	// TLSVersion10 represents TLS 1.0
+	TLSVersion10 TLSVersion = "1.0"
+	// TLSVersion11 represents TLS 1.1
+	TLSVersion11 TLSVersion = "1.1"
+	// TLSVersion12 represents TLS 1.2
+	TLSVersion12 TLSVersion = "1.2"
+	// TLSVersion13 represents TLS 1.3
+	TLSVersion13 TLSVersion = "1.3"
+)
+
+// TLSConfig configures TLS for an http listener. Certificate, key and client CA
+// files are reloaded when they change on disk.
+type TLSConfig struct {
+	// CertFile is the path to the PEM-encoded server certificate (chain)
+	CertFile string `yaml:"certFile" json:"certFile" jsonschema:"required,default=/etc/vmware-event-router/tls/tls.crt"`
+	// KeyFile is the path to the PEM-encoded server private key
+	KeyFile string `yaml:"keyFile" json:"keyFile" jsonschema:"required,default=/etc/vmware-event-router/tls/tls.key"`
+	// ClientCAFile is the path to PEM-encoded CA certificates used to verify
+	// client certificates. If set, clients must present a valid certificate
+	// (mutual TLS).
+	// +optional
+	ClientCAFile string `yaml:"clientCAFile,omitempty" json:"clientCAFile,omitempty" jsonschema:"description=CA certificates to verify client certificates (enables mutual TLS)"`
+	// MinVersion is the minimum accepted TLS version (defaults to 1.2)
+	// +optional
+	MinVersion TLSVersion `yaml:"minVersion,omitempty" json:"minVersion,omitempty" jsonschema:"enum=1.0,enum=1.1,enum=1.2,enum=1.3,default=1.2,description=Minimum accepted TLS version"`
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"context"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type vSphereEventKey struct{}
+
+// WithVSphereEvent returns a context carrying the vSphere event a CloudEvent
+// was created from, e.g. to record the original event
+func WithVSphereEvent(ctx context.Context, event types.BaseEvent) context.Context {
+	return context.WithValue(ctx, vSphereEventKey{}, event)
+}
+
+// VSphereEventFromContext returns the vSphere event set with WithVSphereEvent
+func VSphereEventFromContext(ctx context.Context) (types.BaseEvent, bool) {
+	event, ok := ctx.Value(vSphereEventKey{}).(types.BaseEvent)
+	return event, ok && event != nil
+}
@@ -20,6 +20,48 @@ const (
 	// EventContentType is the CloudEvent data content type used by the VMware Event
 	// Router
 	EventContentType = cloudevents.ApplicationJSON
+	// HorizonEventCategory is the CloudEvent type category used for VMware
+	// Horizon events
+	HorizonEventCategory = "horizon"
+	// SyslogEventCategory is the CloudEvent type category used for syslog
+	// messages
+	SyslogEventCategory = "syslog"
+	// SNMPEventCategory is the CloudEvent type category used for SNMP traps
+	SNMPEventCategory = "snmp"
+	// KubernetesEventCategory is the CloudEvent type category used for
+	// Kubernetes events
+	KubernetesEventCategory = "kubernetes"
+	// NATSEventCategory is the CloudEvent type category used for NATS messages
+	// which are not CloudEvents
+	NATSEventCategory = "nats"
+)
+
+// CloudEvent extension attributes set for VMware Horizon events
+const (
+	horizonSeverityKey    = "severity"
+	horizonModuleKey      = "module"
+	horizonUserKey        = "user"
+	horizonDesktopPoolKey = "desktoppool"
+)
+
+// CloudEvent extension attributes set for syslog messages
+const (
+	syslogHostnameKey = "hostname"
+	syslogAppnameKey  = "appname"
+	syslogSeverityKey = "severity"
+)
+
+// CloudEvent extension attributes set for SNMP traps
+const (
+	snmpTrapOIDKey = "trapoid"
+)
+
+// CloudEvent extension attributes set for Kubernetes events
+const (
+	kubernetesNamespaceKey  = "namespace"
+	kubernetesObjectKindKey = "objectkind"
+	kubernetesObjectNameKey = "objectname"
+	kubernetesEventTypeKey  = "eventtype"
 )
 
 // VCenterEventInfo contains the name and category of an event received from vCenter
@@ -85,9 +127,157 @@ func WithAttributes(ceAttrs map[string]string) Option {
 	}
 }
 
+// HorizonEventInfo contains the details of a VMware Horizon audit event used
+// to create a CloudEvent
+type HorizonEventInfo struct {
+	// Type is the Horizon event type, e.g. VLSI_USERLOGGEDIN
+	Type string
+	// Time is the time the event occurred
+	Time time.Time
+	// Severity is the event severity, e.g. AUDIT_SUCCESS
+	Severity string
+	// Module is the Horizon component which logged the event
+	Module string
+	// User is the Sid of the user associated with the event (optional)
+	User string
+	// DesktopPool is the desktop pool associated with the event (optional)
+	DesktopPool string
+}
+
+// SyslogEventInfo contains the details of a syslog message used to create a
+// CloudEvent
+type SyslogEventInfo struct {
+	// Hostname is the host which sent the message
+	Hostname string
+	// Appname is the application which logged the message, e.g. vpxa
+	Appname string
+	// Severity is the message severity, e.g. warning
+	Severity string
+	// Time is the message timestamp
+	Time time.Time
+}
+
+// SNMPEventInfo contains the details of an SNMP trap used to create a
+// CloudEvent
+type SNMPEventInfo struct {
+	// TrapOID is the numeric trap OID, e.g. .1.3.6.1.6.3.1.1.5.3
+	TrapOID string
+	// TrapName is the trap OID name resolved through MIB mappings, e.g.
+	// linkDown (optional)
+	TrapName string
+	// Time is the time the trap was received
+	Time time.Time
+}
+
+// KubernetesEventInfo contains the details of a Kubernetes event used to
+// create a CloudEvent
+type KubernetesEventInfo struct {
+	// Reason is the reason of the event, e.g. Evicted
+	Reason string
+	// Type is the event type, i.e. Normal or Warning
+	Type string
+	// Namespace is the namespace of the event
+	Namespace string
+	// ObjectKind is the kind of the object the event is about, e.g. Pod
+	ObjectKind string
+	// ObjectName is the name of the object the event is about
+	ObjectName string
+	// Time is the time the event was last observed
+	Time time.Time
+}
+
+// NATSEventInfo contains the details of a NATS message used to create a
+// CloudEvent
+type NATSEventInfo struct {
+	// Subject is the NATS subject the message was published to
+	Subject string
+	// Time is the time the message was stored (JetStream) or received
+	Time time.Time
+}
+
 // NewFromVSphere returns a compliant CloudEvent for the given vSphere event
 func NewFromVSphere(event types.BaseEvent, source string, options ...Option) (*cloudevents.Event, error) {
 	eventInfo := GetDetails(event)
+	return newEvent(eventInfo.Category, eventInfo.Name, event.GetEvent().CreatedTime, event, source, options...)
+}
+
+// NewFromHorizon returns a compliant CloudEvent for the given Horizon event and
+// data. The Horizon event type is used as the CloudEvent subject. Severity,
+// module, user and desktop pool are set as extension attributes if not empty.
+func NewFromHorizon(info HorizonEventInfo, data interface{}, source string, options ...Option) (*cloudevents.Event, error) {
+	options = withExtensions(map[string]string{
+		horizonSeverityKey:    info.Severity,
+		horizonModuleKey:      info.Module,
+		horizonUserKey:        info.User,
+		horizonDesktopPoolKey: info.DesktopPool,
+	}, options)
+	return newEvent(HorizonEventCategory, info.Type, info.Time, data, source, options...)
+}
+
+// NewFromSyslog returns a compliant CloudEvent for the given syslog message
+// details and data. The application name is used as the CloudEvent subject.
+// Hostname, application name and severity are set as extension attributes if
+// not empty.
+func NewFromSyslog(info SyslogEventInfo, data interface{}, source string, options ...Option) (*cloudevents.Event, error) {
+	options = withExtensions(map[string]string{
+		syslogHostnameKey: info.Hostname,
+		syslogAppnameKey:  info.Appname,
+		syslogSeverityKey: info.Severity,
+	}, options)
+	return newEvent(SyslogEventCategory, info.Appname, info.Time, data, source, options...)
+}
+
+// NewFromSNMP returns a compliant CloudEvent for the given SNMP trap details
+// and data. The trap name is used as the CloudEvent subject, or the trap OID if
+// the name is unknown. The trap OID is set as extension attribute.
+func NewFromSNMP(info SNMPEventInfo, data interface{}, source string, options ...Option) (*cloudevents.Event, error) {
+	subject := info.TrapName
+	if subject == "" {
+		subject = info.TrapOID
+	}
+
+	options = withExtensions(map[string]string{
+		snmpTrapOIDKey: info.TrapOID,
+	}, options)
+	return newEvent(SNMPEventCategory, subject, info.Time, data, source, options...)
+}
+
+// NewFromKubernetes returns a compliant CloudEvent for the given Kubernetes
+// event details and data. The event reason is used as the CloudEvent subject.
+// Namespace, object kind and name and the event type are set as extension
+// attributes if not empty.
+func NewFromKubernetes(info KubernetesEventInfo, data interface{}, source string, options ...Option) (*cloudevents.Event, error) {
+	options = withExtensions(map[string]string{
+		kubernetesNamespaceKey:  info.Namespace,
+		kubernetesObjectKindKey: info.ObjectKind,
+		kubernetesObjectNameKey: info.ObjectName,
+		kubernetesEventTypeKey:  info.Type,
+	}, options)
+	return newEvent(KubernetesEventCategory, info.Reason, info.Time, data, source, options...)
+}
+
+// NewFromNATS returns a compliant CloudEvent for the given NATS message details
+// and data. The NATS subject is used as the CloudEvent subject.
+func NewFromNATS(info NATSEventInfo, data interface{}, source string, options ...Option) (*cloudevents.Event, error) {
+	return newEvent(NATSEventCategory, info.Subject, info.Time, data, source, options...)
+}
+
+// withExtensions prepends the non-empty extension attributes attrs to options.
+// Extensions are applied first so they can be overwritten by options.
+func withExtensions(attrs map[string]string, options []Option) []Option {
+	ext := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		if v != "" {
+			ext[k] = v
+		}
+	}
+
+	return append([]Option{WithAttributes(ext)}, options...)
+}
+
+// newEvent returns a compliant CloudEvent using the given event category, name
+// (subject), time and data
+func newEvent(category, name string, t time.Time, data interface{}, source string, options ...Option) (*cloudevents.Event, error) {
 	ce := cloudevents.NewEvent(EventSpecVersion)
 
 	// URI of the event producer, e.g. http(s)://vcenter.domain.ext/sdk
@@ -95,13 +285,13 @@ func NewFromVSphere(event types.BaseEvent, source string, options ...Option) (*c
 
 	// apply defaults
 	ce.SetID(uuid.New().String())
-	ce.SetTime(event.GetEvent().CreatedTime)
+	ce.SetTime(t)
 
-	ce.SetType(EventCanonicalType + "/" + eventInfo.Category)
-	ce.SetSubject(eventInfo.Name)
+	ce.SetType(EventCanonicalType + "/" + category)
+	ce.SetSubject(name)
 
 	var err error
-	err = ce.SetData(EventContentType, event)
+	err = ce.SetData(EventContentType, data)
 	if err != nil {
 		return nil, errors.Wrap(err, "set CloudEvent data")
 	}
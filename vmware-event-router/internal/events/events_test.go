@@ -4,6 +4,7 @@
 package events
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -150,3 +151,270 @@ func Test_NewFromVSphere(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewFromHorizon(t *testing.T) {
+	const (
+		source = "https://api.myhorizon.corp.local"
+	)
+
+	now := time.Now().UTC()
+	data := map[string]string{"type": "VLSI_USERLOGGEDIN"}
+
+	e1 := cloudevents.NewEvent()
+	e1.SetSource(source)
+	e1.SetID("1")
+	e1.SetTime(now)
+	e1.SetType(EventCanonicalType + "/" + "horizon")
+	e1.SetSubject("VLSI_USERLOGGEDIN")
+	e1.SetExtension("severity", "AUDIT_SUCCESS")
+	e1.SetExtension("module", "Vlsi")
+	if err := e1.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	e2 := e1.Clone()
+	e2.SetExtension("user", "S-1-5-21-500")
+	e2.SetExtension("desktoppool", "win10-pool")
+
+	testEvents := []cloudevents.Event{e1, e2}
+
+	tests := []struct {
+		name string
+		info HorizonEventInfo
+		want *cloudevents.Event
+	}{
+		{
+			name: "event without user and desktop pool",
+			info: HorizonEventInfo{
+				Type:     "VLSI_USERLOGGEDIN",
+				Time:     now,
+				Severity: "AUDIT_SUCCESS",
+				Module:   "Vlsi",
+			},
+			want: &testEvents[0],
+		},
+		{
+			name: "event with user and desktop pool",
+			info: HorizonEventInfo{
+				Type:        "VLSI_USERLOGGEDIN",
+				Time:        now,
+				Severity:    "AUDIT_SUCCESS",
+				Module:      "Vlsi",
+				User:        "S-1-5-21-500",
+				DesktopPool: "win10-pool",
+			},
+			want: &testEvents[1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromHorizon(tt.info, data, source, WithID("1"))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.want, got)
+		})
+	}
+}
+
+func Test_NewFromSyslog(t *testing.T) {
+	const (
+		source = "syslog://10.0.0.10"
+	)
+
+	now := time.Now().UTC()
+	data := map[string]string{"message": "vpxa started"}
+
+	e1 := cloudevents.NewEvent()
+	e1.SetSource(source)
+	e1.SetID("1")
+	e1.SetTime(now)
+	e1.SetType(EventCanonicalType + "/" + "syslog")
+	e1.SetSubject("vpxa")
+	e1.SetExtension("hostname", "esx-01.corp.local")
+	e1.SetExtension("appname", "vpxa")
+	e1.SetExtension("severity", "info")
+	if err := e1.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	e2 := cloudevents.NewEvent()
+	e2.SetSource(source)
+	e2.SetID("1")
+	e2.SetTime(now)
+	e2.SetType(EventCanonicalType + "/" + "syslog")
+	e2.SetExtension("severity", "info")
+	if err := e2.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	testEvents := []cloudevents.Event{e1, e2}
+
+	tests := []struct {
+		name string
+		info SyslogEventInfo
+		want *cloudevents.Event
+	}{
+		{
+			name: "message with hostname and appname",
+			info: SyslogEventInfo{
+				Hostname: "esx-01.corp.local",
+				Appname:  "vpxa",
+				Severity: "info",
+				Time:     now,
+			},
+			want: &testEvents[0],
+		},
+		{
+			name: "message without hostname and appname",
+			info: SyslogEventInfo{
+				Severity: "info",
+				Time:     now,
+			},
+			want: &testEvents[1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromSyslog(tt.info, data, source, WithID("1"))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.want, got)
+		})
+	}
+}
+
+func Test_NewFromSNMP(t *testing.T) {
+	const (
+		source  = "snmp://10.0.0.20"
+		trapOID = ".1.3.6.1.6.3.1.1.5.3"
+	)
+
+	now := time.Now().UTC()
+	data := map[string]string{"trapOID": trapOID}
+
+	e1 := cloudevents.NewEvent()
+	e1.SetSource(source)
+	e1.SetID("1")
+	e1.SetTime(now)
+	e1.SetType(EventCanonicalType + "/" + "snmp")
+	e1.SetSubject("linkDown")
+	e1.SetExtension("trapoid", trapOID)
+	if err := e1.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	e2 := e1.Clone()
+	e2.SetSubject(trapOID)
+
+	testEvents := []cloudevents.Event{e1, e2}
+
+	tests := []struct {
+		name string
+		info SNMPEventInfo
+		want *cloudevents.Event
+	}{
+		{
+			name: "trap with resolved name",
+			info: SNMPEventInfo{TrapOID: trapOID, TrapName: "linkDown", Time: now},
+			want: &testEvents[0],
+		},
+		{
+			name: "trap without resolved name",
+			info: SNMPEventInfo{TrapOID: trapOID, Time: now},
+			want: &testEvents[1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromSNMP(tt.info, data, source, WithID("1"))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.want, got)
+		})
+	}
+}
+
+func Test_NewFromKubernetes(t *testing.T) {
+	const source = "https://10.0.0.1:6443"
+
+	now := time.Now().UTC()
+	data := map[string]string{"message": "The node was low on resource: memory."}
+
+	e1 := cloudevents.NewEvent()
+	e1.SetSource(source)
+	e1.SetID("1")
+	e1.SetTime(now)
+	e1.SetType(EventCanonicalType + "/" + "kubernetes")
+	e1.SetSubject("Evicted")
+	e1.SetExtension("namespace", "default")
+	e1.SetExtension("objectkind", "Pod")
+	e1.SetExtension("objectname", "nginx-6799fc88d8-zx8hw")
+	e1.SetExtension("eventtype", "Warning")
+	if err := e1.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	e2 := cloudevents.NewEvent()
+	e2.SetSource(source)
+	e2.SetID("1")
+	e2.SetTime(now)
+	e2.SetType(EventCanonicalType + "/" + "kubernetes")
+	e2.SetSubject("NodeNotReady")
+	e2.SetExtension("objectkind", "Node")
+	e2.SetExtension("objectname", "worker-01")
+	if err := e2.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	testEvents := []cloudevents.Event{e1, e2}
+
+	tests := []struct {
+		name string
+		info KubernetesEventInfo
+		want *cloudevents.Event
+	}{
+		{
+			name: "namespaced event",
+			info: KubernetesEventInfo{
+				Reason:     "Evicted",
+				Type:       "Warning",
+				Namespace:  "default",
+				ObjectKind: "Pod",
+				ObjectName: "nginx-6799fc88d8-zx8hw",
+				Time:       now,
+			},
+			want: &testEvents[0],
+		},
+		{
+			name: "cluster-scoped event without type",
+			info: KubernetesEventInfo{Reason: "NodeNotReady", ObjectKind: "Node", ObjectName: "worker-01", Time: now},
+			want: &testEvents[1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFromKubernetes(tt.info, data, source, WithID("1"))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.want, got)
+		})
+	}
+}
+
+func Test_NewFromNATS(t *testing.T) {
+	const source = "nats://10.0.0.30:4222"
+
+	now := time.Now().UTC()
+	data := json.RawMessage(`{"vm":"vm-42","action":"snapshot"}`)
+
+	want := cloudevents.NewEvent()
+	want.SetSource(source)
+	want.SetID("1")
+	want.SetTime(now)
+	want.SetType(EventCanonicalType + "/" + "nats")
+	want.SetSubject("vsphere.vm.snapshot")
+	if err := want.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Errorf("marshal data: %v", err)
+	}
+
+	got, err := NewFromNATS(NATSEventInfo{Subject: "vsphere.vm.snapshot", Time: now}, data, source, WithID("1"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &want, got)
+	assert.Equal(t, string(got.Data()), string(data))
+}
@@ -27,7 +27,6 @@ var _ = Describe("OpenFaaS Processor", func() {
 			log,
 			openfaas.WithRebuildInterval(time.Millisecond),
 			openfaas.WithResponseHandler(receiver),
-			openfaas.WithResponseChan(resCh),
 		)
 		Expect(err).ShouldNot(HaveOccurred())
 		ofProcessor = op
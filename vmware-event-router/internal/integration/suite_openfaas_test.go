@@ -45,12 +45,6 @@ func (f *fakeReceiver) Response(res ofsdk.InvokerResponse) {
 	f.Lock()
 	defer f.Unlock()
 
-	// when finished send response to unblock and return from Process() in the
-	// processor
-	defer func() {
-		resCh <- res
-	}()
-
 	if res.Error != nil || res.Status != http.StatusOK {
 		f.responseMap[fail]++
 		return
@@ -66,7 +60,6 @@ var (
 	ofProcessor processor.Processor
 	cfg         *config.ProcessorConfigOpenFaaS
 	receiver    *fakeReceiver
-	resCh       = make(chan ofsdk.InvokerResponse)
 )
 
 func TestOpenfaas(t *testing.T) {
@@ -92,7 +85,3 @@ var _ = BeforeSuite(func() {
 		},
 	}
 })
-
-var _ = AfterSuite(func() {
-	close(resCh)
-})
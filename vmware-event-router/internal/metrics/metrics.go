@@ -3,6 +3,7 @@ package metrics
 import (
 	"encoding/json"
 	"io/ioutil"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +21,13 @@ const (
 type InvocationDetails struct {
 	SuccessCount int
 	FailureCount int
+	// latency is only recorded when invocation results are reported
+	// asynchronously, e.g. via callback
+	LatencyAvgSeconds float64 `json:",omitempty"`
+	LatencyMaxSeconds float64 `json:",omitempty"`
+
+	latencyCount int
+	latencyTotal time.Duration
 }
 
 // Success records a successful invocation
@@ -32,6 +40,24 @@ func (i *InvocationDetails) Failure() {
 	i.FailureCount++
 }
 
+// Latency records the latency of an invocation
+func (i *InvocationDetails) Latency(d time.Duration) {
+	i.latencyCount++
+	i.latencyTotal += d
+
+	i.LatencyAvgSeconds = math.Round(i.latencyTotal.Seconds()/float64(i.latencyCount)*1000) / 1000 // 0.3f syntax
+	if seconds := math.Round(d.Seconds()*1000) / 1000; seconds > i.LatencyMaxSeconds {
+		i.LatencyMaxSeconds = seconds
+	}
+}
+
+// RejectionDetails contains the number of requests rejected by reason
+type RejectionDetails struct {
+	RateLimited  int `json:"rate_limited"`
+	BodyTooLarge int `json:"body_too_large"`
+	QueueFull    int `json:"queue_full"`
+}
+
 // EventStats are provided and continuously updated by event streams and
 // processors
 type EventStats struct {
@@ -42,7 +68,9 @@ type EventStats struct {
 	EventsTotal *int                          `json:"events_total,omitempty"`   // only used by event streams, total events received
 	EventsErr   *int                          `json:"events_err,omitempty"`     // only used by event streams, events received which lead to error
 	EventsSec   *float64                      `json:"events_per_sec,omitempty"` // only used by event streams
+	Rejected    *RejectionDetails             `json:"rejected,omitempty"`       // only used by event streams, requests rejected before processing
 	Invocations map[string]*InvocationDetails `json:"invocations,omitempty"`    // event.Category to success/failure invocations - only used by event processors
+	Functions   map[string]*InvocationDetails `json:"functions,omitempty"`      // function to success/failure invocations reported asynchronously - only used by event processors
 }
 
 func (s *EventStats) String() string {
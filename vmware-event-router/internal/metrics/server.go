@@ -84,6 +84,16 @@ func NewServer(cfg *config.MetricsProviderConfigDefault, log logger.Logger) (*Se
 		Logger: metricLog,
 	}
 
+	if cfg.TLS != nil {
+		tlsCfg, err := util.NewTLSConfig(cfg.TLS, metricLog)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not configure TLS")
+		}
+
+		metricLog.Infow("enabling TLS", "mutualTLS", cfg.TLS.ClientCAFile != "", "minVersion", cfg.TLS.MinVersion)
+		srv.http.TLSConfig = tlsCfg
+	}
+
 	return srv, nil
 }
 
@@ -94,10 +104,21 @@ func (s *Server) Run(ctx context.Context) error {
 	defer close(errCh)
 
 	go func() {
-		addr := fmt.Sprintf("http://%s%s", s.http.Addr, endpoint)
+		scheme := "http"
+		if s.http.TLSConfig != nil {
+			scheme = "https"
+		}
+
+		addr := fmt.Sprintf("%s://%s%s", scheme, s.http.Addr, endpoint)
 		s.Infow("starting metrics server", "address", addr)
 
-		err := s.http.ListenAndServe()
+		var err error
+		if s.http.TLSConfig != nil {
+			// certificates are provided by the TLS config
+			err = s.http.ListenAndServeTLS("", "")
+		} else {
+			err = s.http.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
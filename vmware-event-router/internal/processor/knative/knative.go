@@ -11,9 +11,11 @@ import (
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/embano1/waitgroup"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/resolver"
 
@@ -41,7 +43,8 @@ type Processor struct {
 	kConfig  *rest.Config
 	ceClient cloudevents.Client
 	sink     string
-	wg       waitgroup.WaitGroup // used in graceful shutdown
+	replies  processor.ReplyHandler // optional
+	wg       waitgroup.WaitGroup    // used in graceful shutdown
 
 	mu      sync.RWMutex
 	stopped bool // indicate whether the processor has been stopped
@@ -80,30 +83,11 @@ func NewProcessor(ctx context.Context, cfg *config.ProcessorConfigKnative, ms me
 		}
 	}
 
-	// assume in-cluster mode if empty
-	if p.kConfig == nil {
-		kCfg, err := rest.InClusterConfig()
-		if err != nil {
-			return nil, errors.Wrap(err, "get Kubernetes configuration")
-		}
-		p.kConfig = kCfg
-	}
-
-	// start Knative informers
-	ctx = logging.WithLogger(ctx, kLog.(*zap.SugaredLogger))
-	ctx, startInformers := injection.EnableInjectionOrDie(ctx, p.kConfig)
-	startInformers()
-
-	// placeholder type, works with any addressable, e.g. Broker, kService
-	var source corev1.Service
-
-	uriResolver := resolver.NewURIResolver(ctx, func(name types.NamespacedName) {})
-	uri, err := uriResolver.URIFromDestinationV1(ctx, *cfg.Destination, &source)
+	target, err := p.resolveDestination(ctx, cfg, kLog)
 	if err != nil {
-		return nil, errors.Wrap(err, "get URI from destination")
+		return nil, err
 	}
 
-	target := uri.String()
 	client, err := ceClient(target, cfg.InsecureSSL, cfg.Encoding)
 	if err != nil {
 		return nil, err
@@ -124,6 +108,52 @@ func NewProcessor(ctx context.Context, cfg *config.ProcessorConfigKnative, ms me
 	return &p, nil
 }
 
+// resolveDestination returns the URI of the configured destination. A plain URI
+// destination is used as is without connecting to Kubernetes. Otherwise the
+// destination is resolved with Knative informers using the custom REST
+// configuration, the configured kubeconfig or the in-cluster configuration.
+func (p *Processor) resolveDestination(ctx context.Context, cfg *config.ProcessorConfigKnative, log logger.Logger) (string, error) {
+	if cfg.Destination.Ref == nil && cfg.Destination.URI != nil {
+		return cfg.Destination.URI.String(), nil
+	}
+
+	if p.kConfig == nil {
+		var (
+			kCfg *rest.Config
+			err  error
+		)
+
+		// assume in-cluster mode if empty
+		if cfg.Kubeconfig != "" {
+			kCfg, err = clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+		} else {
+			kCfg, err = rest.InClusterConfig()
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "get Kubernetes configuration")
+		}
+		p.kConfig = kCfg
+	}
+
+	// start Knative informers
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		ctx = logging.WithLogger(ctx, zapSugared)
+	}
+	ctx, startInformers := injection.EnableInjectionOrDie(ctx, p.kConfig)
+	startInformers()
+
+	// placeholder type, works with any addressable, e.g. Broker, kService
+	var source corev1.Service
+
+	uriResolver := resolver.NewURIResolver(ctx, func(name types.NamespacedName) {})
+	uri, err := uriResolver.URIFromDestinationV1(ctx, *cfg.Destination, &source)
+	if err != nil {
+		return "", errors.Wrap(err, "get URI from destination")
+	}
+
+	return uri.String(), nil
+}
+
 // ceClient returns a cloud events client for the given URI and TLS insecure value
 func ceClient(uri string, insecure bool, encoding string) (ceclient.Client, error) {
 	tlsConfig := tls.Config{
@@ -179,22 +209,43 @@ func (p *Processor) Process(ctx context.Context, ce cloudevents.Event) error {
 	// register retry options
 	ctx = cloudevents.ContextWithRetriesExponentialBackoff(ctx, retryDelay, maxRetries)
 	p.Infow("sending event", "eventID", ce.ID(), "subject", subject)
-	result := p.ceClient.Send(ctx, ce)
+	reply, result := p.send(ctx, ce)
 	p.Debugw("got response", "eventID", ce.ID(), "response", result)
 
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	if !cloudevents.IsACK(result) {
 		p.stats.Invocations[subject].Failure()
+		p.mu.Unlock()
 		return processor.NewError(config.ProcessorKnative, errors.Wrapf(result, "send event %s", ce.ID()))
 	}
+	p.stats.Invocations[subject].Success()
+	p.mu.Unlock()
 
 	p.Infow("successfully sent event", "eventID", ce.ID())
-	p.stats.Invocations[subject].Success()
+	if reply != nil {
+		p.Debugw("received reply event from sink", "eventID", ce.ID(), "reply", reply)
+		p.replies(ctx, ce, *reply)
+	}
 	return nil
 }
 
+// send sends the given event to the sink. The reply event of the sink is only
+// returned if a reply handler is configured.
+func (p *Processor) send(ctx context.Context, ce cloudevents.Event) (*cloudevents.Event, protocol.Result) {
+	if p.replies == nil {
+		return nil, p.ceClient.Send(ctx, ce)
+	}
+
+	reply, result := p.ceClient.Request(ctx, ce)
+	if reply != nil {
+		if err := reply.Validate(); err != nil {
+			p.Warnw("ignoring invalid reply event from sink", "eventID", ce.ID(), "error", err)
+			reply = nil
+		}
+	}
+	return reply, result
+}
+
 func (p *Processor) PushMetrics(ctx context.Context, ms metrics.Receiver) {
 	ticker := time.NewTicker(metrics.PushInterval)
 	defer ticker.Stop()
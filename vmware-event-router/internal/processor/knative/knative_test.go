@@ -0,0 +1,133 @@
+//go:build unit
+// +build unit
+
+package knative
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+)
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+func Test_NewProcessorURIDestination(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("ce-id")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sink.Close()
+
+	uri, err := apis.ParseURL(sink.URL)
+	assert.NilError(t, err)
+
+	// no Kubernetes configuration is available in tests
+	cfg := config.ProcessorConfigKnative{
+		Destination: &duckv1.Destination{URI: uri},
+		Encoding:    "binary",
+		Kubeconfig:  filepath.Join(t.TempDir(), "missing"),
+	}
+
+	p, err := NewProcessor(ctx, &cfg, metricsStub{}, log)
+	assert.NilError(t, err)
+	assert.Equal(t, p.sink, sink.URL)
+
+	e := cloudevents.NewEvent()
+	e.SetID("42")
+	e.SetSource("https://vcenter-01:443/sdk")
+	e.SetType("com.vmware.event.router/event")
+	e.SetSubject("VmPoweredOnEvent")
+
+	assert.NilError(t, p.Process(ctx, e))
+	assert.Equal(t, <-received, "42")
+	assert.NilError(t, p.Shutdown(ctx))
+}
+
+func Test_NewProcessorRefDestination(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	cfg := config.ProcessorConfigKnative{
+		Destination: &duckv1.Destination{
+			Ref: &duckv1.KReference{
+				APIVersion: "eventing.knative.dev/v1",
+				Kind:       "Broker",
+				Name:       "default",
+				Namespace:  "default",
+			},
+		},
+		Encoding:   "binary",
+		Kubeconfig: filepath.Join(t.TempDir(), "missing"),
+	}
+
+	_, err := NewProcessor(context.Background(), &cfg, metricsStub{}, log)
+	assert.ErrorContains(t, err, "get Kubernetes configuration")
+}
+
+func Test_ProcessReply(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// reply with event in binary mode
+		w.Header().Set("ce-id", "reply-"+r.Header.Get("ce-id"))
+		w.Header().Set("ce-specversion", "1.0")
+		w.Header().Set("ce-type", "com.example.reply")
+		w.Header().Set("ce-source", "echo")
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("tagged vm-1"))
+	}))
+	defer sink.Close()
+
+	uri, err := apis.ParseURL(sink.URL)
+	assert.NilError(t, err)
+
+	cfg := config.ProcessorConfigKnative{
+		Destination: &duckv1.Destination{URI: uri},
+		Encoding:    "binary",
+	}
+
+	replies := make(chan cloudevents.Event, 1)
+	handler := func(ctx context.Context, event, reply cloudevents.Event) {
+		assert.Equal(t, event.ID(), "42")
+		replies <- reply
+	}
+
+	p, err := NewProcessor(ctx, &cfg, metricsStub{}, log, WithReplyHandler(handler))
+	assert.NilError(t, err)
+
+	e := cloudevents.NewEvent()
+	e.SetID("42")
+	e.SetSource("https://vcenter-01:443/sdk")
+	e.SetType("com.vmware.event.router/event")
+	e.SetSubject("VmPoweredOnEvent")
+
+	assert.NilError(t, p.Process(ctx, e))
+
+	reply := <-replies
+	assert.Equal(t, reply.ID(), "reply-42")
+	assert.Equal(t, reply.Type(), "com.example.reply")
+	assert.Equal(t, string(reply.Data()), "tagged vm-1")
+	assert.NilError(t, p.Shutdown(ctx))
+
+	_, err = NewProcessor(ctx, &cfg, metricsStub{}, log, WithReplyHandler(nil))
+	assert.ErrorContains(t, err, "reply handler")
+}
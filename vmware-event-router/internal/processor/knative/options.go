@@ -3,6 +3,8 @@ package knative
 import (
 	"github.com/pkg/errors"
 	"k8s.io/client-go/rest"
+
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
 )
 
 // Option configures the Knative processor
@@ -19,3 +21,14 @@ func WithRestConfig(cfg *rest.Config) Option {
 		return nil
 	}
 }
+
+// WithReplyHandler sets a handler for the reply events returned by the sink
+func WithReplyHandler(handler processor.ReplyHandler) Option {
+	return func(o *Processor) error {
+		if handler == nil {
+			return errors.New("no reply handler provided")
+		}
+		o.replies = handler
+		return nil
+	}
+}
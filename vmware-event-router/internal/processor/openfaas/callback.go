@@ -0,0 +1,264 @@
+package openfaas
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+const (
+	headerFunctionStatus = "X-Function-Status"
+
+	defaultCallbackTimeout = 5 * time.Minute
+	expireInterval         = time.Second
+)
+
+// pendingCall is an accepted asynchronous function invocation waiting for its
+// callback
+type pendingCall struct {
+	function string
+	message  []byte
+	started  time.Time // first invocation
+	accepted time.Time // current invocation
+	retries  uint
+}
+
+// callInvoker invokes the function of call asynchronously
+type callInvoker func(ctx context.Context, function string, message []byte, call pendingCall) ([]byte, int, http.Header, error)
+
+// resultFunc records the result and latency of an asynchronous function
+// invocation
+type resultFunc func(function string, success bool, latency time.Duration)
+
+// callbackReceiver receives the results of asynchronous function invocations
+// from the OpenFaaS queue worker and correlates them with pending calls by
+// call ID
+type callbackReceiver struct {
+	address string
+	path    string
+	timeout time.Duration
+	retry   bool
+	policy  retryPolicy
+	invoke  callInvoker
+	record  resultFunc
+	reply   replyFunc // optional, handles function responses
+	server  *http.Server
+	logger.Logger
+
+	ctx    context.Context // used for retries
+	cancel context.CancelFunc
+
+	lock  sync.Mutex
+	calls map[string]pendingCall
+}
+
+// newCallbackReceiver returns a callback receiver for the given configuration
+func newCallbackReceiver(cfg *config.OpenFaaSCallback, policy retryPolicy, record resultFunc, log logger.Logger) (*callbackReceiver, error) {
+	if cfg.BindAddress == "" {
+		return nil, errors.New("callback bind address must be specified")
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse callback URL")
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid callback URL %q: must be an absolute http(s) URL", cfg.URL)
+	}
+
+	if cfg.TimeoutSeconds < 0 {
+		return nil, errors.Errorf("invalid callback timeout: %d", cfg.TimeoutSeconds)
+	}
+
+	r := callbackReceiver{
+		address: cfg.BindAddress,
+		path:    u.Path,
+		timeout: defaultCallbackTimeout,
+		retry:   cfg.Retry,
+		policy:  policy,
+		record:  record,
+		Logger:  log,
+		calls:   make(map[string]pendingCall),
+	}
+
+	if r.path == "" {
+		r.path = "/"
+	}
+
+	if cfg.TimeoutSeconds > 0 {
+		r.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	return &r, nil
+}
+
+// start starts the callback server and the expiration of calls without
+// callback until the given context is cancelled or stop is called
+func (r *callbackReceiver) start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", r.address)
+	if err != nil {
+		return errors.Wrap(err, "start callback server")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(r.path, r)
+	r.server = &http.Server{Handler: mux}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	go func() {
+		r.Infow("starting callback server", "address", ln.Addr().String(), "path", r.path)
+		if err := r.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			r.Errorw("could not serve callbacks", "error", err)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(expireInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				_ = r.server.Close()
+				return
+			case now := <-ticker.C:
+				r.expire(now)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// stop gracefully shuts down the callback server and cancels pending retries
+func (r *callbackReceiver) stop(ctx context.Context) error {
+	if r.server == nil {
+		return nil
+	}
+
+	defer r.cancel()
+	return r.server.Shutdown(ctx)
+}
+
+// add registers a call waiting for a callback
+func (r *callbackReceiver) add(id string, call pendingCall) {
+	now := time.Now()
+	if call.started.IsZero() {
+		call.started = now
+	}
+	call.accepted = now
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls[id] = call
+}
+
+// remove removes the call with the given ID and returns it
+func (r *callbackReceiver) remove(id string) (pendingCall, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	call, ok := r.calls[id]
+	delete(r.calls, id)
+	return call, ok
+}
+
+// expire records calls without callback within the timeout as failed
+func (r *callbackReceiver) expire(now time.Time) {
+	var expired []pendingCall
+
+	r.lock.Lock()
+	for id, call := range r.calls {
+		if now.Sub(call.accepted) > r.timeout {
+			expired = append(expired, call)
+			delete(r.calls, id)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, call := range expired {
+		r.Warnw("no callback received for async function invocation", "function", call.function, "timeout", r.timeout)
+		r.record(call.function, false, now.Sub(call.started))
+	}
+}
+
+// ServeHTTP handles callbacks of the OpenFaaS queue worker
+func (r *callbackReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "could not read callback body", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	id := req.Header.Get(headerCallID)
+	call, ok := r.remove(id)
+	if !ok {
+		r.Debugw("ignoring callback for unknown call", "callID", id)
+		return
+	}
+
+	// missing or invalid status is treated as failure
+	status, _ := strconv.Atoi(req.Header.Get(headerFunctionStatus))
+	if isSuccessful(status, nil) && r.reply != nil {
+		r.reply(r.ctx, call.function, call.message, req.Header, body)
+	}
+	r.result(call, status, nil, req.Header)
+}
+
+// result records the result of the given call or retries the call if
+// retries are enabled and the status code is retryable
+func (r *callbackReceiver) result(call pendingCall, status int, err error, header http.Header) {
+	latency := time.Since(call.started)
+
+	if isSuccessful(status, err) {
+		r.Infow("successfully invoked async function", "function", call.function, "retries", call.retries, "latency", latency)
+		r.record(call.function, true, latency)
+		return
+	}
+
+	if r.retry && call.retries < r.policy.attempts {
+		if retryable, ctxErr := r.policy.isRetryable(r.ctx, status, err); retryable && ctxErr == nil {
+			after, exceeded := r.policy.exceedsBudget(call.retries, header)
+			if !exceeded {
+				go r.retryCall(call, header)
+				return
+			}
+			r.Warnw("not retrying async function: Retry-After exceeds retry budget", "function", call.function, "retryAfter", after, "retries", call.retries)
+		}
+	}
+
+	r.Errorw("could not invoke async function", "function", call.function, "status", status, "retries", call.retries, "error", err)
+	r.record(call.function, false, latency)
+}
+
+// retryCall invokes the given call again after the delay of the retry policy
+func (r *callbackReceiver) retryCall(call pendingCall, header http.Header) {
+	select {
+	case <-r.ctx.Done():
+		return
+	case <-time.After(r.policy.backoff(call.retries, header)):
+	}
+
+	call.retries++
+	_, status, header, err := r.invoke(r.ctx, call.function, call.message, call)
+	if !isSuccessful(status, err) {
+		r.result(call, status, err, header)
+	}
+}
@@ -0,0 +1,249 @@
+//go:build unit
+// +build unit
+
+package openfaas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+)
+
+// fakeGateway accepts async function invocations and reports the configured
+// function status codes via callback
+type fakeGateway struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	statuses    []int // function status per invocation
+	invocations int
+	callbackURL string
+}
+
+func newFakeGateway(t *testing.T, statuses ...int) *fakeGateway {
+	t.Helper()
+
+	g := fakeGateway{statuses: statuses}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/system/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/system/functions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"name":"echo","annotations":{"topic":"VmPoweredOnEvent"}}]`))
+	})
+	mux.HandleFunc("/async-function/echo", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		status := g.statuses[g.invocations%len(g.statuses)]
+		g.invocations++
+		g.callbackURL = r.Header.Get(headerCallbackURL)
+		g.mu.Unlock()
+
+		id := r.Header.Get(headerCallID)
+		w.WriteHeader(http.StatusAccepted)
+
+		// callback from queue worker
+		go func() {
+			req, err := http.NewRequest(http.MethodPost, r.Header.Get(headerCallbackURL), bytes.NewBufferString("result"))
+			if err != nil {
+				t.Errorf("create callback request: %v", err)
+				return
+			}
+			req.Header.Set(headerCallID, id)
+			req.Header.Set(headerFunctionStatus, strconv.Itoa(status))
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("send callback: %v", err)
+				return
+			}
+			_ = res.Body.Close()
+		}()
+	})
+
+	g.Server = httptest.NewServer(mux)
+	t.Cleanup(g.Close)
+
+	return &g
+}
+
+func (g *fakeGateway) calls() (int, string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.invocations, g.callbackURL
+}
+
+// freeAddress returns a local address with an unused port
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer ln.Close()
+
+	return ln.Addr().String()
+}
+
+// waitForResult waits until the function has the given number of recorded
+// results
+func waitForResult(t *testing.T, p *Processor, function string, results int) metrics.InvocationDetails {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		p.lock.RLock()
+		details, ok := p.stats.Functions[function]
+		if ok && details.SuccessCount+details.FailureCount == results {
+			d := *details
+			p.lock.RUnlock()
+			return d
+		}
+		p.lock.RUnlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %d results of function %q", results, function)
+	return metrics.InvocationDetails{}
+}
+
+func Test_callbackReceiver(t *testing.T) {
+	log := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel)).Sugar()
+
+	event := cloudevents.NewEvent()
+	event.SetID("42")
+	event.SetSource("https://vcenter-01:443/sdk")
+	event.SetType("com.vmware.event.router/event")
+	event.SetSubject("VmPoweredOnEvent")
+
+	attempts := 1
+	tests := []struct {
+		name            string
+		statuses        []int
+		retry           bool
+		wantInvocations int
+		wantSuccess     int
+		wantFailure     int
+	}{
+		{name: "function succeeds", statuses: []int{200}, wantInvocations: 1, wantSuccess: 1},
+		{name: "function fails without retry", statuses: []int{500}, wantInvocations: 1, wantFailure: 1},
+		{name: "function succeeds after retry", statuses: []int{503, 200}, retry: true, wantInvocations: 2, wantSuccess: 1},
+		{name: "function fails after retries", statuses: []int{503}, retry: true, wantInvocations: 2, wantFailure: 1},
+		{name: "function fails with non retryable status", statuses: []int{400}, retry: true, wantInvocations: 1, wantFailure: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			gw := newFakeGateway(t, tt.statuses...)
+			addr := freeAddress(t)
+			callbackURL := fmt.Sprintf("http://%s/callback", addr)
+
+			cfg := config.ProcessorConfigOpenFaaS{
+				Address: gw.URL,
+				Async:   true,
+				Retry: &config.OpenFaaSRetry{
+					Attempts:             &attempts,
+					DelayMilliseconds:    1,
+					MaxDelayMilliseconds: 10,
+				},
+				Callback: &config.OpenFaaSCallback{
+					BindAddress: addr,
+					URL:         callbackURL,
+					Retry:       tt.retry,
+				},
+			}
+
+			p, err := NewProcessor(ctx, &cfg, metricsStub{}, log)
+			assert.NilError(t, err)
+
+			assert.NilError(t, p.Process(ctx, event))
+
+			details := waitForResult(t, p, "echo", tt.wantSuccess+tt.wantFailure)
+			assert.Equal(t, details.SuccessCount, tt.wantSuccess)
+			assert.Equal(t, details.FailureCount, tt.wantFailure)
+			assert.Assert(t, details.LatencyAvgSeconds >= 0)
+
+			invocations, gotURL := gw.calls()
+			assert.Equal(t, invocations, tt.wantInvocations)
+			assert.Equal(t, gotURL, callbackURL)
+
+			assert.NilError(t, p.Shutdown(context.Background()))
+		})
+	}
+}
+
+func Test_callbackReceiverExpire(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	var (
+		mu      sync.Mutex
+		results = map[string]bool{}
+	)
+	record := func(function string, success bool, latency time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[function] = success
+	}
+
+	cfg := config.OpenFaaSCallback{BindAddress: "127.0.0.1:0", URL: "http://localhost:8081/callback", TimeoutSeconds: 60}
+	r, err := newCallbackReceiver(&cfg, defaultRetryPolicy, record, log)
+	assert.NilError(t, err)
+
+	r.add("1", pendingCall{function: "expired"})
+	r.add("2", pendingCall{function: "pending"})
+
+	r.lock.Lock()
+	call := r.calls["1"]
+	call.accepted = call.accepted.Add(-2 * time.Minute)
+	r.calls["1"] = call
+	r.lock.Unlock()
+
+	r.expire(time.Now())
+
+	_, ok := r.remove("1")
+	assert.Assert(t, !ok, "expired call must be removed")
+	_, ok = r.remove("2")
+	assert.Assert(t, ok, "pending call must not be removed")
+	assert.DeepEqual(t, results, map[string]bool{"expired": false})
+}
+
+func Test_newCallbackReceiver(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	tests := []struct {
+		name      string
+		cfg       config.OpenFaaSCallback
+		errString string
+	}{
+		{"no bind address", config.OpenFaaSCallback{URL: "http://localhost:8081"}, "callback bind address must be specified"},
+		{"relative URL", config.OpenFaaSCallback{BindAddress: ":8081", URL: "/callback"}, "must be an absolute http(s) URL"},
+		{"invalid scheme", config.OpenFaaSCallback{BindAddress: ":8081", URL: "nats://localhost:4222"}, "must be an absolute http(s) URL"},
+		{"negative timeout", config.OpenFaaSCallback{BindAddress: ":8081", URL: "http://localhost:8081", TimeoutSeconds: -1}, "invalid callback timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newCallbackReceiver(&tt.cfg, defaultRetryPolicy, nil, log)
+			assert.ErrorContains(t, err, tt.errString)
+		})
+	}
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
@@ -0,0 +1,268 @@
+package openfaas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	ofsdk "github.com/openfaas-incubator/connector-sdk/types"
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/pkg/errors"
+
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+const (
+	headerCallbackURL = "X-Callback-Url"
+	headerCallID      = "X-Call-Id"
+
+	syncRoute  = "function"
+	asyncRoute = "async-function"
+)
+
+// controller invokes the OpenFaaS functions subscribed to an event via their
+// topic annotation. The function to topic mapping is periodically retrieved
+// from the gateway and topics are matched by the router to support patterns
+// (see parseTopic).
+type controller struct {
+	functionURL string // gateway URL including function route
+	callbackURL string
+	client      *http.Client
+	lookup      functionLookup
+	interval    time.Duration
+	async       bool
+	binary      bool              // use binary content mode
+	calls       *callbackReceiver // only set in async mode with callback
+	limits      *limiter          // only set in sync mode
+	reply       replyFunc         // optional, handles sync function responses
+	logger.Logger
+
+	subsLock sync.RWMutex
+	subs     subscriptions
+
+	lock        sync.RWMutex
+	subscribers []ofsdk.ResponseSubscriber
+}
+
+// newController returns a controller invoking functions via the gateway
+// configured in cfg. If calls is not nil, async invocations pass callbackURL
+// to OpenFaaS and are tracked by calls. If limits is not nil, the concurrency
+// and timeout of invocations are limited per function.
+func newController(credentials *auth.BasicAuthCredentials, cfg *ofsdk.ControllerConfig, callbackURL string, calls *callbackReceiver, limits *limiter, log logger.Logger) *controller {
+	client := ofsdk.MakeClient(cfg.UpstreamTimeout)
+
+	route := syncRoute
+	if cfg.AsyncFunctionInvocation {
+		route = asyncRoute
+	}
+
+	c := controller{
+		functionURL: fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.GatewayURL, "/"), route),
+		callbackURL: callbackURL,
+		client:      client,
+		lookup: functionLookup{
+			gatewayURL:  cfg.GatewayURL,
+			client:      client,
+			credentials: credentials,
+			delimiter:   cfg.TopicAnnotationDelimiter,
+		},
+		interval: cfg.RebuildInterval,
+		async:    cfg.AsyncFunctionInvocation,
+		calls:    calls,
+		limits:   limits,
+		Logger:   log,
+	}
+
+	if limits != nil {
+		// invocations are bounded by the per function timeout instead of the
+		// client timeout. The function lookup keeps the upstream timeout, both
+		// share the same transport (connection pool).
+		invoker := *client
+		invoker.Timeout = 0
+		c.client = &invoker
+	}
+
+	return &c
+}
+
+// Subscribe adds a ResponseSubscriber receiving the responses of function
+// invocations
+func (c *controller) Subscribe(subscriber ofsdk.ResponseSubscriber) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.subscribers = append(c.subscribers, subscriber)
+}
+
+// InvokeEvent invokes all functions subscribed to the given event with message
+// and returns the number of matched functions. The responses of the
+// invocations are passed to the subscribers with the given topic and returned
+// on the response channel which is buffered for all matched functions.
+func (c *controller) InvokeEvent(ctx context.Context, event cloudevents.Event, topic string, message []byte) (int, <-chan ofsdk.InvokerResponse, error) {
+	if len(message) == 0 {
+		return 0, nil, ofsdk.ErrEmptyMessage
+	}
+
+	c.subsLock.RLock()
+	functions := c.subs.match(event)
+	c.subsLock.RUnlock()
+
+	resCh := make(chan ofsdk.InvokerResponse, len(functions))
+	for _, fn := range functions {
+		go func(function string) {
+			body, status, header, err := c.InvokeFunction(ctx, function, message)
+			if err != nil {
+				err = errors.Wrapf(err, "unable to invoke %s", function)
+			}
+
+			res := ofsdk.InvokerResponse{
+				Context:  ctx,
+				Body:     body,
+				Header:   header,
+				Status:   status,
+				Error:    err,
+				Topic:    topic,
+				Function: function,
+			}
+
+			c.lock.RLock()
+			for _, sub := range c.subscribers {
+				sub.Response(res)
+			}
+			c.lock.RUnlock()
+
+			resCh <- res
+		}(fn)
+	}
+
+	return len(functions), resCh, nil
+}
+
+// InvokeFunction invokes the given function and tracks async invocations with
+// the callback receiver (if configured). It returns the function response,
+// status code, headers and error.
+func (c *controller) InvokeFunction(ctx context.Context, function string, message []byte) ([]byte, int, http.Header, error) {
+	return c.invoke(ctx, function, message, pendingCall{function: function, message: message})
+}
+
+// invoke invokes the given function and registers the invocation as call with
+// the callback receiver (if configured). The call ID is set by the router so
+// the callback can be correlated even if it is received before the gateway
+// response. The invocation waits for or is rejected with errFunctionBusy if
+// the function concurrency limit is reached.
+func (c *controller) invoke(ctx context.Context, function string, message []byte, call pendingCall) ([]byte, int, http.Header, error) {
+	if c.limits != nil {
+		release, timeout, err := c.limits.acquire(ctx, function)
+		if errors.Is(err, errFunctionBusy) {
+			return nil, http.StatusTooManyRequests, nil, err
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, nil, err
+		}
+		defer release()
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	fnURL := fmt.Sprintf("%s/%s", c.functionURL, function)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fnURL, bytes.NewReader(message))
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	}
+
+	if c.binary {
+		if err = binaryRequest(ctx, req, message); err != nil {
+			return nil, http.StatusInternalServerError, nil, err
+		}
+	}
+
+	if c.calls == nil {
+		body, status, header, err := c.do(req)
+		// in async mode the response is the acknowledgement of the gateway
+		if !c.async && c.reply != nil && isSuccessful(status, err) {
+			c.reply(ctx, function, message, header, body)
+		}
+		return body, status, header, err
+	}
+
+	id := uuid.New().String()
+	req.Header.Set(headerCallID, id)
+	req.Header.Set(headerCallbackURL, c.callbackURL)
+
+	c.calls.add(id, call)
+	body, status, header, err := c.do(req)
+	if !isSuccessful(status, err) {
+		c.calls.remove(id)
+	}
+
+	return body, status, header, err
+}
+
+// do sends the request and returns the response body, status code and
+// headers
+func (c *controller) do(req *http.Request) ([]byte, int, http.Header, error) {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	}
+
+	return body, res.StatusCode, res.Header, nil
+}
+
+// BeginMapBuilder periodically synchronizes the function subscriptions with
+// the gateway
+func (c *controller) BeginMapBuilder() {
+	build := func() {
+		lookups, annotations, err := c.lookup.build()
+		if err != nil {
+			c.Errorw("could not synchronize functions with gateway", "error", err)
+			return
+		}
+
+		if c.limits != nil {
+			for function, err := range c.limits.update(annotations) {
+				c.Warnw("ignoring invalid limits in function annotations", "function", function, "error", err)
+			}
+		}
+
+		subs := newSubscriptions(lookups)
+		c.subsLock.Lock()
+		c.subs = subs
+		c.subsLock.Unlock()
+	}
+
+	build()
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			build()
+		}
+	}()
+}
+
+// Topics returns the topics functions are subscribed to
+func (c *controller) Topics() []string {
+	c.subsLock.RLock()
+	defer c.subsLock.RUnlock()
+	return c.subs.topics()
+}
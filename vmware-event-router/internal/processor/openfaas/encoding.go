@@ -0,0 +1,71 @@
+package openfaas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/pkg/errors"
+)
+
+// supported encodings of the event in function invocations
+const (
+	encodingStructured = "structured" // JSON-encoded event as body
+	encodingBinary     = "binary"     // event attributes as ce-* headers and event data as body
+)
+
+// replyFunc handles the response of a successful invocation of function with
+// the JSON-encoded event message
+type replyFunc func(ctx context.Context, function string, message []byte, header http.Header, body []byte)
+
+// binaryRequest encodes the JSON-encoded event message in binary content
+// mode, i.e. sets the event attributes as ce-* headers of req and the event
+// data as request body
+func binaryRequest(ctx context.Context, req *http.Request, message []byte) error {
+	var event cloudevents.Event
+	if err := json.Unmarshal(message, &event); err != nil {
+		return errors.Wrap(err, "JSON-decode CloudEvent")
+	}
+
+	// the request is created with the structured event as body which is only
+	// replaced if the event has data
+	req.Body = http.NoBody
+	req.ContentLength = 0
+	req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+
+	ctx = binding.WithForceBinary(ctx)
+	if err := cehttp.WriteRequest(ctx, binding.ToMessage(&event), req); err != nil {
+		return errors.Wrap(err, "encode CloudEvent in binary mode")
+	}
+	return nil
+}
+
+// replyEvent returns the CloudEvent of a function response in binary or
+// structured content mode. If the response is not a CloudEvent false is
+// returned.
+func replyEvent(ctx context.Context, header http.Header, body []byte) (*cloudevents.Event, bool, error) {
+	msg := cehttp.NewMessage(header, ioutil.NopCloser(bytes.NewReader(body)))
+	defer func() {
+		_ = msg.Finish(nil)
+	}()
+
+	if msg.ReadEncoding() == binding.EncodingUnknown {
+		return nil, false, nil
+	}
+
+	event, err := binding.ToEvent(ctx, msg)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "decode CloudEvent")
+	}
+
+	if err = event.Validate(); err != nil {
+		return nil, true, errors.Wrap(err, "invalid CloudEvent")
+	}
+	return event, true, nil
+}
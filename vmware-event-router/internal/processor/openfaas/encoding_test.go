@@ -0,0 +1,182 @@
+//go:build unit
+// +build unit
+
+package openfaas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+func newDataEvent(t *testing.T) cloudevents.Event {
+	t.Helper()
+
+	e := newTestEvent("com.vmware.event.router/event", "https://vcenter-01:443/sdk", "VmPoweredOnEvent")
+	assert.NilError(t, e.SetData(cloudevents.ApplicationJSON, map[string]string{"vm": "vm-1"}))
+	return e
+}
+
+func Test_binaryRequest(t *testing.T) {
+	event := newDataEvent(t)
+	message, err := json.Marshal(event)
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "http://gateway:8080/function/echo", nil)
+	assert.NilError(t, binaryRequest(context.Background(), req, message))
+
+	assert.Equal(t, req.Header.Get("ce-id"), "42")
+	assert.Equal(t, req.Header.Get("ce-specversion"), "1.0")
+	assert.Equal(t, req.Header.Get("ce-type"), "com.vmware.event.router/event")
+	assert.Equal(t, req.Header.Get("ce-source"), "https://vcenter-01:443/sdk")
+	assert.Equal(t, req.Header.Get("ce-subject"), "VmPoweredOnEvent")
+	assert.Equal(t, req.Header.Get("Content-Type"), cloudevents.ApplicationJSON)
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NilError(t, err)
+	assert.Equal(t, string(body), `{"vm":"vm-1"}`)
+
+	err = binaryRequest(context.Background(), req, []byte("not an event"))
+	assert.ErrorContains(t, err, "JSON-decode CloudEvent")
+}
+
+func Test_binaryRequestWithoutData(t *testing.T) {
+	event := newTestEvent("com.vmware.event.router/event", "https://vcenter-01:443/sdk", "VmPoweredOnEvent")
+	message, err := json.Marshal(event)
+	assert.NilError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "http://gateway:8080/function/echo", bytes.NewReader(message))
+	assert.NilError(t, err)
+	assert.NilError(t, binaryRequest(context.Background(), req, message))
+
+	assert.Equal(t, req.Header.Get("ce-id"), "42")
+	assert.Equal(t, req.ContentLength, int64(0))
+
+	body, err := ioutil.ReadAll(req.Body)
+	assert.NilError(t, err)
+	assert.Equal(t, len(body), 0)
+
+	rc, err := req.GetBody()
+	assert.NilError(t, err)
+	body, err = ioutil.ReadAll(rc)
+	assert.NilError(t, err)
+	assert.Equal(t, len(body), 0)
+}
+
+func Test_replyEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		body      string
+		wantEvent bool
+		wantID    string
+		errString string
+	}{
+		{
+			name:      "binary event",
+			header:    http.Header{"Ce-Id": {"1"}, "Ce-Specversion": {"1.0"}, "Ce-Type": {"reply"}, "Ce-Source": {"fn"}, "Content-Type": {"text/plain"}},
+			body:      "done",
+			wantEvent: true,
+			wantID:    "1",
+		},
+		{
+			name:      "structured event",
+			header:    http.Header{"Content-Type": {"application/cloudevents+json"}},
+			body:      `{"id":"2","specversion":"1.0","type":"reply","source":"fn"}`,
+			wantEvent: true,
+			wantID:    "2",
+		},
+		{
+			name:   "no event",
+			header: http.Header{"Content-Type": {"text/plain"}},
+			body:   "OK",
+		},
+		{
+			name:      "invalid event",
+			header:    http.Header{"Ce-Id": {"1"}, "Ce-Specversion": {"1.0"}},
+			body:      "done",
+			wantEvent: true,
+			errString: "invalid CloudEvent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok, err := replyEvent(context.Background(), tt.header, []byte(tt.body))
+			assert.Equal(t, ok, tt.wantEvent)
+
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+
+			assert.NilError(t, err)
+			if tt.wantEvent {
+				assert.Equal(t, event.ID(), tt.wantID)
+			}
+		})
+	}
+}
+
+func Test_ProcessBinaryEncoding(t *testing.T) {
+	log := zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel)).Sugar()
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/system/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/system/functions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"name":"echo","annotations":{"topic":"VmPoweredOnEvent"}}]`))
+	})
+	mux.HandleFunc("/function/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("ce-id") != "42" || string(body) != `{"vm":"vm-1"}` {
+			http.Error(w, "expected binary event", http.StatusBadRequest)
+			return
+		}
+
+		// reply with event in binary mode
+		w.Header().Set("ce-id", "reply-42")
+		w.Header().Set("ce-specversion", "1.0")
+		w.Header().Set("ce-type", "com.example.reply")
+		w.Header().Set("ce-source", "echo")
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("tagged vm-1"))
+	})
+
+	gw := httptest.NewServer(mux)
+	defer gw.Close()
+
+	replies := make(chan cloudevents.Event, 1)
+	handler := func(ctx context.Context, event, reply cloudevents.Event) {
+		assert.Equal(t, event.ID(), "42")
+		replies <- reply
+	}
+
+	cfg := config.ProcessorConfigOpenFaaS{Address: gw.URL, Encoding: "binary"}
+	p, err := NewProcessor(ctx, &cfg, metricsStub{}, log, WithReplyHandler(handler))
+	assert.NilError(t, err)
+
+	assert.NilError(t, p.Process(ctx, newDataEvent(t)))
+
+	reply := <-replies
+	assert.Equal(t, reply.ID(), "reply-42")
+	assert.Equal(t, reply.Type(), "com.example.reply")
+	assert.Equal(t, string(reply.Data()), "tagged vm-1")
+
+	assert.NilError(t, p.Shutdown(ctx))
+
+	_, err = NewProcessor(ctx, &config.ProcessorConfigOpenFaaS{Address: gw.URL, Encoding: "xml"}, metricsStub{}, log)
+	assert.ErrorContains(t, err, `unsupported encoding type specified: "xml"`)
+}
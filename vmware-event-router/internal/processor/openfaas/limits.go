@@ -0,0 +1,207 @@
+package openfaas
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+// function annotations to configure limits in OpenFaaS
+const (
+	annotationMaxConcurrency = "com.vmware.event.router/max-concurrency"
+	annotationTimeout        = "com.vmware.event.router/timeout-seconds"
+	annotationQueuePolicy    = "com.vmware.event.router/queue-policy"
+)
+
+// errFunctionBusy is returned when an invocation is rejected because the
+// function concurrency limit is reached
+var errFunctionBusy = errors.New("function concurrency limit reached")
+
+// functionLimits are the concurrency limit and timeout of a function. Zero
+// values are unset.
+type functionLimits struct {
+	maxConcurrency int
+	timeout        time.Duration
+	policy         config.OpenFaaSQueuePolicy
+}
+
+// merge returns l with unset values replaced by the values of defaults
+func (l functionLimits) merge(defaults functionLimits) functionLimits {
+	if l.maxConcurrency == 0 {
+		l.maxConcurrency = defaults.maxConcurrency
+	}
+	if l.timeout == 0 {
+		l.timeout = defaults.timeout
+	}
+	if l.policy == "" {
+		l.policy = defaults.policy
+	}
+	return l
+}
+
+// newFunctionLimits validates and returns the limits for the given values
+func newFunctionLimits(maxConcurrency, timeoutSeconds int, policy config.OpenFaaSQueuePolicy) (functionLimits, error) {
+	if maxConcurrency < 0 {
+		return functionLimits{}, errors.Errorf("invalid max concurrency: %d", maxConcurrency)
+	}
+
+	if timeoutSeconds < 0 {
+		return functionLimits{}, errors.Errorf("invalid timeout: %d", timeoutSeconds)
+	}
+
+	switch policy {
+	case "", config.QueuePolicyWait, config.QueuePolicyReject:
+	default:
+		return functionLimits{}, errors.Errorf("invalid queue policy %q", policy)
+	}
+
+	return functionLimits{
+		maxConcurrency: maxConcurrency,
+		timeout:        time.Duration(timeoutSeconds) * time.Second,
+		policy:         policy,
+	}, nil
+}
+
+// parseAnnotations returns the limits set with the given function annotations
+func parseAnnotations(annotations map[string]string) (functionLimits, error) {
+	atoi := func(key string) (int, error) {
+		value, ok := annotations[key]
+		if !ok {
+			return 0, nil
+		}
+
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, errors.Wrapf(err, "parse annotation %q", key)
+		}
+		return i, nil
+	}
+
+	maxConcurrency, err := atoi(annotationMaxConcurrency)
+	if err != nil {
+		return functionLimits{}, err
+	}
+
+	timeout, err := atoi(annotationTimeout)
+	if err != nil {
+		return functionLimits{}, err
+	}
+
+	policy := config.OpenFaaSQueuePolicy(annotations[annotationQueuePolicy])
+	return newFunctionLimits(maxConcurrency, timeout, policy)
+}
+
+// limiter enforces the concurrency limits and timeouts of function
+// invocations. Limits configured in the router take precedence over limits set
+// with function annotations.
+type limiter struct {
+	defaults   functionLimits
+	configured map[string]functionLimits
+
+	lock      sync.Mutex
+	annotated map[string]functionLimits
+	slots     map[string]chan struct{} // semaphore per function
+}
+
+// newLimiter returns a limiter for the given function configurations using
+// timeout as the default invocation timeout
+func newLimiter(cfgs []config.OpenFaaSFunction, timeout time.Duration) (*limiter, error) {
+	l := limiter{
+		defaults:   functionLimits{timeout: timeout, policy: config.QueuePolicyWait},
+		configured: make(map[string]functionLimits),
+		annotated:  make(map[string]functionLimits),
+		slots:      make(map[string]chan struct{}),
+	}
+
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, errors.New("function name must be specified")
+		}
+
+		if _, ok := l.configured[cfg.Name]; ok {
+			return nil, errors.Errorf("duplicate configuration for function %q", cfg.Name)
+		}
+
+		limits, err := newFunctionLimits(cfg.MaxConcurrency, cfg.TimeoutSeconds, cfg.QueuePolicy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "function %q", cfg.Name)
+		}
+		l.configured[cfg.Name] = limits
+	}
+
+	return &l, nil
+}
+
+// update replaces the limits set with function annotations. Functions with
+// invalid annotations are returned with the parse error and use the defaults.
+func (l *limiter) update(annotations map[string]map[string]string) map[string]error {
+	annotated := make(map[string]functionLimits)
+	invalid := make(map[string]error)
+
+	for function, values := range annotations {
+		limits, err := parseAnnotations(values)
+		if err != nil {
+			invalid[function] = err
+			continue
+		}
+		annotated[function] = limits
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.annotated = annotated
+
+	return invalid
+}
+
+// limits returns the effective limits of the given function
+func (l *limiter) limits(function string) functionLimits {
+	l.lock.Lock()
+	annotated := l.annotated[function]
+	l.lock.Unlock()
+
+	return l.configured[function].merge(annotated).merge(l.defaults)
+}
+
+// acquire waits for or, depending on the queue policy, rejects an invocation
+// slot of the given function. It returns a function to release the slot and
+// the invocation timeout.
+func (l *limiter) acquire(ctx context.Context, function string) (func(), time.Duration, error) {
+	limits := l.limits(function)
+	if limits.maxConcurrency == 0 {
+		return func() {}, limits.timeout, nil
+	}
+
+	l.lock.Lock()
+	slots, ok := l.slots[function]
+	// the limit might have changed, inflight invocations release the slot of
+	// the previous semaphore
+	if !ok || cap(slots) != limits.maxConcurrency {
+		slots = make(chan struct{}, limits.maxConcurrency)
+		l.slots[function] = slots
+	}
+	l.lock.Unlock()
+
+	release := func() { <-slots }
+
+	if limits.policy == config.QueuePolicyReject {
+		select {
+		case slots <- struct{}{}:
+			return release, limits.timeout, nil
+		default:
+			return nil, 0, errFunctionBusy
+		}
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return release, limits.timeout, nil
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+}
@@ -0,0 +1,171 @@
+//go:build unit
+// +build unit
+
+package openfaas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+func Test_newLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfgs      []config.OpenFaaSFunction
+		errString string
+	}{
+		{"valid configuration", []config.OpenFaaSFunction{{Name: "fn", MaxConcurrency: 1, TimeoutSeconds: 60, QueuePolicy: config.QueuePolicyReject}}, ""},
+		{"no function name", []config.OpenFaaSFunction{{MaxConcurrency: 1}}, "function name must be specified"},
+		{"duplicate function", []config.OpenFaaSFunction{{Name: "fn"}, {Name: "fn"}}, `duplicate configuration for function "fn"`},
+		{"negative max concurrency", []config.OpenFaaSFunction{{Name: "fn", MaxConcurrency: -1}}, "invalid max concurrency: -1"},
+		{"negative timeout", []config.OpenFaaSFunction{{Name: "fn", TimeoutSeconds: -1}}, "invalid timeout: -1"},
+		{"invalid queue policy", []config.OpenFaaSFunction{{Name: "fn", QueuePolicy: "drop"}}, `invalid queue policy "drop"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLimiter(tt.cfgs, defaultTimeout)
+			if tt.errString == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errString)
+		})
+	}
+}
+
+func Test_limiterLimits(t *testing.T) {
+	l, err := newLimiter([]config.OpenFaaSFunction{
+		{Name: "configured", MaxConcurrency: 2},
+		{Name: "both", TimeoutSeconds: 60},
+	}, defaultTimeout)
+	assert.NilError(t, err)
+
+	invalid := l.update(map[string]map[string]string{
+		"both": {
+			annotationMaxConcurrency: "5",
+			annotationTimeout:        "30",
+			annotationQueuePolicy:    "reject",
+		},
+		"annotated": {annotationTimeout: "120"},
+		"invalid":   {annotationMaxConcurrency: "many"},
+	})
+	assert.Equal(t, len(invalid), 1)
+	assert.ErrorContains(t, invalid["invalid"], annotationMaxConcurrency)
+
+	tests := []struct {
+		function string
+		want     functionLimits
+	}{
+		{"configured", functionLimits{maxConcurrency: 2, timeout: defaultTimeout, policy: config.QueuePolicyWait}},
+		{"both", functionLimits{maxConcurrency: 5, timeout: time.Minute, policy: config.QueuePolicyReject}},
+		{"annotated", functionLimits{timeout: 2 * time.Minute, policy: config.QueuePolicyWait}},
+		{"invalid", functionLimits{timeout: defaultTimeout, policy: config.QueuePolicyWait}},
+		{"unknown", functionLimits{timeout: defaultTimeout, policy: config.QueuePolicyWait}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			assert.Equal(t, l.limits(tt.function), tt.want)
+		})
+	}
+}
+
+func Test_limiterAcquire(t *testing.T) {
+	l, err := newLimiter([]config.OpenFaaSFunction{
+		{Name: "wait", MaxConcurrency: 1},
+		{Name: "reject", MaxConcurrency: 1, QueuePolicy: config.QueuePolicyReject},
+	}, defaultTimeout)
+	assert.NilError(t, err)
+
+	ctx := context.Background()
+
+	t.Run("unlimited function", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_, timeout, err := l.acquire(ctx, "unlimited")
+			assert.NilError(t, err)
+			assert.Equal(t, timeout, defaultTimeout)
+		}
+	})
+
+	t.Run("reject invocation exceeding limit", func(t *testing.T) {
+		release, _, err := l.acquire(ctx, "reject")
+		assert.NilError(t, err)
+
+		_, _, err = l.acquire(ctx, "reject")
+		assert.Equal(t, err, errFunctionBusy)
+
+		release()
+		release, _, err = l.acquire(ctx, "reject")
+		assert.NilError(t, err)
+		release()
+	})
+
+	t.Run("wait for invocation exceeding limit", func(t *testing.T) {
+		release, _, err := l.acquire(ctx, "wait")
+		assert.NilError(t, err)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, _, err = l.acquire(waitCtx, "wait")
+		assert.Equal(t, err, context.DeadlineExceeded)
+
+		acquired := make(chan error)
+		go func() {
+			release, _, err := l.acquire(ctx, "wait")
+			if err == nil {
+				release()
+			}
+			acquired <- err
+		}()
+
+		release()
+		assert.NilError(t, <-acquired)
+	})
+}
+
+func Test_ProcessRejectsBusyFunction(t *testing.T) {
+	log := zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel)).Sugar()
+	ctx := context.Background()
+
+	started := make(chan struct{}, 1)
+	gw := newSyncGateway(t, started)
+
+	noRetries := 0
+	cfg := config.ProcessorConfigOpenFaaS{
+		Address:   gw.URL,
+		Retry:     &config.OpenFaaSRetry{Attempts: &noRetries},
+		Functions: []config.OpenFaaSFunction{{Name: "slow", MaxConcurrency: 1, TimeoutSeconds: 1, QueuePolicy: config.QueuePolicyReject}},
+	}
+	p, err := NewProcessor(ctx, &cfg, metricsStub{}, log)
+	assert.NilError(t, err)
+
+	// the first invocation blocks until the function timeout is exceeded
+	first := make(chan error)
+	go func() {
+		first <- p.Process(ctx, newSubjectEvent("1", "VmSuspendedEvent"))
+	}()
+	<-started
+
+	assert.ErrorContains(t, p.Process(ctx, newSubjectEvent("2", "VmSuspendedEvent")), errFunctionBusy.Error())
+
+	assert.ErrorContains(t, <-first, "deadline exceeded")
+	assert.NilError(t, p.Shutdown(ctx))
+}
+
+func Test_NewProcessorFunctionLimits(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	cfg := config.ProcessorConfigOpenFaaS{
+		Address:   "http://127.0.0.1:0",
+		Async:     true,
+		Functions: []config.OpenFaaSFunction{{Name: "fn", MaxConcurrency: 1}},
+	}
+	_, err := NewProcessor(context.Background(), &cfg, metricsStub{}, log)
+	assert.ErrorContains(t, err, "function limits require sync mode")
+}
@@ -0,0 +1,133 @@
+package openfaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openfaas/faas-provider/auth"
+	"github.com/openfaas/faas-provider/types"
+	"github.com/pkg/errors"
+)
+
+const annotationTopic = "topic"
+
+// functionLookup retrieves the functions deployed in the OpenFaaS gateway. In
+// contrast to the connector-sdk FunctionLookupBuilder it also returns the
+// function annotations.
+type functionLookup struct {
+	gatewayURL  string
+	client      *http.Client
+	credentials *auth.BasicAuthCredentials
+	delimiter   string // topic annotation delimiter
+}
+
+// build returns the functions subscribed to each topic and the annotations of
+// all functions. Functions in a namespace are named "<function>.<namespace>".
+func (l functionLookup) build() (map[string][]string, map[string]map[string]string, error) {
+	var namespaces []string
+	if err := l.get("/system/namespaces", nil, &namespaces); err != nil {
+		return nil, nil, errors.Wrap(err, "get namespaces")
+	}
+
+	// gateway without namespace support
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	lookups := make(map[string][]string)
+	annotations := make(map[string]map[string]string)
+
+	for _, ns := range namespaces {
+		var query url.Values
+		if ns != "" {
+			query = url.Values{"namespace": []string{ns}}
+		}
+
+		var functions []types.FunctionStatus
+		if err := l.get("/system/functions", query, &functions); err != nil {
+			return nil, nil, errors.Wrapf(err, "get functions in namespace %q", ns)
+		}
+
+		for _, fn := range functions {
+			if fn.Annotations == nil {
+				continue
+			}
+
+			name := fn.Name
+			if ns != "" {
+				name = fmt.Sprintf("%s.%s", fn.Name, ns)
+			}
+			annotations[name] = *fn.Annotations
+
+			for _, topic := range l.topics((*fn.Annotations)[annotationTopic]) {
+				lookups[topic] = append(lookups[topic], name)
+			}
+		}
+	}
+
+	return lookups, annotations, nil
+}
+
+// topics returns the topics of the given topic annotation value
+func (l functionLookup) topics(annotation string) []string {
+	values := []string{annotation}
+	if l.delimiter != "" {
+		values = strings.Split(annotation, l.delimiter)
+	}
+
+	var topics []string
+	for _, topic := range values {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// get decodes the JSON response of the given gateway API path into v
+func (l functionLookup) get(path string, query url.Values, v interface{}) error {
+	u := strings.TrimSuffix(l.gatewayURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+
+	if l.credentials != nil {
+		req.SetBasicAuth(l.credentials.User, l.credentials.Password)
+	}
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return errors.Errorf("authentication failure against gateway: %s", http.StatusText(res.StatusCode))
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode > 299 {
+		return errors.Errorf("unexpected HTTP response: %s", http.StatusText(res.StatusCode))
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response body")
+	}
+
+	if err = json.Unmarshal(body, v); err != nil {
+		return errors.Wrap(err, "unmarshal JSON")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+//go:build unit
+// +build unit
+
+package openfaas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openfaas/faas-provider/auth"
+	"gotest.tools/assert"
+)
+
+func Test_functionLookup(t *testing.T) {
+	functions := map[string]string{
+		"openfaas-fn": `[
+			{"name":"echo","annotations":{"topic":"VmPoweredOnEvent, VmPoweredOffEvent"}},
+			{"name":"no-annotations"}
+		]`,
+		"dev": `[{"name":"echo","annotations":{"topic":"VmPoweredOnEvent","com.vmware.event.router/max-concurrency":"1"}}]`,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/system/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`["openfaas-fn","dev"]`))
+	})
+	mux.HandleFunc("/system/functions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(functions[r.URL.Query().Get("namespace")]))
+	})
+
+	gw := httptest.NewServer(mux)
+	defer gw.Close()
+
+	t.Run("build topics and annotations", func(t *testing.T) {
+		l := functionLookup{
+			gatewayURL:  gw.URL,
+			client:      gw.Client(),
+			credentials: &auth.BasicAuthCredentials{User: "admin", Password: "secret"},
+			delimiter:   defaultTopicDelimiter,
+		}
+
+		lookups, annotations, err := l.build()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, lookups, map[string][]string{
+			"VmPoweredOnEvent":  {"echo.openfaas-fn", "echo.dev"},
+			"VmPoweredOffEvent": {"echo.openfaas-fn"},
+		})
+		assert.Equal(t, annotations["echo.dev"][annotationMaxConcurrency], "1")
+		assert.Equal(t, len(annotations), 2)
+	})
+
+	t.Run("authentication failure", func(t *testing.T) {
+		l := functionLookup{gatewayURL: gw.URL, client: gw.Client()}
+
+		_, _, err := l.build()
+		assert.ErrorContains(t, err, "authentication failure against gateway")
+	})
+}
@@ -0,0 +1,135 @@
+package openfaas
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// event attributes functions can subscribe to in their topic annotation using
+// the "<attribute>:<pattern>" syntax. Topics without attribute match the event
+// subject.
+const (
+	attributeSubject = "subject"
+	attributeType    = "type"
+	attributeSource  = "source"
+	attributeVCenter = "vcenter" // host of the event source
+)
+
+// topicPattern matches an event attribute against a topic which may contain
+// "*" (any sequence of characters) and "?" (any single character) wildcards
+type topicPattern struct {
+	attribute string
+	value     string         // matched literally if the topic has no wildcards
+	re        *regexp.Regexp // set if the topic has wildcards
+}
+
+// parseTopic returns the pattern for the given topic annotation value
+func parseTopic(topic string) topicPattern {
+	topic = strings.TrimSpace(topic)
+	p := topicPattern{attribute: attributeSubject}
+
+	if i := strings.Index(topic, ":"); i > 0 {
+		switch attr := strings.ToLower(topic[:i]); attr {
+		case attributeSubject, attributeType, attributeSource, attributeVCenter:
+			p.attribute = attr
+			topic = strings.TrimSpace(topic[i+1:])
+		}
+	}
+
+	p.value = topic
+	if !strings.ContainsAny(topic, "*?") {
+		return p
+	}
+
+	expr := regexp.QuoteMeta(topic)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	p.re = regexp.MustCompile("^" + expr + "$")
+
+	return p
+}
+
+// match returns true if the attribute of the given event matches the pattern
+func (p topicPattern) match(event cloudevents.Event) bool {
+	var value string
+
+	switch p.attribute {
+	case attributeType:
+		value = event.Type()
+	case attributeSource:
+		value = event.Source()
+	case attributeVCenter:
+		u, err := url.Parse(event.Source())
+		if err != nil {
+			return false
+		}
+		value = u.Hostname()
+	default:
+		value = event.Subject()
+	}
+
+	if p.re != nil {
+		return p.re.MatchString(value)
+	}
+	return value == p.value
+}
+
+// subscription is a topic pattern and the functions subscribed to it
+type subscription struct {
+	topic     string
+	pattern   topicPattern
+	functions []string
+}
+
+// subscriptions are the function subscriptions of the OpenFaaS gateway
+type subscriptions []subscription
+
+// newSubscriptions returns the subscriptions for the given topic to functions
+// lookup of the OpenFaaS gateway
+func newSubscriptions(lookups map[string][]string) subscriptions {
+	subs := make(subscriptions, 0, len(lookups))
+	for topic, functions := range lookups {
+		subs = append(subs, subscription{
+			topic:     topic,
+			pattern:   parseTopic(topic),
+			functions: functions,
+		})
+	}
+	return subs
+}
+
+// match returns the sorted functions subscribed to the given event. Functions
+// matching the event with multiple topics are only returned once.
+func (s subscriptions) match(event cloudevents.Event) []string {
+	matched := make(map[string]bool)
+	for _, sub := range s {
+		if !sub.pattern.match(event) {
+			continue
+		}
+
+		for _, fn := range sub.functions {
+			matched[fn] = true
+		}
+	}
+
+	functions := make([]string, 0, len(matched))
+	for fn := range matched {
+		functions = append(functions, fn)
+	}
+	sort.Strings(functions)
+
+	return functions
+}
+
+// topics returns the subscribed topics
+func (s subscriptions) topics() []string {
+	topics := make([]string, 0, len(s))
+	for _, sub := range s {
+		topics = append(topics, sub.topic)
+	}
+	return topics
+}
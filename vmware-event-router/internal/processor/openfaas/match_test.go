@@ -0,0 +1,96 @@
+//go:build unit
+// +build unit
+
+package openfaas
+
+import (
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func newTestEvent(eventType, source, subject string) cloudevents.Event {
+	e := cloudevents.NewEvent()
+	e.SetID("42")
+	e.SetType(eventType)
+	e.SetSource(source)
+	e.SetSubject(subject)
+	return e
+}
+
+func Test_topicPattern(t *testing.T) {
+	vmEvent := newTestEvent("com.vmware.event.router/event", "https://vcenter-01:443/sdk", "VmPoweredOnEvent")
+	eventEx := newTestEvent("com.vmware.event.router/eventex", "https://vcenter-02:443/sdk", "com.vmware.applmgmt.backup.job.failed.event")
+
+	tests := []struct {
+		topic string
+		event cloudevents.Event
+		want  bool
+	}{
+		{topic: "VmPoweredOnEvent", event: vmEvent, want: true},
+		{topic: " VmPoweredOnEvent ", event: vmEvent, want: true},
+		{topic: "VmPoweredOffEvent", event: vmEvent, want: false},
+		{topic: "VmPowered", event: vmEvent, want: false},
+		{topic: "Vm*Event", event: vmEvent, want: true},
+		{topic: "Vm*Event", event: eventEx, want: false},
+		{topic: "VmPowered??Event", event: vmEvent, want: true},
+		{topic: "*", event: vmEvent, want: true},
+		{topic: "com.vmware.applmgmt.*", event: eventEx, want: true},
+		{topic: "com.vmware.applmgmt.*", event: vmEvent, want: false},
+		{topic: "com.vmware.applmgmt.backup.job.failed.event", event: eventEx, want: true},
+		{topic: "subject:Vm*", event: vmEvent, want: true},
+		{topic: "type:com.vmware.event.router/eventex", event: eventEx, want: true},
+		{topic: "type:com.vmware.event.router/eventex", event: vmEvent, want: false},
+		{topic: "type:*/eventex", event: eventEx, want: true},
+		{topic: "TYPE:*/event", event: vmEvent, want: true},
+		{topic: "source:https://vcenter-01:443/sdk", event: vmEvent, want: true},
+		{topic: "source:https://vcenter-01*", event: eventEx, want: false},
+		{topic: "vcenter:vcenter-02", event: eventEx, want: true},
+		{topic: "vcenter:vcenter-0?", event: vmEvent, want: true},
+		{topic: "vcenter:vcenter-02", event: vmEvent, want: false},
+		{topic: "unknown:VmPoweredOnEvent", event: vmEvent, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			assert.Equal(t, parseTopic(tt.topic).match(tt.event), tt.want)
+		})
+	}
+}
+
+func Test_subscriptions(t *testing.T) {
+	subs := newSubscriptions(map[string][]string{
+		"VmPoweredOnEvent":      {"power-on", "echo"},
+		"Vm*Event":              {"vm-events", "echo"},
+		"vcenter:vcenter-01":    {"vcenter-01"},
+		"type:*/eventex":        {"eventex"},
+		"com.vmware.applmgmt.*": {"applmgmt"},
+	})
+
+	tests := []struct {
+		name  string
+		event cloudevents.Event
+		want  []string
+	}{
+		{
+			name:  "function subscribed with multiple matching topics is returned once",
+			event: newTestEvent("com.vmware.event.router/event", "https://vcenter-01:443/sdk", "VmPoweredOnEvent"),
+			want:  []string{"echo", "power-on", "vcenter-01", "vm-events"},
+		},
+		{
+			name:  "event type and subject pattern",
+			event: newTestEvent("com.vmware.event.router/eventex", "https://vcenter-02:443/sdk", "com.vmware.applmgmt.backup.job.failed.event"),
+			want:  []string{"applmgmt", "eventex"},
+		},
+		{
+			name:  "no matching function",
+			event: newTestEvent("com.vmware.event.router/event", "https://vcenter-02:443/sdk", "AlarmStatusChangedEvent"),
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, subs.match(tt.event), tt.want)
+		})
+	}
+}
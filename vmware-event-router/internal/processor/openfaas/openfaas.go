@@ -7,9 +7,9 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/avast/retry-go"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/embano1/waitgroup"
 	ofsdk "github.com/openfaas-incubator/connector-sdk/types"
@@ -28,12 +28,13 @@ const (
 	defaultTopicDelimiter  = ","
 	defaultRebuildInterval = time.Second * 10
 	defaultTimeout         = time.Second * 15
-	waitShutdown           = 5 * time.Second // wait for processing to finish during shutdown
+	defaultDrainTimeout    = 5 * time.Second // wait for inflight invocations to finish during shutdown
 )
 
 var (
-	// ErrStopped error is returned when the processor has been shutdown but there
-	// are still inflight processing requests
+	// ErrStopped error is returned when the processor has been shutdown or
+	// inflight invocations were aborted because the drain timeout during
+	// shutdown was exceeded
 	ErrStopped = errors.New("processor already stopped")
 
 	// assert we implement Processor interface
@@ -60,15 +61,20 @@ func (r responseFunc) Response(res ofsdk.InvokerResponse) {
 
 // Processor implements the Processor interface
 type Processor struct {
-	controller ofsdk.Controller
+	controller *controller
 	ofsdk.ResponseSubscriber
-	respChan chan ofsdk.InvokerResponse // responses from sync fn invocation
-	wg       waitgroup.WaitGroup        // used in graceful shutdown
+	wg       waitgroup.WaitGroup // used in graceful shutdown
+	inflight int64               // number of inflight function invocations
+	abort    chan struct{}       // closed when the drain timeout is exceeded
 
 	// options
 	topicDelimiter  string
 	rebuildInterval time.Duration
 	gatewayTimeout  time.Duration
+	retry           retryPolicy
+	drainTimeout    time.Duration
+	callbacks       *callbackReceiver      // only used in async mode with callback
+	replies         processor.ReplyHandler // optional
 	logger.Logger
 
 	lock    sync.RWMutex
@@ -89,7 +95,8 @@ func NewProcessor(ctx context.Context, cfg *config.ProcessorConfigOpenFaaS, ms m
 		rebuildInterval: defaultRebuildInterval,
 		gatewayTimeout:  defaultTimeout,
 		Logger:          ofLog,
-		respChan:        make(chan ofsdk.InvokerResponse),
+		drainTimeout:    defaultDrainTimeout,
+		abort:           make(chan struct{}),
 	}
 	ofProcessor.ResponseSubscriber = defaultResponseHandler(&ofProcessor)
 
@@ -102,6 +109,26 @@ func NewProcessor(ctx context.Context, cfg *config.ProcessorConfigOpenFaaS, ms m
 		return nil, errors.New("no OpenFaaS configuration found")
 	}
 
+	policy, err := newRetryPolicy(cfg.Retry)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid OpenFaaS retry configuration")
+	}
+	ofProcessor.retry = policy
+
+	if cfg.DrainTimeoutSeconds < 0 {
+		return nil, errors.Errorf("invalid OpenFaaS drain timeout: %d", cfg.DrainTimeoutSeconds)
+	}
+
+	if cfg.DrainTimeoutSeconds > 0 {
+		ofProcessor.drainTimeout = time.Duration(cfg.DrainTimeoutSeconds) * time.Second
+	}
+
+	switch cfg.Encoding {
+	case "", encodingStructured, encodingBinary:
+	default:
+		return nil, fmt.Errorf("unsupported encoding type specified: %q", cfg.Encoding)
+	}
+
 	// it's ok to pass empty credentials to OpenFaaS if basic_auth is not used
 	var credentials auth.BasicAuthCredentials
 
@@ -127,11 +154,51 @@ func NewProcessor(ctx context.Context, cfg *config.ProcessorConfigOpenFaaS, ms m
 		RebuildInterval:          ofProcessor.rebuildInterval,
 		UpstreamTimeout:          ofProcessor.gatewayTimeout,
 		AsyncFunctionInvocation:  cfg.Async,
-		PrintSync:                true,
 	}
 
-	ctl := ofsdk.NewController(&credentials, &ctlCfg, ofProcessor.Logger)
-	ofProcessor.controller = ctl
+	var (
+		calls       *callbackReceiver
+		callbackURL string
+	)
+
+	if cfg.Callback != nil {
+		if !cfg.Async {
+			return nil, errors.New("invalid OpenFaaS callback configuration: callback requires async mode")
+		}
+
+		calls, err = newCallbackReceiver(cfg.Callback, policy, ofProcessor.recordResult, ofProcessor.Logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid OpenFaaS callback configuration")
+		}
+		callbackURL = cfg.Callback.URL
+	}
+
+	var limits *limiter
+	switch {
+	case cfg.Async && len(cfg.Functions) > 0:
+		return nil, errors.New("invalid OpenFaaS functions configuration: function limits require sync mode")
+	case !cfg.Async:
+		limits, err = newLimiter(cfg.Functions, ofProcessor.gatewayTimeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid OpenFaaS functions configuration")
+		}
+	}
+
+	ofProcessor.controller = newController(&credentials, &ctlCfg, callbackURL, calls, limits, ofProcessor.Logger)
+	ofProcessor.controller.binary = cfg.Encoding == encodingBinary
+	if ofProcessor.replies != nil {
+		ofProcessor.controller.reply = ofProcessor.handleReply
+	}
+
+	if calls != nil {
+		calls.invoke = ofProcessor.controller.invoke
+		calls.reply = ofProcessor.controller.reply
+		if err = calls.start(ctx); err != nil {
+			return nil, err
+		}
+		ofProcessor.callbacks = calls
+	}
+
 	ofProcessor.controller.Subscribe(&ofProcessor)
 	ofProcessor.controller.BeginMapBuilder()
 
@@ -142,17 +209,19 @@ func NewProcessor(ctx context.Context, cfg *config.ProcessorConfigOpenFaaS, ms m
 		Address:     cfg.Address,
 		Started:     time.Now().UTC(),
 		Invocations: make(map[string]*metrics.InvocationDetails),
+		Functions:   make(map[string]*metrics.InvocationDetails),
 	}
 	go ofProcessor.PushMetrics(ctx, ms)
 
 	return &ofProcessor, nil
 }
 
-// defaultResponseHandler records metrics and handles invoker responses
+// defaultResponseHandler records metrics of invoker responses
 func defaultResponseHandler(of *Processor) responseFunc {
 	return func(res ofsdk.InvokerResponse) {
-		// TODO: currently we only support metrics when in sync invocation mode because
-		// we don't get a callback for async invocations
+		// note: in async invocation mode only the submission to the gateway is
+		// recorded, function results are recorded by the callback receiver (if
+		// configured)
 		of.lock.Lock()
 
 		// check for existing topic entry
@@ -168,23 +237,78 @@ func defaultResponseHandler(of *Processor) responseFunc {
 			of.stats.Invocations[res.Topic].Failure()
 		}
 		of.lock.Unlock()
+	}
+}
+
+// recordResult records the result and latency of an asynchronous function
+// invocation reported by the callback receiver
+func (p *Processor) recordResult(function string, success bool, latency time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.stats.Functions[function]; !ok {
+		p.stats.Functions[function] = &metrics.InvocationDetails{}
+	}
 
-		of.respChan <- res
+	if success {
+		p.stats.Functions[function].Success()
+	} else {
+		p.stats.Functions[function].Failure()
 	}
+	p.stats.Functions[function].Latency(latency)
+}
+
+// handleReply passes the CloudEvent returned in a function response and the
+// event the function was invoked with to the reply handler. Responses which are
+// not a CloudEvent are ignored.
+func (p *Processor) handleReply(ctx context.Context, function string, message []byte, header http.Header, body []byte) {
+	reply, ok, err := replyEvent(ctx, header, body)
+	if err != nil {
+		p.Warnw("ignoring invalid CloudEvent in function response", "function", function, "error", err)
+		return
+	}
+
+	if !ok {
+		p.Debugw("function response is not a CloudEvent", "function", function, "response", string(body))
+		return
+	}
+
+	var event cloudevents.Event
+	if err = json.Unmarshal(message, &event); err != nil {
+		p.Errorw("could not decode event of reply", "function", function, "error", err)
+		return
+	}
+
+	p.Debugw("received reply event from function", "function", function, "eventID", event.ID(), "reply", reply)
+	p.replies(ctx, event, *reply)
 }
 
 // Process implements the stream processor interface and invokes any OpenFaaS
 // function subscribed to the passed cloud event. If the processor has already
-// been shutdown, ErrStopped will be returned.
+// been shutdown or the invocations were aborted during shutdown, ErrStopped
+// will be returned.
 func (p *Processor) Process(ctx context.Context, ce cloudevents.Event) error {
-	if p.isStopped() {
+	// coordinate concurrent shutdown
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
 		return ErrStopped
 	}
-
-	// coordinate concurrent shutdown
 	p.wg.Add(1)
+	p.lock.Unlock()
 	defer p.wg.Done()
 
+	// abort inflight invocations when the drain timeout is exceeded
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	p.Debugw("processing event", "eventID", ce.ID(), "event", ce)
 	topic, message, err := handleEvent(ce)
 	if err != nil {
@@ -197,7 +321,7 @@ func (p *Processor) Process(ctx context.Context, ce cloudevents.Event) error {
 		p.Infow("finished processing of event", "eventID", ce.ID(), "topic", topic)
 	}()
 
-	m, err := p.controller.InvokeWithContext(ctx, topic, message)
+	m, resCh, err := p.controller.InvokeEvent(ctx, ce, topic, message)
 	if err != nil {
 		return processor.NewError(config.ProcessorOpenFaaS, errors.Wrap(err, "invoke function"))
 	}
@@ -209,19 +333,33 @@ func (p *Processor) Process(ctx context.Context, ce cloudevents.Event) error {
 
 	p.Infow("waiting for functions to return", "count", m)
 
-	waitFn := waitForOne(p.respChan, p.controller.InvokeFunction, message, p.Logger, defaultRetryOpts...)
-	return waitForAll(ctx, m, waitFn)
+	atomic.AddInt64(&p.inflight, int64(m))
+	waitFn := waitForOne(resCh, p.controller.InvokeFunction, message, p.Logger, p.retry)
+	err = waitForAll(ctx, m, func(ctx context.Context) error {
+		defer atomic.AddInt64(&p.inflight, -1)
+		return waitFn(ctx)
+	})
+
+	if p.isAborted() {
+		return ErrStopped
+	}
+
+	if err != nil {
+		return processor.NewError(config.ProcessorOpenFaaS, err)
+	}
+	return nil
 }
 
 // waitForAll waits for waitN wait functions to return. It returns the first
-// error encountered by fn or nil on success.
+// error encountered by fn or nil on success. A failing wait function does not
+// cancel the retries of the other functions.
 func waitForAll(ctx context.Context, waitN int, fn waitFunc) error {
-	eg, egCtx := errgroup.WithContext(ctx)
+	var eg errgroup.Group
 
 	// expect m callbacks
 	for i := 0; i < waitN; i++ {
 		eg.Go(func() error {
-			return fn(egCtx)
+			return fn(ctx)
 		})
 	}
 
@@ -230,32 +368,33 @@ func waitForAll(ctx context.Context, waitN int, fn waitFunc) error {
 }
 
 // waitForOne waits for one InvokerResponse from resCh from a single function
-// invocation and handles retries in case of failure. If the processor has
-// already been stopped, ErrStopped will be returned.
-func waitForOne(resCh <-chan ofsdk.InvokerResponse, invoker invokeFunc, retryMsg []byte, log logger.Logger, retryOpts ...retry.Option) waitFunc {
+// invocation and handles retries in case of failure using the given retry
+// policy. An error is returned if the invocation still fails after retries or
+// the context is cancelled before the response is received.
+func waitForOne(resCh <-chan ofsdk.InvokerResponse, invoker invokeFunc, retryMsg []byte, log logger.Logger, policy retryPolicy) waitFunc {
 	return func(ctx context.Context) error {
-		var retryCount int32
-
-		if res, ok := <-resCh; ok {
-			// return early
-			if isSuccessful(res.Status, res.Error) {
-				log.Infow("successfully invoked function", "function", res.Function, "topic", res.Topic, "retries", retryCount)
-				return nil
-			}
-
-			// retries unless error is nil or of type retry.Unrecoverable
-			err := retry.Do(retryFunc(ctx, res, invoker, retryMsg, &retryCount), retryOpts...)
-			if err != nil {
-				log.Errorw("could not invoke function", "function", res.Function, "topic", res.Topic, "retries", retryCount, "error", err)
-				return nil
-			}
-
-			log.Infow("successfully invoked function", "function", res.Function, "topic", res.Topic, "retries", retryCount)
+		var res ofsdk.InvokerResponse
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case res = <-resCh:
+		}
+
+		// return early
+		if isSuccessful(res.Status, res.Error) {
+			log.Infow("successfully invoked function", "function", res.Function, "topic", res.Topic, "retries", 0)
 			return nil
 		}
 
-		// avoid deadlock when processor is stopped concurrently
-		return ErrStopped
+		retryCount, err := retryInvocation(ctx, res, invoker, retryMsg, policy)
+		if err != nil {
+			log.Errorw("could not invoke function", "function", res.Function, "topic", res.Topic, "retries", retryCount, "error", err)
+			return errors.Wrapf(err, "invoke function %q on topic %q (retries: %d)", res.Function, res.Topic, retryCount)
+		}
+
+		log.Infow("successfully invoked function", "function", res.Function, "topic", res.Topic, "retries", retryCount)
+		return nil
 	}
 }
 
@@ -286,36 +425,51 @@ func (p *Processor) PushMetrics(ctx context.Context, ms metrics.Receiver) {
 	}
 }
 
-// Shutdown performs a clean shutdown of the OpenFaaS processor. It must not be
-// called more than once and only after all inflight event processing requests
-// have finished to avoid a panic. If the processor has already been stopped
-// ErrStopped is returned.
+// Shutdown performs a clean shutdown of the OpenFaaS processor. New events are
+// rejected with ErrStopped and inflight invocations are drained until the
+// drain timeout is exceeded. Remaining invocations are aborted and reported in
+// the returned error. If the processor has already been stopped ErrStopped is
+// returned.
 func (p *Processor) Shutdown(_ context.Context) error {
 	p.Logger.Infof("attempting graceful shutdown")
-	if p.isStopped() {
-		return ErrStopped
-	}
 
 	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return ErrStopped
+	}
 	p.stopped = true
 	p.lock.Unlock()
 
-	p.Logger.Infof("waiting up to %v for inflight events to finish processing", waitShutdown)
-	err := p.wg.WaitTimeout(waitShutdown)
-	if err != nil {
-		p.Logger.Warnw("timeout waiting for inflight events", "error", err)
-		p.Logger.Warn("enforcing shutdown")
+	var err error
+
+	p.Logger.Infof("waiting up to %v for inflight invocations to finish", p.drainTimeout)
+	if p.wg.WaitTimeout(p.drainTimeout) != nil {
+		abandoned := atomic.LoadInt64(&p.inflight)
+		p.Logger.Warnw("timeout waiting for inflight invocations: aborting invocations", "abandoned", abandoned)
+		close(p.abort)
+		err = errors.Errorf("abandoned %d inflight invocation(s) after drain timeout of %v", abandoned, p.drainTimeout)
 	}
 
-	// free resources - if waitShutdown fails (they're still inflight processor
-	// invocations) this might (intentionally) lead to a panic by writing to a
-	// closed channel from the worker routines
-	close(p.respChan)
-	return nil
+	if p.callbacks != nil {
+		// the callback server is stopped independently of the (cancelled)
+		// shutdown context
+		ctx, cancel := context.WithTimeout(context.Background(), p.drainTimeout)
+		defer cancel()
+
+		if stopErr := p.callbacks.stop(ctx); stopErr != nil && err == nil {
+			err = errors.Wrap(stopErr, "stop callback server")
+		}
+	}
+
+	return err
 }
 
-func (p *Processor) isStopped() bool {
-	p.lock.RLock()
-	defer p.lock.RUnlock()
-	return p.stopped
+func (p *Processor) isAborted() bool {
+	select {
+	case <-p.abort:
+		return true
+	default:
+		return false
+	}
 }
@@ -5,17 +5,26 @@ package openfaas
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/avast/retry-go"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
 	ofsdk "github.com/openfaas-incubator/connector-sdk/types"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
 
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
 )
 
@@ -121,7 +130,7 @@ func Test_waitForOne(t *testing.T) {
 		retryCounts = make([]int, numTests) // desired number of retries per test
 		responses   = make([]ofsdk.InvokerResponse, numTests)
 		resChan     = make(chan ofsdk.InvokerResponse, numTests)
-		retryOpts   = []retry.Option{retry.Attempts(uint(maxRetries))}
+		policy      = retryPolicy{attempts: uint(maxRetries), delay: time.Millisecond, maxDelay: 10 * time.Millisecond}
 	)
 
 	// this retry func never fails
@@ -166,12 +175,12 @@ func Test_waitForOne(t *testing.T) {
 	cancel()
 
 	type args struct {
-		ctx       context.Context
-		resCh     <-chan ofsdk.InvokerResponse
-		invoker   invokeFunc
-		retryMsg  []byte
-		log       logger.Logger
-		retryOpts []retry.Option
+		ctx      context.Context
+		resCh    <-chan ofsdk.InvokerResponse
+		invoker  invokeFunc
+		retryMsg []byte
+		log      logger.Logger
+		policy   retryPolicy
 	}
 	tests := []struct {
 		name           string
@@ -183,12 +192,12 @@ func Test_waitForOne(t *testing.T) {
 		{
 			name: "First invocation is immediately successful (no retries)",
 			args: args{
-				ctx:       ctx,
-				resCh:     resChan,
-				invoker:   okFunc,
-				retryMsg:  []byte("should not retry"),
-				log:       log,
-				retryOpts: retryOpts,
+				ctx:      ctx,
+				resCh:    resChan,
+				invoker:  okFunc,
+				retryMsg: []byte("should not retry"),
+				log:      log,
+				policy:   policy,
 			},
 			firstResponse: ofsdk.InvokerResponse{
 				Body:     []byte("OK"),
@@ -203,12 +212,12 @@ func Test_waitForOne(t *testing.T) {
 		{
 			name: "Invocation always fails (stop after max retries)",
 			args: args{
-				ctx:       ctx,
-				resCh:     resChan,
-				invoker:   failFunc,
-				retryMsg:  []byte("should retry"),
-				log:       log,
-				retryOpts: retryOpts,
+				ctx:      ctx,
+				resCh:    resChan,
+				invoker:  failFunc,
+				retryMsg: []byte("should retry"),
+				log:      log,
+				policy:   policy,
 			},
 			firstResponse: ofsdk.InvokerResponse{
 				Body:     []byte("OK"),
@@ -218,17 +227,17 @@ func Test_waitForOne(t *testing.T) {
 				Function: "test-function-http_500",
 			},
 			wantRetryCount: 3,
-			wantErr:        false,
+			wantErr:        true,
 		},
 		{
 			name: "Retry func fails with error (stop after first retry)",
 			args: args{
-				ctx:       ctx,
-				resCh:     resChan,
-				invoker:   failWithErrFunc,
-				retryMsg:  []byte("should retry"),
-				log:       log,
-				retryOpts: retryOpts,
+				ctx:      ctx,
+				resCh:    resChan,
+				invoker:  failWithErrFunc,
+				retryMsg: []byte("should retry"),
+				log:      log,
+				policy:   policy,
 			},
 			firstResponse: ofsdk.InvokerResponse{
 				Body:     []byte("OK"),
@@ -238,17 +247,17 @@ func Test_waitForOne(t *testing.T) {
 				Function: "test-function-http_500",
 			},
 			wantRetryCount: 1,
-			wantErr:        false,
+			wantErr:        true,
 		},
 		{
 			name: "Retry func succeeds (stop after second retry)",
 			args: args{
-				ctx:       ctx,
-				resCh:     resChan,
-				invoker:   retryableFunc,
-				retryMsg:  []byte("should retry"),
-				log:       log,
-				retryOpts: retryOpts,
+				ctx:      ctx,
+				resCh:    resChan,
+				invoker:  retryableFunc,
+				retryMsg: []byte("should retry"),
+				log:      log,
+				policy:   policy,
 			},
 			firstResponse: ofsdk.InvokerResponse{
 				Body:     []byte("OK"),
@@ -263,12 +272,12 @@ func Test_waitForOne(t *testing.T) {
 		{
 			name: "First invocation failed and meanwhile context timed out (no retry)",
 			args: args{
-				ctx:       timeoutCtx,
-				resCh:     resChan,
-				invoker:   okFunc,
-				retryMsg:  []byte("should not retry"),
-				log:       log,
-				retryOpts: retryOpts,
+				ctx:      timeoutCtx,
+				resCh:    resChan,
+				invoker:  okFunc,
+				retryMsg: []byte("should not retry"),
+				log:      log,
+				policy:   policy,
 			},
 			firstResponse: ofsdk.InvokerResponse{
 				Body:     []byte("OK"),
@@ -278,7 +287,7 @@ func Test_waitForOne(t *testing.T) {
 				Function: "test-function-http_500",
 			},
 			wantRetryCount: 0,
-			wantErr:        false,
+			wantErr:        true,
 		},
 	}
 	for idx, tt := range tests {
@@ -291,7 +300,7 @@ func Test_waitForOne(t *testing.T) {
 		retryCount = 0
 
 		t.Run(tt.name, func(t *testing.T) {
-			wf := waitForOne(tt.args.resCh, tt.args.invoker, tt.args.retryMsg, tt.args.log, tt.args.retryOpts...)
+			wf := waitForOne(tt.args.resCh, tt.args.invoker, tt.args.retryMsg, tt.args.log, tt.args.policy)
 
 			// run function
 			if err := wf(tt.args.ctx); (err != nil) != tt.wantErr {
@@ -305,18 +314,21 @@ func Test_waitForOne(t *testing.T) {
 	}
 }
 
-func Test_waitForOneProcStopped(t *testing.T) {
+func Test_waitForOneCancelled(t *testing.T) {
 	var (
 		resChan = make(chan ofsdk.InvokerResponse)
 		log     = zaptest.NewLogger(t).Sugar()
 	)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
 	type args struct {
-		resCh     <-chan ofsdk.InvokerResponse
-		invoker   invokeFunc
-		retryMsg  []byte
-		log       logger.Logger
-		retryOpts []retry.Option
+		resCh    <-chan ofsdk.InvokerResponse
+		invoker  invokeFunc
+		retryMsg []byte
+		log      logger.Logger
+		policy   retryPolicy
 	}
 	tests := []struct {
 		name    string
@@ -324,27 +336,164 @@ func Test_waitForOneProcStopped(t *testing.T) {
 		wantErr error
 	}{
 		{
-			name: "Processor stopped",
+			name: "Context cancelled before response",
 			args: args{
-				resCh:     resChan,
-				invoker:   nil,
-				retryMsg:  nil,
-				log:       log,
-				retryOpts: nil,
+				resCh:    resChan,
+				invoker:  nil,
+				retryMsg: nil,
+				log:      log,
+				policy:   retryPolicy{},
 			},
-			wantErr: ErrStopped,
+			wantErr: context.Canceled,
 		},
 	}
 
-	close(resChan)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			wf := waitForOne(tt.args.resCh, tt.args.invoker, tt.args.retryMsg, tt.args.log, tt.args.retryOpts...)
+			wf := waitForOne(tt.args.resCh, tt.args.invoker, tt.args.retryMsg, tt.args.log, tt.args.policy)
 
 			// run function
-			if err := wf(context.Background()); err != tt.wantErr {
+			if err := wf(ctx); err != tt.wantErr {
 				t.Errorf("waitForOne() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+// newSyncGateway returns a gateway with the functions "ok" (VmPoweredOnEvent),
+// "fail" (VmPoweredOffEvent) and "slow" (VmSuspendedEvent). The slow function
+// signals invocations on started and blocks until the request is cancelled.
+func newSyncGateway(t *testing.T, started chan<- struct{}) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/system/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/system/functions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"name":"ok","annotations":{"topic":"VmPoweredOnEvent"}},
+			{"name":"fail","annotations":{"topic":"VmPoweredOffEvent"}},
+			{"name":"slow","annotations":{"topic":"VmSuspendedEvent"}}
+		]`))
+	})
+	mux.HandleFunc("/function/ok", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/function/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+	})
+	mux.HandleFunc("/function/slow", func(w http.ResponseWriter, r *http.Request) {
+		// body must be consumed to detect client disconnects
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	})
+
+	gw := httptest.NewServer(mux)
+	t.Cleanup(gw.Close)
+
+	return gw
+}
+
+func newSubjectEvent(id, subject string) cloudevents.Event {
+	e := cloudevents.NewEvent()
+	e.SetID(id)
+	e.SetSource("https://vcenter-01:443/sdk")
+	e.SetType("com.vmware.event.router/event")
+	e.SetSubject(subject)
+	return e
+}
+
+func Test_ProcessCorrelatesResponses(t *testing.T) {
+	log := zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel)).Sugar()
+	ctx := context.Background()
+
+	gw := newSyncGateway(t, nil)
+	p, err := NewProcessor(ctx, &config.ProcessorConfigOpenFaaS{Address: gw.URL}, metricsStub{}, log)
+	assert.NilError(t, err)
+
+	// concurrently process successful and failing events
+	const events = 20
+	var eg errgroup.Group
+	for i := 0; i < events; i++ {
+		i := i
+		eg.Go(func() error {
+			if i%2 == 0 {
+				if err := p.Process(ctx, newSubjectEvent(fmt.Sprint(i), "VmPoweredOnEvent")); err != nil {
+					return fmt.Errorf("event %d: unexpected error: %v", i, err)
+				}
+				return nil
+			}
+
+			if err := p.Process(ctx, newSubjectEvent(fmt.Sprint(i), "VmPoweredOffEvent")); err == nil {
+				return fmt.Errorf("event %d: expected error", i)
+			}
+			return nil
+		})
+	}
+	assert.NilError(t, eg.Wait())
+
+	p.lock.RLock()
+	assert.Equal(t, p.stats.Invocations["VmPoweredOnEvent"].SuccessCount, events/2)
+	assert.Equal(t, p.stats.Invocations["VmPoweredOffEvent"].FailureCount, events/2)
+	p.lock.RUnlock()
+
+	assert.NilError(t, p.Shutdown(ctx))
+}
+
+func Test_Shutdown(t *testing.T) {
+	log := zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel)).Sugar()
+	ctx := context.Background()
+
+	t.Run("drains inflight invocations", func(t *testing.T) {
+		gw := newSyncGateway(t, nil)
+		p, err := NewProcessor(ctx, &config.ProcessorConfigOpenFaaS{Address: gw.URL}, metricsStub{}, log)
+		assert.NilError(t, err)
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return p.Process(ctx, newSubjectEvent("1", "VmPoweredOnEvent"))
+		})
+
+		// wait for invocation to start
+		assert.NilError(t, waitForInflight(p, 1))
+
+		assert.NilError(t, p.Shutdown(ctx))
+		assert.NilError(t, eg.Wait())
+
+		assert.Equal(t, p.Process(ctx, newSubjectEvent("2", "VmPoweredOnEvent")), ErrStopped)
+		assert.Equal(t, p.Shutdown(ctx), ErrStopped)
+	})
+
+	t.Run("aborts inflight invocations after drain timeout", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		gw := newSyncGateway(t, started)
+
+		cfg := config.ProcessorConfigOpenFaaS{Address: gw.URL, DrainTimeoutSeconds: 1}
+		p, err := NewProcessor(ctx, &cfg, metricsStub{}, log)
+		assert.NilError(t, err)
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return p.Process(ctx, newSubjectEvent("1", "VmSuspendedEvent"))
+		})
+
+		<-started
+		assert.ErrorContains(t, p.Shutdown(ctx), "abandoned 1 inflight invocation(s) after drain timeout of 1s")
+		assert.Equal(t, eg.Wait(), ErrStopped)
+	})
+}
+
+// waitForInflight waits until the processor has n inflight invocations
+func waitForInflight(p *Processor, n int64) error {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt64(&p.inflight) == n {
+			return nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return errors.New("timed out waiting for inflight invocations")
+}
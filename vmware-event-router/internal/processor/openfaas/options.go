@@ -4,19 +4,14 @@ import (
 	"time"
 
 	ofsdk "github.com/openfaas-incubator/connector-sdk/types"
+
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
 )
 
 // Option configures the OpenFaaS processor
 // TODO: change signature to return errors
 type Option func(*Processor)
 
-// WithResponseChan sets a custom response channel to use for returning invocation responses
-func WithResponseChan(resCh chan ofsdk.InvokerResponse) Option {
-	return func(o *Processor) {
-		o.respChan = resCh
-	}
-}
-
 // WithDelimiter changes the default topic delimiter (comma-separated
 // strings) for the OpenFaaS processor
 func WithDelimiter(delim string) Option {
@@ -41,6 +36,14 @@ func WithRebuildInterval(interval time.Duration) Option {
 	}
 }
 
+// WithReplyHandler sets a handler for the CloudEvents returned by functions.
+// Function responses which are not a CloudEvent are ignored.
+func WithReplyHandler(handler processor.ReplyHandler) Option {
+	return func(o *Processor) {
+		o.replies = handler
+	}
+}
+
 // WithResponseHandler sets an alternative response handler for the
 // OpenFaaS processor
 func WithResponseHandler(handler ofsdk.ResponseSubscriber) Option {
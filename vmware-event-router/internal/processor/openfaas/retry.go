@@ -3,16 +3,18 @@ package openfaas
 import (
 	"context"
 	"crypto/x509"
-	"fmt"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"regexp"
-	"sync/atomic"
+	"strconv"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/openfaas-incubator/connector-sdk/types"
 	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 )
 
 const (
@@ -21,13 +23,156 @@ const (
 	maxRetryDelay = 5 * time.Second
 )
 
-// default retry options
-var defaultRetryOpts = []retry.Option{
-	retry.Attempts(retryAttempts),
-	retry.Delay(retryDelay),
-	retry.MaxDelay(maxRetryDelay),
-	retry.DelayType(retry.BackOffDelay),
-	// retry.LastErrorOnly(true),
+// retryPolicy configures retries of failed function invocations
+type retryPolicy struct {
+	attempts   uint
+	delay      time.Duration
+	maxDelay   time.Duration
+	jitter     time.Duration
+	retryAfter bool         // use Retry-After response header as delay
+	codes      map[int]bool // retryable status codes, uses isRetryable if empty
+}
+
+// default retry policy
+var defaultRetryPolicy = retryPolicy{
+	attempts:   retryAttempts,
+	delay:      retryDelay,
+	maxDelay:   maxRetryDelay,
+	retryAfter: true,
+}
+
+// newRetryPolicy returns the retry policy for the given configuration. Unset
+// values are replaced with the defaults.
+func newRetryPolicy(cfg *config.OpenFaaSRetry) (retryPolicy, error) {
+	policy := defaultRetryPolicy
+	if cfg == nil {
+		return policy, nil
+	}
+
+	if cfg.Attempts != nil {
+		if *cfg.Attempts < 0 {
+			return retryPolicy{}, errors.Errorf("invalid retry attempts: %d", *cfg.Attempts)
+		}
+		policy.attempts = uint(*cfg.Attempts)
+	}
+
+	if cfg.DelayMilliseconds < 0 || cfg.MaxDelayMilliseconds < 0 || cfg.JitterMilliseconds < 0 {
+		return retryPolicy{}, errors.New("invalid retry delay: delays must not be negative")
+	}
+
+	if cfg.DelayMilliseconds > 0 {
+		policy.delay = time.Duration(cfg.DelayMilliseconds) * time.Millisecond
+	}
+
+	if cfg.MaxDelayMilliseconds > 0 {
+		policy.maxDelay = time.Duration(cfg.MaxDelayMilliseconds) * time.Millisecond
+	}
+
+	if policy.maxDelay < policy.delay {
+		return retryPolicy{}, errors.Errorf("invalid retry delay: maximum delay %v must not be less than delay %v", policy.maxDelay, policy.delay)
+	}
+
+	policy.jitter = time.Duration(cfg.JitterMilliseconds) * time.Millisecond
+	policy.retryAfter = !cfg.IgnoreRetryAfter
+
+	if len(cfg.StatusCodes) > 0 {
+		policy.codes = make(map[int]bool)
+		for _, code := range cfg.StatusCodes {
+			if code < 100 || code > 599 {
+				return retryPolicy{}, errors.Errorf("invalid retry status code: %d", code)
+			}
+			policy.codes[code] = true
+		}
+	}
+
+	return policy, nil
+}
+
+// isRetryable returns whether the invocation with the given status code and
+// error is retried. Configured status codes take precedence over the default
+// classification of status codes by isRetryable.
+func (p retryPolicy) isRetryable(ctx context.Context, code int, err error) (bool, error) {
+	if len(p.codes) == 0 || err != nil || ctx.Err() != nil {
+		return isRetryable(ctx, code, err)
+	}
+	return p.codes[code], nil
+}
+
+// delayFunc returns a retry.DelayTypeFunc using backoff with the header of
+// the last response
+func (p retryPolicy) delayFunc(header func() http.Header) retry.DelayTypeFunc {
+	return func(n uint, _ *retry.Config) time.Duration {
+		return p.backoff(n, header())
+	}
+}
+
+// backoff returns the delay before retry n+1. The delay increases
+// exponentially with every retry plus a random jitter and is capped at the
+// maximum delay. If the last response specifies a longer delay with the
+// Retry-After header, the Retry-After delay is used instead.
+func (p retryPolicy) backoff(n uint, header http.Header) time.Duration {
+	delay := p.maxDelay
+	if n < 32 && p.delay<<n < delay {
+		delay = p.delay << n
+	}
+
+	if p.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.jitter)))
+	}
+
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+
+	if p.retryAfter {
+		if after, ok := retryAfter(header, time.Now()); ok && after > delay {
+			delay = after
+		}
+	}
+	return delay
+}
+
+// exceedsBudget returns the Retry-After delay of the last response and true if
+// it exceeds the remaining retry budget before retry n+1, i.e. the maximum
+// delay of all remaining retries. Such invocations are not retried instead of
+// retrying earlier than requested by the function.
+func (p retryPolicy) exceedsBudget(n uint, header http.Header) (time.Duration, bool) {
+	if !p.retryAfter || n >= p.attempts {
+		return 0, false
+	}
+
+	after, ok := retryAfter(header, time.Now())
+	if !ok {
+		return 0, false
+	}
+
+	budget := time.Duration(p.attempts-n) * p.maxDelay
+	return after, after > budget
+}
+
+// retryAfter parses the Retry-After header in delay-seconds or HTTP-date
+// format
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if delay := t.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
 }
 
 // isRetryable provides a default callback for Client.CheckRetry, which
@@ -50,6 +195,12 @@ func isRetryable(ctx context.Context, code int, err error) (bool, error) {
 	}
 
 	if err != nil {
+		// Don't retry if the function concurrency limit rejected the
+		// invocation.
+		if errors.Is(err, errFunctionBusy) {
+			return false, nil
+		}
+
 		if v, ok := err.(*url.Error); ok {
 			// Don't retry if the error was due to too many redirects.
 			if redirectsErrorRe.MatchString(v.Error()) {
@@ -89,32 +240,65 @@ func isRetryable(ctx context.Context, code int, err error) (bool, error) {
 	return false, nil
 }
 
-// retryFunc returns a function with internal retry logic based on the given
-// initial response. Retries, if any, will be performed with invoker and message
-// (to perform retries)
-func retryFunc(ctx context.Context, res types.InvokerResponse, invoker invokeFunc, msg []byte, counter *int32) func() error {
-	// define closure and copy res values so they can be reassigned, updated and
-	// reevaluated during retries
+// retryInvocation retries the failed function invocation res with invoker
+// and message using the given retry policy. It returns the number of retries
+// and nil on success or the last error if the invocation is not retryable or
+// all attempts are exhausted.
+func retryInvocation(ctx context.Context, res types.InvokerResponse, invoker invokeFunc, msg []byte, policy retryPolicy) (int, error) {
+	// copy res values so they can be updated and reevaluated during retries
 	var (
 		resStatus = res.Status
 		resError  = res.Error
-		resMsg    []byte
+		resHeader = res.Header
+		resMsg    = res.Body
+		retries   int
 	)
 
-	return func() error {
-		retryable, err := isRetryable(ctx, resStatus, resError)
-		if err != nil || !retryable {
-			return retry.Unrecoverable(errors.Errorf("could not invoke function %q on topic %q: %v", res.Function, res.Topic, err))
+	// the first attempt only evaluates the initial response so the delay
+	// before the first retry is based on it
+	first := true
+	fn := func() error {
+		if !first {
+			if err := ctx.Err(); err != nil {
+				return retry.Unrecoverable(err)
+			}
+
+			retries++
+			resMsg, resStatus, resHeader, resError = invoker(ctx, res.Function, msg)
+			if isSuccessful(resStatus, resError) {
+				return nil
+			}
 		}
+		first = false
 
-		// retry
-		atomic.AddInt32(counter, 1)
-		resMsg, resStatus, _, resError = invoker(ctx, res.Function, msg)
-		if !isSuccessful(resStatus, resError) {
-			return fmt.Errorf("function %q on topic %q returned non successful status code %d: %q", res.Function, res.Topic, resStatus, string(resMsg))
+		err := resError
+		if err == nil {
+			err = errors.Errorf("function %q on topic %q returned non successful status code %d: %q", res.Function, res.Topic, resStatus, string(resMsg))
 		}
-		return nil
+
+		retryable, ctxErr := policy.isRetryable(ctx, resStatus, resError)
+		if ctxErr != nil {
+			return retry.Unrecoverable(ctxErr)
+		}
+
+		if !retryable {
+			return retry.Unrecoverable(err)
+		}
+
+		if after, exceeded := policy.exceedsBudget(uint(retries), resHeader); exceeded {
+			return retry.Unrecoverable(errors.Wrapf(err, "Retry-After of %v exceeds retry budget", after))
+		}
+		return err
 	}
+
+	// the delay is not capped with retry.MaxDelay so Retry-After is honored
+	err := retry.Do(fn,
+		retry.Attempts(policy.attempts+1), // includes the initial response
+		retry.Delay(policy.delay),
+		retry.DelayType(policy.delayFunc(func() http.Header { return resHeader })),
+		retry.LastErrorOnly(true),
+	)
+	return retries, err
 }
 
 // isSuccessful returns true if no error has occurred and when the HTTP status
@@ -6,7 +6,16 @@ package openfaas
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	ofsdk "github.com/openfaas-incubator/connector-sdk/types"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 )
 
 func Test_isSuccessful(t *testing.T) {
@@ -106,3 +115,224 @@ func Test_isRetryable(t *testing.T) {
 		})
 	}
 }
+
+func Test_newRetryPolicy(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name    string
+		cfg     *config.OpenFaaSRetry
+		want    retryPolicy
+		wantErr bool
+	}{
+		{
+			name: "no config uses defaults",
+			cfg:  nil,
+			want: defaultRetryPolicy,
+		},
+		{
+			name: "empty config uses defaults",
+			cfg:  &config.OpenFaaSRetry{},
+			want: defaultRetryPolicy,
+		},
+		{
+			name: "custom policy",
+			cfg: &config.OpenFaaSRetry{
+				Attempts:             intPtr(5),
+				DelayMilliseconds:    200,
+				MaxDelayMilliseconds: 10000,
+				JitterMilliseconds:   100,
+				IgnoreRetryAfter:     true,
+				StatusCodes:          []int{409, 503},
+			},
+			want: retryPolicy{
+				attempts: 5,
+				delay:    200 * time.Millisecond,
+				maxDelay: 10 * time.Second,
+				jitter:   100 * time.Millisecond,
+				codes:    map[int]bool{409: true, 503: true},
+			},
+		},
+		{
+			name: "retries disabled",
+			cfg:  &config.OpenFaaSRetry{Attempts: intPtr(0)},
+			want: retryPolicy{
+				attempts:   0,
+				delay:      retryDelay,
+				maxDelay:   maxRetryDelay,
+				retryAfter: true,
+			},
+		},
+		{
+			name:    "negative attempts",
+			cfg:     &config.OpenFaaSRetry{Attempts: intPtr(-1)},
+			wantErr: true,
+		},
+		{
+			name:    "negative jitter",
+			cfg:     &config.OpenFaaSRetry{JitterMilliseconds: -1},
+			wantErr: true,
+		},
+		{
+			name:    "max delay less than delay",
+			cfg:     &config.OpenFaaSRetry{DelayMilliseconds: 2000, MaxDelayMilliseconds: 1000},
+			wantErr: true,
+		},
+		{
+			name:    "invalid status code",
+			cfg:     &config.OpenFaaSRetry{StatusCodes: []int{42}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRetryPolicy(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newRetryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newRetryPolicy() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retryPolicyIsRetryable(t *testing.T) {
+	policy := retryPolicy{codes: map[int]bool{409: true}}
+
+	tests := []struct {
+		name string
+		code int
+		err  error
+		want bool
+	}{
+		{name: "configured status code", code: 409, want: true},
+		{name: "default retryable status code not configured", code: 503, want: false},
+		{name: "connection error", code: 0, err: errors.New("connection refused"), want: true},
+		{name: "function busy", code: 429, err: fmt.Errorf("unable to invoke fn: %w", errFunctionBusy), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.isRetryable(context.Background(), tt.code, tt.err)
+			if err != nil {
+				t.Errorf("isRetryable() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("isRetryable() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	now := time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "no header", value: "", want: 0, wantOK: false},
+		{name: "delay seconds", value: "2", want: 2 * time.Second, wantOK: true},
+		{name: "negative delay seconds", value: "-2", want: 0, wantOK: false},
+		{name: "http date", value: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute, wantOK: true},
+		{name: "http date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, wantOK: true},
+		{name: "invalid value", value: "soon", want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+
+			got, ok := retryAfter(header, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter() got = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_retryInvocationRetryAfter(t *testing.T) {
+	const maxDelay = 400 * time.Millisecond
+
+	res := ofsdk.InvokerResponse{
+		Status:   http.StatusTooManyRequests,
+		Header:   http.Header{"Retry-After": []string{"1"}},
+		Topic:    "test-topic",
+		Function: "test-function-http_429",
+	}
+
+	okFunc := func(ctx context.Context, fn string, message []byte) ([]byte, int, http.Header, error) {
+		return []byte("OK"), 200, http.Header{}, nil
+	}
+
+	tests := []struct {
+		name        string
+		attempts    uint
+		retryAfter  bool
+		wantRetries int
+		wantDelay   time.Duration // minimum delay before first retry
+		errString   string
+	}{
+		{name: "honor Retry-After longer than maximum delay", attempts: 3, retryAfter: true, wantRetries: 1, wantDelay: time.Second},
+		{name: "Retry-After exceeds retry budget", attempts: 1, retryAfter: true, wantRetries: 0, errString: "Retry-After of 1s exceeds retry budget"},
+		{name: "ignore Retry-After", attempts: 1, retryAfter: false, wantRetries: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := retryPolicy{attempts: tt.attempts, delay: time.Millisecond, maxDelay: maxDelay, retryAfter: tt.retryAfter}
+
+			start := time.Now()
+			retries, err := retryInvocation(context.Background(), res, okFunc, nil, policy)
+			if tt.errString != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errString) {
+					t.Fatalf("retryInvocation() error = %v, want %q", err, tt.errString)
+				}
+			} else if err != nil {
+				t.Fatalf("retryInvocation() error = %v", err)
+			}
+
+			if retries != tt.wantRetries {
+				t.Errorf("retryInvocation() retries = %v, want %v", retries, tt.wantRetries)
+			}
+
+			if elapsed := time.Since(start); elapsed < tt.wantDelay || (tt.wantDelay == 0 && elapsed >= maxDelay) {
+				t.Errorf("retryInvocation() elapsed = %v, want delay %v", elapsed, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func Test_retryPolicyExceedsBudget(t *testing.T) {
+	policy := retryPolicy{attempts: 3, delay: time.Millisecond, maxDelay: time.Second, retryAfter: true}
+	header := http.Header{"Retry-After": []string{"2"}}
+
+	tests := []struct {
+		name   string
+		policy retryPolicy
+		n      uint
+		want   bool
+	}{
+		{name: "within budget of remaining retries", policy: policy, n: 0, want: false},
+		{name: "exceeds budget of remaining retries", policy: policy, n: 2, want: true},
+		{name: "retries exhausted", policy: policy, n: 3, want: false},
+		{name: "Retry-After ignored", policy: retryPolicy{attempts: 1, maxDelay: time.Second}, n: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := tt.policy.exceedsBudget(tt.n, header); got != tt.want {
+				t.Errorf("exceedsBudget() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Retry-After is the lower bound of the delay
+	if got := policy.backoff(0, header); got != 2*time.Second {
+		t.Errorf("backoff() got = %v, want %v", got, 2*time.Second)
+	}
+}
@@ -17,6 +17,10 @@ type Processor interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ReplyHandler handles a reply event returned by a sink of the processor for
+// the given event
+type ReplyHandler func(ctx context.Context, event, reply cloudevents.Event)
+
 // Error struct contains the generic error content used by the processors
 // it extends the simple error by providing context which processor gave
 // the error
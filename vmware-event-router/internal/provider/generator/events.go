@@ -0,0 +1,188 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+const (
+	defaultEntities = 100
+	userName        = "VSPHERE.LOCAL\\Administrator"
+)
+
+// defaultMix is the mix of generated events if none is configured
+var defaultMix = []config.GeneratorEvent{
+	{Type: "VmPoweredOnEvent", Weight: 4},
+	{Type: "VmPoweredOffEvent", Weight: 4},
+	{Type: "VmReconfiguredEvent", Weight: 2},
+	{Type: "VmCreatedEvent", Weight: 1},
+	{Type: "VmRemovedEvent", Weight: 1},
+	{Type: "DrsVmMigratedEvent", Weight: 2},
+	{Type: "UserLoginSessionEvent", Weight: 3},
+	{Type: "UserLogoutSessionEvent", Weight: 3},
+	{Type: "HostConnectionLostEvent", Weight: 1},
+	{Type: "EventEx", EventTypeID: "com.vmware.vc.HA.DasHostFailedEvent", Weight: 1},
+}
+
+// eventType is a generated vSphere event type with its weight
+type eventType struct {
+	kind        reflect.Type
+	eventTypeID string // EventEx and ExtendedEvent only
+	weight      int
+}
+
+// inventory contains randomized names of inventory objects referenced by the
+// generated events
+type inventory struct {
+	datacenters []string
+	clusters    []string
+	hosts       []string
+	vms         []string
+}
+
+// eventFactory creates random vSphere events of the configured mix
+type eventFactory struct {
+	types       []eventType
+	totalWeight int
+	inventory   inventory
+	rnd         *rand.Rand
+	key         int32
+}
+
+// newEventFactory returns a factory for the given event mix
+func newEventFactory(mix []config.GeneratorEvent, entities int, rnd *rand.Rand) (*eventFactory, error) {
+	if len(mix) == 0 {
+		mix = defaultMix
+	}
+
+	if entities == 0 {
+		entities = defaultEntities
+	}
+
+	f := eventFactory{rnd: rnd}
+
+	for _, m := range mix {
+		kind, ok := types.TypeFunc()(m.Type)
+		if !ok {
+			return nil, errors.Errorf("unknown vSphere event type %q", m.Type)
+		}
+
+		if _, ok = reflect.New(kind).Interface().(types.BaseEvent); !ok {
+			return nil, errors.Errorf("vSphere type %q is not an event", m.Type)
+		}
+
+		switch m.Type {
+		case "EventEx", "ExtendedEvent":
+			if m.EventTypeID == "" {
+				return nil, errors.Errorf("event type ID must be specified for vSphere event type %q", m.Type)
+			}
+		default:
+			if m.EventTypeID != "" {
+				return nil, errors.Errorf("event type ID is only supported for vSphere event types EventEx and ExtendedEvent: %q", m.Type)
+			}
+		}
+
+		weight := m.Weight
+		switch {
+		case weight == 0:
+			weight = 1
+		case weight < 0:
+			return nil, errors.Errorf("weight must not be negative: %q", m.Type)
+		}
+
+		f.types = append(f.types, eventType{kind: kind, eventTypeID: m.EventTypeID, weight: weight})
+		f.totalWeight += weight
+	}
+
+	f.inventory = inventory{
+		datacenters: f.names("dc", max(entities/50, 1)),
+		clusters:    f.names("cluster", max(entities/20, 1)),
+		hosts:       f.names("esx", max(entities/10, 1)),
+		vms:         f.names("vm", entities),
+	}
+
+	return &f, nil
+}
+
+// names returns n random names with the given prefix, e.g. vm-3f9a2c
+func (f *eventFactory) names(prefix string, n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%06x", prefix, f.rnd.Intn(1<<24))
+	}
+	return names
+}
+
+// pick returns a random element of the given names
+func (f *eventFactory) pick(names []string) string {
+	return names[f.rnd.Intn(len(names))]
+}
+
+// newEvent returns a random event of the configured mix created at the given
+// time. Event keys are increasing.
+func (f *eventFactory) newEvent(t time.Time) types.BaseEvent {
+	n := f.rnd.Intn(f.totalWeight)
+
+	var et eventType
+	for _, et = range f.types {
+		if n < et.weight {
+			break
+		}
+		n -= et.weight
+	}
+
+	event := reflect.New(et.kind).Interface().(types.BaseEvent)
+	f.key++
+
+	vm, host := f.pick(f.inventory.vms), f.pick(f.inventory.hosts)
+
+	e := event.GetEvent()
+	e.Key = f.key
+	e.ChainId = f.key
+	e.CreatedTime = t
+	e.UserName = userName
+	e.Datacenter = &types.DatacenterEventArgument{
+		EntityEventArgument: types.EntityEventArgument{Name: f.pick(f.inventory.datacenters)},
+		Datacenter:          types.ManagedObjectReference{Type: "Datacenter", Value: "datacenter-1"},
+	}
+	e.ComputeResource = &types.ComputeResourceEventArgument{
+		EntityEventArgument: types.EntityEventArgument{Name: f.pick(f.inventory.clusters)},
+		ComputeResource:     types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "domain-c1"},
+	}
+	e.Host = &types.HostEventArgument{
+		EntityEventArgument: types.EntityEventArgument{Name: host},
+		Host:                types.ManagedObjectReference{Type: "HostSystem", Value: "host-" + host[len("esx-"):]},
+	}
+	e.Vm = &types.VmEventArgument{
+		EntityEventArgument: types.EntityEventArgument{Name: vm},
+		Vm:                  types.ManagedObjectReference{Type: "VirtualMachine", Value: vm},
+	}
+
+	name := et.kind.Name()
+	switch ev := event.(type) {
+	case *types.EventEx:
+		ev.EventTypeId = et.eventTypeID
+		ev.Severity = string(types.EventEventSeverityInfo)
+		name = et.eventTypeID
+	case *types.ExtendedEvent:
+		ev.EventTypeId = et.eventTypeID
+		name = et.eventTypeID
+	}
+	e.FullFormattedMessage = fmt.Sprintf("Generated %s for %s on %s", name, vm, host)
+
+	return event
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+//go:build unit
+// +build unit
+
+package generator
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+)
+
+func Test_newEventFactory(t *testing.T) {
+	tests := []struct {
+		name      string
+		mix       []config.GeneratorEvent
+		errString string
+	}{
+		{name: "default mix"},
+		{name: "unknown type", mix: []config.GeneratorEvent{{Type: "NoSuchEvent"}}, errString: `unknown vSphere event type "NoSuchEvent"`},
+		{name: "not an event", mix: []config.GeneratorEvent{{Type: "ManagedObjectReference"}}, errString: `vSphere type "ManagedObjectReference" is not an event`},
+		{name: "EventEx without event type ID", mix: []config.GeneratorEvent{{Type: "EventEx"}}, errString: "event type ID must be specified"},
+		{name: "event type ID for other types", mix: []config.GeneratorEvent{{Type: "VmPoweredOnEvent", EventTypeID: "com.vmware.vc.test"}}, errString: "event type ID is only supported"},
+		{name: "negative weight", mix: []config.GeneratorEvent{{Type: "VmPoweredOnEvent", Weight: -1}}, errString: "weight must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newEventFactory(tt.mix, 0, rand.New(rand.NewSource(1)))
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, len(f.types), len(defaultMix))
+			assert.Equal(t, len(f.inventory.vms), defaultEntities)
+			assert.Equal(t, len(f.inventory.datacenters), 2)
+		})
+	}
+}
+
+func Test_eventFactory_newEvent(t *testing.T) {
+	mix := []config.GeneratorEvent{
+		{Type: "VmPoweredOnEvent", Weight: 3},
+		{Type: "EventEx", EventTypeID: "com.vmware.vc.HA.DasHostFailedEvent"},
+	}
+
+	f, err := newEventFactory(mix, 10, rand.New(rand.NewSource(1)))
+	assert.NilError(t, err)
+
+	now := time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)
+	counts := make(map[string]int)
+
+	for i := 1; i <= 1000; i++ {
+		event := f.newEvent(now)
+
+		e := event.GetEvent()
+		assert.Equal(t, e.Key, int32(i))
+		assert.Equal(t, e.CreatedTime, now)
+		assert.Assert(t, strings.HasPrefix(e.Vm.Name, "vm-"))
+		assert.Assert(t, strings.HasPrefix(e.Host.Name, "esx-"))
+
+		ce, err := events.NewFromVSphere(event, defaultSource)
+		assert.NilError(t, err)
+		counts[ce.Subject()]++
+
+		if ex, ok := event.(*types.EventEx); ok {
+			assert.Equal(t, ex.EventTypeId, "com.vmware.vc.HA.DasHostFailedEvent")
+			assert.Equal(t, ce.Type(), "com.vmware.event.router/eventex")
+		}
+	}
+
+	assert.Equal(t, len(counts), 2)
+	assert.Assert(t, counts["VmPoweredOnEvent"] > 2*counts["com.vmware.vc.HA.DasHostFailedEvent"], "events are generated according to their weight: %v", counts)
+
+	// same seed generates the same sequence
+	f1, err := newEventFactory(mix, 10, rand.New(rand.NewSource(42)))
+	assert.NilError(t, err)
+	f2, err := newEventFactory(mix, 10, rand.New(rand.NewSource(42)))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, f1.newEvent(now), f2.newEvent(now))
+}
@@ -0,0 +1,284 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider"
+)
+
+const (
+	defaultSource = "https://generator.vmware-event-router.local/sdk"
+
+	// generation falling behind the schedule by more than maxLag is not caught
+	// up to avoid unintended bursts, e.g. after a slow processor recovered
+	maxLag = time.Second
+)
+
+// assert we implement the provider interface
+var _ provider.Provider = (*Generator)(nil)
+
+// Generator is an event provider emitting synthetic vSphere events at a
+// target rate
+type Generator struct {
+	rate          float64
+	concurrency   int
+	maxEvents     int
+	burstSize     int
+	burstInterval time.Duration
+	source        string
+	factory       *eventFactory
+	logger.Logger
+
+	sync.RWMutex
+	stats    metrics.EventStats
+	reported reportState
+}
+
+// reportState is the state of the last throughput report
+type reportState struct {
+	time   time.Time
+	events int
+}
+
+// NewGenerator returns a synthetic event generator for the given configuration
+func NewGenerator(ctx context.Context, cfg *config.ProviderConfigGenerator, ms metrics.Receiver, log logger.Logger) (*Generator, error) {
+	if cfg == nil {
+		return nil, errors.New("generator configuration must be provided")
+	}
+
+	if cfg.Rate <= 0 || math.IsInf(cfg.Rate, 0) || math.IsNaN(cfg.Rate) {
+		return nil, errors.Errorf("invalid generator config: rate must be greater than 0: %v", cfg.Rate)
+	}
+
+	g := Generator{
+		rate:        cfg.Rate,
+		concurrency: cfg.Concurrency,
+		maxEvents:   cfg.MaxEvents,
+		source:      cfg.Source,
+		Logger:      log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		prov := strings.ToUpper(string(config.ProviderGenerator))
+		g.Logger = zapSugared.Named(fmt.Sprintf("[%s]", prov))
+	}
+
+	switch {
+	case cfg.Concurrency == 0:
+		g.concurrency = 1
+	case cfg.Concurrency < 0:
+		return nil, errors.Errorf("invalid generator config: concurrency must not be negative: %d", cfg.Concurrency)
+	}
+
+	if cfg.MaxEvents < 0 {
+		return nil, errors.Errorf("invalid generator config: maxEvents must not be negative: %d", cfg.MaxEvents)
+	}
+
+	if cfg.Entities < 0 {
+		return nil, errors.Errorf("invalid generator config: entities must not be negative: %d", cfg.Entities)
+	}
+
+	if b := cfg.Burst; b != nil {
+		if b.Size <= 0 || b.IntervalSeconds <= 0 {
+			return nil, errors.New("invalid generator config: burst size and intervalSeconds must be greater than 0")
+		}
+		g.burstSize = b.Size
+		g.burstInterval = time.Duration(b.IntervalSeconds) * time.Second
+	}
+
+	if g.source == "" {
+		g.source = defaultSource
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	factory, err := newEventFactory(cfg.Events, cfg.Entities, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid generator config")
+	}
+	g.factory = factory
+
+	g.stats = metrics.EventStats{
+		Provider:    string(config.ProviderGenerator),
+		Type:        config.EventProvider,
+		Address:     g.source,
+		Started:     time.Now().UTC(),
+		EventsTotal: new(int),
+		EventsErr:   new(int),
+		EventsSec:   new(float64),
+	}
+	g.reported = reportState{time: g.stats.Started}
+
+	go g.PushMetrics(ctx, ms)
+
+	return &g, nil
+}
+
+// PushMetrics pushes metrics to the configured metrics receiver and logs the
+// achieved throughput since the last push
+func (g *Generator) PushMetrics(ctx context.Context, ms metrics.Receiver) {
+	ticker := time.NewTicker(metrics.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Lock()
+			eventsSec := math.Round((float64(*g.stats.EventsTotal)/time.Since(g.stats.Started).Seconds())*100) / 100 // 0.2f syntax
+			g.stats.EventsSec = &eventsSec
+			ms.Receive(&g.stats)
+			g.report()
+			g.Unlock()
+		}
+	}
+}
+
+// report logs the throughput since the last report. Must be called with the
+// lock held.
+func (g *Generator) report() {
+	now := time.Now()
+	elapsed := now.Sub(g.reported.time).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	achieved := math.Round((float64(*g.stats.EventsTotal-g.reported.events)/elapsed)*100) / 100
+	g.Infow("throughput", "targetEventsSec", g.rate, "achievedEventsSec", achieved, "avgEventsSec", *g.stats.EventsSec, "events", *g.stats.EventsTotal, "errors", *g.stats.EventsErr)
+
+	g.reported = reportState{time: now, events: *g.stats.EventsTotal}
+}
+
+// Stream generates events at the configured rate and invokes the specified
+// processor for every event using the configured concurrency. The achieved
+// rate falls below the target rate if the processor cannot keep up. After
+// the maximum number of events has been generated Stream blocks until the
+// given context is cancelled.
+func (g *Generator) Stream(ctx context.Context, proc processor.Processor) error {
+	g.Infow("starting event generator", "rate", g.rate, "concurrency", g.concurrency, "maxEvents", g.maxEvents, "burstSize", g.burstSize, "burstInterval", g.burstInterval)
+
+	eventCh := make(chan types.BaseEvent)
+
+	var wg sync.WaitGroup
+	for i := 0; i < g.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range eventCh {
+				g.process(ctx, proc, event)
+			}
+		}()
+	}
+
+	err := g.generate(ctx, eventCh)
+	close(eventCh)
+	wg.Wait()
+
+	g.Lock()
+	g.report()
+	g.Unlock()
+
+	if err != nil {
+		g.Info("stopping event generator")
+		return err
+	}
+
+	g.Infow("event generator completed", "events", g.maxEvents)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// generate sends events to the given channel following the configured rate
+// and bursts until the maximum number of events has been generated or the
+// context is cancelled
+func (g *Generator) generate(ctx context.Context, eventCh chan<- types.BaseEvent) error {
+	var burstC <-chan time.Time
+	if g.burstInterval > 0 {
+		burst := time.NewTicker(g.burstInterval)
+		defer burst.Stop()
+		burstC = burst.C
+	}
+
+	interval := time.Duration(float64(time.Second) / g.rate)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var generated int
+	next := time.Now()
+	for g.maxEvents == 0 || generated < g.maxEvents {
+		n := 1
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-burstC:
+			n = g.burstSize
+		case <-timer.C:
+			now := time.Now()
+			if now.Sub(next) > maxLag {
+				g.Debugw("event generation fell behind schedule", "lag", now.Sub(next))
+				next = now
+			}
+			next = next.Add(interval)
+			timer.Reset(time.Until(next))
+		}
+
+		for i := 0; i < n && (g.maxEvents == 0 || generated < g.maxEvents); i++ {
+			event := g.factory.newEvent(time.Now().UTC())
+			generated++
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case eventCh <- event:
+			}
+		}
+	}
+
+	return nil
+}
+
+// process converts the given event into a CloudEvent and invokes the
+// processor
+func (g *Generator) process(ctx context.Context, proc processor.Processor, event types.BaseEvent) {
+	ce, err := events.NewFromVSphere(event, g.source)
+	if err != nil {
+		g.Errorw("skipping event because it could not be converted to CloudEvent format", "event", event, "error", err)
+	} else {
+		err = proc.Process(events.WithVSphereEvent(ctx, event), *ce)
+		if err != nil {
+			g.Errorw("could not process event", "eventID", ce.ID(), "error", err)
+		}
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	*g.stats.EventsTotal++
+	if err != nil {
+		*g.stats.EventsErr++
+	}
+}
+
+// Shutdown is a no-op
+func (g *Generator) Shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,141 @@
+//go:build unit
+// +build unit
+
+package generator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/generator"
+)
+
+func Test_Generator(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel))
+
+	t.Run("fails to start with invalid generator config", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			cfg       *config.ProviderConfigGenerator
+			errString string
+		}{
+			{"no config", nil, "generator configuration must be provided"},
+			{"no rate", &config.ProviderConfigGenerator{}, "rate must be greater than 0"},
+			{"negative concurrency", &config.ProviderConfigGenerator{Rate: 1, Concurrency: -1}, "concurrency must not be negative"},
+			{"negative max events", &config.ProviderConfigGenerator{Rate: 1, MaxEvents: -1}, "maxEvents must not be negative"},
+			{"invalid burst", &config.ProviderConfigGenerator{Rate: 1, Burst: &config.GeneratorBurst{Size: 10}}, "burst size and intervalSeconds must be greater than 0"},
+			{"unknown event type", &config.ProviderConfigGenerator{Rate: 1, Events: []config.GeneratorEvent{{Type: "VmPoweredOn"}}}, `invalid generator config: unknown vSphere event type "VmPoweredOn"`},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				_, err := generator.NewGenerator(context.TODO(), test.cfg, metricsStub{}, logger.Sugar())
+				assert.ErrorContains(t, err, test.errString)
+			})
+		}
+	})
+
+	t.Run("generates the maximum number of events at the target rate", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cfg := config.ProviderConfigGenerator{
+			Rate:        100,
+			Concurrency: 4,
+			MaxEvents:   20,
+			Events: []config.GeneratorEvent{
+				{Type: "VmPoweredOnEvent"},
+				{Type: "VmPoweredOffEvent"},
+			},
+			Seed:   1,
+			Source: "https://vcenter-01/sdk",
+		}
+
+		g, err := generator.NewGenerator(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 25)}
+
+		start := time.Now()
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return g.Stream(ctx, proc)
+		})
+
+		for i := 0; i < 20; i++ {
+			e := <-proc.events
+			assert.Equal(t, e.Type(), "com.vmware.event.router/event")
+			assert.Equal(t, e.Source(), "https://vcenter-01/sdk")
+			assert.Assert(t, e.Subject() == "VmPoweredOnEvent" || e.Subject() == "VmPoweredOffEvent", e.Subject())
+		}
+
+		// 20 events at 100 events/s
+		assert.Assert(t, time.Since(start) >= 150*time.Millisecond, "events must be generated at the target rate")
+
+		select {
+		case e := <-proc.events:
+			t.Fatalf("unexpected event after maximum number of events: %v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+
+	t.Run("generates bursts", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cfg := config.ProviderConfigGenerator{
+			Rate:  0.1, // first event immediately, next after 10s
+			Burst: &config.GeneratorBurst{Size: 50, IntervalSeconds: 1},
+		}
+
+		g, err := generator.NewGenerator(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 100)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return g.Stream(ctx, proc)
+		})
+
+		for i := 0; i < 51; i++ {
+			<-proc.events
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+type recordingProcessor struct {
+	events chan ce.Event
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.events <- e
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/pkg/errors"
 
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
 )
 
@@ -19,6 +21,11 @@ const (
 	defaultTimeout = time.Second * 5
 	defaultRetries = 3
 
+	// events paging
+	initialEventsSize = 10  // events retrieved on first request without time range
+	eventsPageSize    = 100 // events per page when retrieving a time range
+	eventsMaxPages    = 100 // upper bound of pages retrieved per time range
+
 	// Horizon API
 	loginPath   = "/rest/login"
 	logoutPath  = "/rest/logout"
@@ -28,10 +35,55 @@ const (
 
 var errTokenExpired = errors.New("refresh token expired")
 
+// errPageLimit is returned if not all events of a time range could be
+// retrieved within the maximum number of pages. The retrieved events are
+// returned along with the error. Events are returned newest first, i.e. the
+// oldest events of the time range are missing.
+var errPageLimit = errors.New("maximum number of event pages reached")
+
+// unavailableError is returned when the Horizon API server could not be reached
+// (transport error) or responded with a server-side (5xx) error
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return fmt.Sprintf("server unavailable: %v", e.err)
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// isUnavailable returns true if err is caused by an unavailable Horizon API
+// server
+func isUnavailable(err error) bool {
+	var u *unavailableError
+	return errors.As(err, &u)
+}
+
+// checkUnavailable returns an unavailableError if err is a transport error (not
+// caused by the given context) or the response status code is 5xx. Otherwise err
+// is returned.
+func checkUnavailable(ctx context.Context, res *resty.Response, err error) error {
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return &unavailableError{err: err}
+	}
+
+	if res.StatusCode() >= http.StatusInternalServerError {
+		return &unavailableError{err: fmt.Errorf("unexpected status code: %d %s", res.StatusCode(), string(res.Body()))}
+	}
+
+	return nil
+}
+
 // Client gets events from the configured Horizon API REST server. Remote()
 // returns the address of the Horizon API REST server.
 type Client interface {
-	GetEvents(ctx context.Context, since Timestamp) ([]AuditEventSummary, error)
+	GetEvents(ctx context.Context, since, until Timestamp) ([]AuditEventSummary, error)
 	Remote() string
 }
 
@@ -39,29 +91,43 @@ type horizonClient struct {
 	client      *resty.Client
 	credentials AuthLoginRequest
 	tokens      AuthTokens
+	filters     []EqualsFilter // optional server-side filters
+	pageSize    int
+	maxPages    int
 	logger      logger.Logger
 }
 
 var _ Client = (*horizonClient)(nil)
 
-func newHorizonClient(ctx context.Context, server string, credentials AuthLoginRequest, insecure bool, log logger.Logger) (*horizonClient, error) {
+func newHorizonClient(ctx context.Context, server string, credentials AuthLoginRequest, filters []EqualsFilter, insecure bool, log logger.Logger) (*horizonClient, error) {
+	c := newUnauthenticatedClient(server, credentials, filters, insecure, log)
+
+	c.logger.Debug("authenticating against Horizon API")
+	if err := c.login(ctx); err != nil {
+		return nil, errors.Wrap(err, "horizon API login")
+	}
+
+	return c, nil
+}
+
+// newUnauthenticatedClient returns a Horizon API client without performing a
+// login. Callers must call login() before retrieving events.
+func newUnauthenticatedClient(server string, credentials AuthLoginRequest, filters []EqualsFilter, insecure bool, log logger.Logger) *horizonClient {
 	rc := newRESTClient(server, insecure, log)
 	c := horizonClient{
 		client:      rc,
 		logger:      log,
 		credentials: credentials,
+		filters:     filters,
+		pageSize:    eventsPageSize,
+		maxPages:    eventsMaxPages,
 	}
 
 	if insecure {
 		c.logger.Warnw("using potentially insecure connection to Horizon API server", "address", server, "insecure", insecure)
 	}
 
-	c.logger.Debug("authenticating against Horizon API")
-	if err := c.login(ctx); err != nil {
-		return nil, errors.Wrap(err, "horizon API login")
-	}
-
-	return &c, nil
+	return &c
 }
 
 func newRESTClient(server string, insecure bool, log logger.Logger) *resty.Client {
@@ -108,7 +174,7 @@ func (h *horizonClient) login(ctx context.Context) error {
 
 	// perform full login
 	res, err := h.client.R().SetContext(ctx).SetBody(h.credentials).Post(loginPath)
-	if err != nil {
+	if err = checkUnavailable(ctx, res, err); err != nil {
 		return err
 	}
 
@@ -134,7 +200,7 @@ func (h *horizonClient) login(ctx context.Context) error {
 func (h *horizonClient) refresh(ctx context.Context) error {
 	request := RefreshTokenRequest{h.tokens.RefreshToken}
 	res, err := h.client.R().SetContext(ctx).SetBody(request).Post(refreshPath)
-	if err != nil {
+	if err = checkUnavailable(ctx, res, err); err != nil {
 		return err
 	}
 
@@ -161,39 +227,101 @@ func (h *horizonClient) refresh(ctx context.Context) error {
 	return nil
 }
 
-// GetEvents returns a list of AuditEventSummary from the Horizon API
-func (h *horizonClient) GetEvents(ctx context.Context, since Timestamp) ([]AuditEventSummary, error) {
+// GetEvents returns a list of AuditEventSummary from the Horizon API. If since
+// is 0 only the most recent events are returned. Otherwise all events between
+// since and until (0 for no upper bound) are retrieved, paging through the
+// results if needed. Paging stops when a page does not contain new events. If
+// the maximum number of pages is reached, the retrieved events are returned
+// with errPageLimit so a narrower time range can be requested.
+func (h *horizonClient) GetEvents(ctx context.Context, since, until Timestamp) ([]AuditEventSummary, error) {
+	filters := make([]interface{}, 0, len(h.filters)+1)
+	if since != 0 {
+		filters = append(filters, timeRangeFilter(since, until))
+	}
+
+	for _, f := range h.filters {
+		filters = append(filters, f)
+	}
+
+	filter, err := queryFilter(filters)
+	if err != nil {
+		return nil, errors.Wrap(err, "create query filter")
+	}
+	h.logger.Debugw("using query filter", "filter", filter)
+
+	// return last (up to) 10 initial events if no timestamp is specified
+	if since == 0 {
+		return h.getEventsPage(ctx, filter, 1, initialEventsSize)
+	}
+
+	var (
+		events []AuditEventSummary
+		seen   = make(map[int64]struct{})
+	)
+
+	for page := 1; ; page++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ev, err := h.getEventsPage(ctx, filter, page, h.pageSize)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get events page %d", page)
+		}
+
+		// new events arriving while paging shift results into the next page
+		added := 0
+		for i := range ev {
+			if _, ok := seen[ev[i].ID]; ok {
+				continue
+			}
+			seen[ev[i].ID] = struct{}{}
+			events = append(events, ev[i])
+			added++
+		}
+
+		if len(ev) < h.pageSize {
+			break
+		}
+
+		// guard against servers ignoring the page parameter
+		if added == 0 {
+			h.logger.Warnw("stopped paging: page did not contain new events", "page", page, "size", h.pageSize)
+			break
+		}
+
+		if h.maxPages > 0 && page >= h.maxPages {
+			return events, errors.Wrapf(errPageLimit, "retrieved %d events in %d pages", len(events), page)
+		}
+		h.logger.Debugw("retrieving next page of events", "page", page+1, "size", h.pageSize)
+	}
+
+	return events, nil
+}
+
+// getEventsPage returns the given page of AuditEventSummary from the Horizon
+// API using the (optional) JSON-encoded filter. Re-authentication is performed
+// if the access token has expired.
+func (h *horizonClient) getEventsPage(ctx context.Context, filter string, page, size int) ([]AuditEventSummary, error) {
 	var (
 		res     *resty.Response
 		retries int
 		err     error
-
-		timeRange string
-		params    map[string]string
 	)
 
-	// handle auth expired cases
-	for retries < 2 {
-		if since == 0 {
-			// return last (up to) 10 initial events if no timestamp is specified
-			params = map[string]string{
-				"size": "10",
-				"page": "1",
-			}
-		} else {
-			timeRange, err = timeRangeFilter(since, 0)
-			h.logger.Debugw("using time range filter", "filter", timeRange)
-			if err != nil {
-				return nil, errors.Wrap(err, "create time range query filter")
-			}
+	params := map[string]string{
+		"page": strconv.Itoa(page),
+		"size": strconv.Itoa(size),
+	}
 
-			params = map[string]string{
-				"filter": timeRange,
-			}
-		}
+	if filter != "" {
+		params["filter"] = filter
+	}
 
+	// handle auth expired cases
+	for retries < 2 {
 		res, err = h.client.R().SetContext(ctx).SetQueryParams(params).Get(eventsPath)
-		if err != nil {
+		if err = checkUnavailable(ctx, res, err); err != nil {
 			return nil, err
 		}
 
@@ -238,10 +366,10 @@ func (h *horizonClient) GetEvents(ctx context.Context, since Timestamp) ([]Audit
 	return nil, fmt.Errorf("get events status code: %d %s", res.StatusCode(), string(res.Body()))
 }
 
-// timeRangeFilter returns the JSON-encoded query string for the given timestamp
-// range. Both values are interpreted as inclusive range values. If to is 0 an
-// arbitrary time (UTC) in the future is used as the upper range bound.
-func timeRangeFilter(from, to Timestamp) (string, error) {
+// timeRangeFilter returns a filter for the given timestamp range. Both values
+// are interpreted as inclusive range values. If to is 0 an arbitrary time (UTC)
+// in the future is used as the upper range bound.
+func timeRangeFilter(from, to Timestamp) BetweenFilter {
 	// avoid small clock sync issues between client and server and use 1d as future
 	// timestamp buffer
 	timeBuffer := time.Hour * 24
@@ -250,12 +378,64 @@ func timeRangeFilter(from, to Timestamp) (string, error) {
 		to = Timestamp(time.Now().Add(timeBuffer).Unix() * 1000) // milliseconds
 	}
 
-	f := BetweenFilter{
+	return BetweenFilter{
 		Type:      "Between",
 		Name:      "time",
 		FromValue: from,
 		ToValue:   to,
 	}
+}
+
+// equalsFilters returns the list of equality filters for the non-empty fields
+// in the given configuration
+func equalsFilters(cfg *config.HorizonEventFilter) []EqualsFilter {
+	if cfg == nil {
+		return nil
+	}
+
+	var filters []EqualsFilter
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{name: "severity", value: cfg.Severity},
+		{name: "module", value: cfg.Module},
+		{name: "type", value: cfg.Type},
+		{name: "desktop_pool_name", value: cfg.DesktopPool},
+	}
+
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+
+		filters = append(filters, EqualsFilter{
+			Type:  "Equals",
+			Name:  f.name,
+			Value: f.value,
+		})
+	}
+
+	return filters
+}
+
+// queryFilter returns the JSON-encoded query string for the given filters.
+// Multiple filters are combined into a logical AND filter. An empty string is
+// returned if no filters are given.
+func queryFilter(filters []interface{}) (string, error) {
+	var f interface{}
+
+	switch len(filters) {
+	case 0:
+		return "", nil
+	case 1:
+		f = filters[0]
+	default:
+		f = AndFilter{
+			Type:    "And",
+			Filters: filters,
+		}
+	}
 
 	filter, err := json.Marshal(f)
 	if err != nil {
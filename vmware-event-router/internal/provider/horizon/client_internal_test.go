@@ -9,12 +9,16 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/zap/zaptest"
 	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 )
 
 const (
@@ -188,11 +192,183 @@ func Test_horizonClient_Logout(t *testing.T) {
 	})
 }
 
+func Test_horizonClient_GetEvents(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	newClient := func(ts *horizonAPIMock, filters []EqualsFilter, pageSize int) *horizonClient {
+		t.Helper()
+
+		h := &horizonClient{
+			client: newRESTClient(ts.httpSrv.URL, false, log.Sugar()),
+			credentials: AuthLoginRequest{
+				Domain:   testDomain,
+				Username: testUsername,
+				Password: testPassword,
+			},
+			filters:  filters,
+			pageSize: pageSize,
+			maxPages: eventsMaxPages,
+			logger:   log.Sugar(),
+		}
+
+		err := h.login(ctx)
+		assert.NilError(t, err)
+		return h
+	}
+
+	t.Run("initial events without time range", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		ts.setEvents(createFakeEvents(25))
+
+		h := newClient(ts, nil, 10)
+		got, err := h.GetEvents(ctx, 0, 0)
+		assert.NilError(t, err)
+		assert.Equal(t, len(got), initialEventsSize)
+		assert.Equal(t, ts.getRequests(), 1)
+		assert.Equal(t, ts.getLastFilter(), "")
+	})
+
+	t.Run("pages through all events in time range", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		events := createFakeEvents(25)
+		ts.setEvents(events)
+
+		h := newClient(ts, nil, 10)
+		got, err := h.GetEvents(ctx, 1, 0)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, events)
+		assert.Equal(t, ts.getRequests(), 3)
+	})
+
+	t.Run("requests additional empty page on exact page boundary", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		events := createFakeEvents(20)
+		ts.setEvents(events)
+
+		h := newClient(ts, nil, 10)
+		got, err := h.GetEvents(ctx, 1, 0)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, events)
+		assert.Equal(t, ts.getRequests(), 3)
+	})
+
+	t.Run("stops paging if server ignores page parameter", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		events := createFakeEvents(25)
+		ts.setEvents(events)
+		ts.setIgnorePage(true)
+
+		h := newClient(ts, nil, 10)
+		got, err := h.GetEvents(ctx, 1, 0)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, events[:10])
+		assert.Equal(t, ts.getRequests(), 2)
+	})
+
+	t.Run("stops paging at maximum number of pages", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		events := createFakeEvents(50)
+		ts.setEvents(events)
+
+		h := newClient(ts, nil, 10)
+		h.maxPages = 3
+		got, err := h.GetEvents(ctx, 1, 0)
+		assert.Assert(t, errors.Is(err, errPageLimit))
+		assert.DeepEqual(t, got, events[:30])
+		assert.Equal(t, ts.getRequests(), 3)
+	})
+
+	t.Run("stops paging if context is cancelled", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		ts.setEvents(createFakeEvents(25))
+
+		h := newClient(ts, nil, 10)
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := h.GetEvents(cancelled, 1, 0)
+		assert.Equal(t, err, context.Canceled)
+		assert.Equal(t, ts.getRequests(), 0)
+	})
+
+	t.Run("combines time range and configured filters", func(t *testing.T) {
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+		ts.setEvents(createFakeEvents(1))
+
+		filters := equalsFilters(&config.HorizonEventFilter{
+			Severity: "ERROR",
+			Module:   "Broker",
+		})
+
+		h := newClient(ts, filters, 10)
+		_, err := h.GetEvents(ctx, 1, 0)
+		assert.NilError(t, err)
+
+		var f struct {
+			Type    string            `json:"type"`
+			Filters []json.RawMessage `json:"filters"`
+		}
+		err = json.Unmarshal([]byte(ts.getLastFilter()), &f)
+		assert.NilError(t, err)
+		assert.Equal(t, f.Type, "And")
+		assert.Equal(t, len(f.Filters), 3)
+		assert.Equal(t, string(f.Filters[1]), `{"type":"Equals","name":"severity","value":"ERROR"}`)
+		assert.Equal(t, string(f.Filters[2]), `{"type":"Equals","name":"module","value":"Broker"}`)
+	})
+}
+
+func Test_queryFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []interface{}
+		want    string
+	}{
+		{
+			name:    "no filters",
+			filters: nil,
+			want:    "",
+		},
+		{
+			name:    "single filter is not wrapped",
+			filters: []interface{}{timeRangeFilter(1, 2)},
+			want:    `{"type":"Between","fromValue":1,"name":"time","toValue":2}`,
+		},
+		{
+			name: "multiple filters are combined with and",
+			filters: []interface{}{
+				timeRangeFilter(1, 2),
+				EqualsFilter{Type: "Equals", Name: "desktop_pool_name", Value: "pool-01"},
+			},
+			want: `{"type":"And","filters":[{"type":"Between","fromValue":1,"name":"time","toValue":2},{"type":"Equals","name":"desktop_pool_name","value":"pool-01"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryFilter(tt.filters)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
 type horizonAPIMock struct {
 	httpSrv *httptest.Server
 
 	sync.RWMutex
-	tokens AuthTokens
+	tokens      AuthTokens
+	events      []AuditEventSummary
+	requests    int    // event requests
+	lastFilter  string // last event request filter
+	unavailable bool   // return 503 for event requests
+	ignorePage  bool   // always return the first page of events
 }
 
 func newTestServer(_ context.Context) *horizonAPIMock {
@@ -208,6 +384,7 @@ func newTestServer(_ context.Context) *horizonAPIMock {
 	mux.HandleFunc(loginPath, ts.loginHandler)
 	mux.HandleFunc(logoutPath, ts.logoutHandler)
 	mux.HandleFunc(refreshPath, ts.refreshHandler)
+	mux.HandleFunc(eventsPath, ts.eventsHandler)
 
 	return &ts
 }
@@ -225,6 +402,86 @@ func (h *horizonAPIMock) getTokens() AuthTokens {
 	return h.tokens
 }
 
+func (h *horizonAPIMock) setEvents(events []AuditEventSummary) {
+	h.Lock()
+	h.events = events
+	h.Unlock()
+}
+
+func (h *horizonAPIMock) setUnavailable(unavailable bool) {
+	h.Lock()
+	h.unavailable = unavailable
+	h.Unlock()
+}
+
+func (h *horizonAPIMock) setIgnorePage(ignore bool) {
+	h.Lock()
+	h.ignorePage = ignore
+	h.Unlock()
+}
+
+func (h *horizonAPIMock) getRequests() int {
+	h.RLock()
+	defer h.RUnlock()
+	return h.requests
+}
+
+func (h *horizonAPIMock) getLastFilter() string {
+	h.RLock()
+	defer h.RUnlock()
+	return h.lastFilter
+}
+
+// eventsHandler returns the requested page of events. Filters are recorded but
+// not applied.
+func (h *horizonAPIMock) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Lock()
+	defer h.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+h.tokens.AccessToken {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	h.requests++
+	h.lastFilter = r.URL.Query().Get("filter")
+
+	if h.unavailable {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	size, err := strconv.Atoi(r.URL.Query().Get("size"))
+	if err != nil || size < 1 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if h.ignorePage {
+		page = 1
+	}
+
+	events := []AuditEventSummary{}
+	if start := (page - 1) * size; start < len(h.events) {
+		end := start + size
+		if end > len(h.events) {
+			end = len(h.events)
+		}
+		events = h.events[start:end]
+	}
+
+	w.Header().Set("content-type", "application/json")
+	if err = json.NewEncoder(w).Encode(events); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
 func (h *horizonAPIMock) loginHandler(w http.ResponseWriter, r *http.Request) {
 	var creds AuthLoginRequest
 	dec := json.NewDecoder(r.Body)
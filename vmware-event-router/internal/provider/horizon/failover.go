@@ -0,0 +1,138 @@
+package horizon
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+// failoverClient gets events from a list of Horizon connection servers. Only
+// one server is active at a time. If the active server is unavailable, i.e.
+// transport or 5xx errors, the client fails over to the next server in the
+// list. Authentication tokens are kept per server.
+type failoverClient struct {
+	servers []*horizonClient
+	logger  logger.Logger
+
+	mu     sync.RWMutex
+	active int // index of the active server
+}
+
+var _ Client = (*failoverClient)(nil)
+
+// newFailoverClient returns a failover client for the given Horizon connection
+// servers. The first server accepting the login becomes the active server. An
+// error is returned if no server is available or a server rejects the login.
+func newFailoverClient(ctx context.Context, servers []string, credentials AuthLoginRequest, filters []EqualsFilter, insecure bool, log logger.Logger) (*failoverClient, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("no Horizon API server specified")
+	}
+
+	f := failoverClient{
+		servers: make([]*horizonClient, len(servers)),
+		logger:  log,
+	}
+
+	for i, s := range servers {
+		f.servers[i] = newUnauthenticatedClient(s, credentials, filters, insecure, log)
+	}
+
+	for i := range f.servers {
+		c := f.servers[i]
+		f.logger.Debugw("authenticating against Horizon API", "address", c.Remote())
+
+		err := c.login(ctx)
+		if err == nil {
+			f.active = i
+			return &f, nil
+		}
+
+		if !isUnavailable(err) {
+			return nil, errors.Wrapf(err, "horizon API login %q", c.Remote())
+		}
+		f.logger.Warnw("horizon API server unavailable", "address", c.Remote(), "error", err)
+	}
+
+	return nil, errors.New("horizon API login: all servers unavailable")
+}
+
+// GetEvents returns a list of AuditEventSummary from the active Horizon API
+// server. If the active server is unavailable, the remaining servers are tried
+// in order until one succeeds. An error is returned if all servers are
+// unavailable.
+func (f *failoverClient) GetEvents(ctx context.Context, since, until Timestamp) ([]AuditEventSummary, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < len(f.servers); attempt++ {
+		f.mu.RLock()
+		idx := f.active
+		f.mu.RUnlock()
+
+		c := f.servers[idx]
+
+		var (
+			ev  []AuditEventSummary
+			err error
+		)
+		// login to servers which have not been used yet
+		if c.tokens.AccessToken == "" {
+			err = c.login(ctx)
+		}
+
+		if err == nil {
+			ev, err = c.GetEvents(ctx, since, until)
+			if err == nil {
+				return ev, nil
+			}
+		}
+
+		// events retrieved before reaching the page limit are passed on
+		if !isUnavailable(err) {
+			return ev, err
+		}
+
+		lastErr = err
+		next := f.failover(idx)
+		f.logger.Warnw("horizon API server unavailable, failing over", "address", c.Remote(), "next", f.servers[next].Remote(), "error", err)
+	}
+
+	return nil, errors.Wrap(lastErr, "all Horizon API servers unavailable")
+}
+
+// failover sets the active server to the one following idx and returns its
+// index
+func (f *failoverClient) failover(idx int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.active = (idx + 1) % len(f.servers)
+	return f.active
+}
+
+// Remote returns the address of the active Horizon API server
+func (f *failoverClient) Remote() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.servers[f.active].Remote()
+}
+
+// logout performs a logout against all Horizon API servers with an existing
+// session
+func (f *failoverClient) logout(ctx context.Context) error {
+	var lastErr error
+	for _, c := range f.servers {
+		if c.tokens.RefreshToken == "" {
+			continue
+		}
+
+		if err := c.logout(ctx); err != nil {
+			f.logger.Warnw("could not log out", "address", c.Remote(), "error", err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
@@ -0,0 +1,120 @@
+//go:build unit
+// +build unit
+
+package horizon
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+)
+
+func Test_newFailoverClient(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	creds := AuthLoginRequest{
+		Domain:   testDomain,
+		Username: testUsername,
+		Password: testPassword,
+	}
+
+	t.Run("first available server becomes active", func(t *testing.T) {
+		down := newTestServer(ctx)
+		down.httpSrv.Close()
+
+		ts := newTestServer(ctx)
+		defer ts.httpSrv.Close()
+
+		f, err := newFailoverClient(ctx, []string{down.httpSrv.URL, ts.httpSrv.URL}, creds, nil, false, log.Sugar())
+		assert.NilError(t, err)
+		assert.Equal(t, f.Remote(), ts.httpSrv.URL)
+	})
+
+	t.Run("all servers unavailable", func(t *testing.T) {
+		down := newTestServer(ctx)
+		down.httpSrv.Close()
+
+		_, err := newFailoverClient(ctx, []string{down.httpSrv.URL}, creds, nil, false, log.Sugar())
+		assert.ErrorContains(t, err, "all servers unavailable")
+	})
+
+	t.Run("invalid credentials do not fail over", func(t *testing.T) {
+		ts1 := newTestServer(ctx)
+		defer ts1.httpSrv.Close()
+		ts2 := newTestServer(ctx)
+		defer ts2.httpSrv.Close()
+
+		invalid := AuthLoginRequest{Domain: testDomain, Username: "unknown", Password: "wrong"}
+		_, err := newFailoverClient(ctx, []string{ts1.httpSrv.URL, ts2.httpSrv.URL}, invalid, nil, false, log.Sugar())
+		assert.ErrorContains(t, err, "401")
+	})
+}
+
+func Test_failoverClient_GetEvents(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	creds := AuthLoginRequest{
+		Domain:   testDomain,
+		Username: testUsername,
+		Password: testPassword,
+	}
+
+	t.Run("fails over on server error and keeps tokens per server", func(t *testing.T) {
+		ts1 := newTestServer(ctx)
+		defer ts1.httpSrv.Close()
+		ts2 := newTestServer(ctx)
+		defer ts2.httpSrv.Close()
+
+		events := createFakeEvents(5)
+		ts1.setEvents(events)
+		ts2.setEvents(events)
+
+		f, err := newFailoverClient(ctx, []string{ts1.httpSrv.URL, ts2.httpSrv.URL}, creds, nil, false, log.Sugar())
+		assert.NilError(t, err)
+		assert.Equal(t, f.Remote(), ts1.httpSrv.URL)
+
+		ts1.setUnavailable(true)
+		got, err := f.GetEvents(ctx, 1, 0)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, got, events)
+		assert.Equal(t, f.Remote(), ts2.httpSrv.URL)
+
+		assert.Equal(t, f.servers[0].tokens, ts1.getTokens())
+		assert.Equal(t, f.servers[1].tokens, ts2.getTokens())
+	})
+
+	t.Run("fails over on transport error", func(t *testing.T) {
+		ts1 := newTestServer(ctx)
+		ts2 := newTestServer(ctx)
+		defer ts2.httpSrv.Close()
+		ts2.setEvents(createFakeEvents(1))
+
+		f, err := newFailoverClient(ctx, []string{ts1.httpSrv.URL, ts2.httpSrv.URL}, creds, nil, false, log.Sugar())
+		assert.NilError(t, err)
+
+		ts1.httpSrv.Close()
+		got, err := f.GetEvents(ctx, 1, 0)
+		assert.NilError(t, err)
+		assert.Equal(t, len(got), 1)
+		assert.Equal(t, f.Remote(), ts2.httpSrv.URL)
+	})
+
+	t.Run("all servers unavailable", func(t *testing.T) {
+		ts1 := newTestServer(ctx)
+		defer ts1.httpSrv.Close()
+		ts2 := newTestServer(ctx)
+		defer ts2.httpSrv.Close()
+
+		f, err := newFailoverClient(ctx, []string{ts1.httpSrv.URL, ts2.httpSrv.URL}, creds, nil, false, log.Sugar())
+		assert.NilError(t, err)
+
+		ts1.setUnavailable(true)
+		ts2.setUnavailable(true)
+		_, err = f.GetEvents(ctx, 1, 0)
+		assert.ErrorContains(t, err, "all Horizon API servers unavailable")
+	})
+}
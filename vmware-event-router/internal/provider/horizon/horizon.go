@@ -27,7 +27,6 @@ import (
 
 const (
 	defaultPollInterval = time.Second
-	eventTypeScheme     = "%s/horizon.%s.v0" // router prefix + normalized event type
 )
 
 var (
@@ -58,14 +57,9 @@ func NewEventStream(ctx context.Context, cfg *config.ProviderConfigHorizon, ms m
 		return nil, errors.New("horizon configuration must be provided")
 	}
 
-	u, err := url.Parse(cfg.Address)
+	addresses, err := parseAddresses(cfg)
 	if err != nil {
-		return nil, errors.Wrapf(err, "address invalid: %q", cfg.Address)
-	}
-
-	// catches parsing errors which url.Parse won't err out
-	if u.Host == "" {
-		return nil, fmt.Errorf("address invalid: %q", cfg.Address)
+		return nil, err
 	}
 
 	auth := cfg.Auth
@@ -108,16 +102,22 @@ func NewEventStream(ctx context.Context, cfg *config.ProviderConfigHorizon, ms m
 		Password: authDetails.Password,
 	}
 
-	client, err := newHorizonClient(ctx, u.String(), creds, cfg.InsecureSSL, stream.Logger)
+	filters := equalsFilters(cfg.Filter)
+	if len(filters) > 0 {
+		stream.Infow("using server-side event filters", "filters", filters)
+	}
+
+	client, err := newFailoverClient(ctx, addresses, creds, filters, cfg.InsecureSSL, stream.Logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "create horizon API client")
 	}
 	stream.client = client
+	stream.Infow("using Horizon API server", "address", client.Remote(), "servers", addresses)
 
 	stream.stats = metrics.EventStats{
 		Provider:    string(config.ProviderHorizon),
 		Type:        config.EventProvider,
-		Address:     u.String(),
+		Address:     client.Remote(),
 		Started:     time.Now().UTC(),
 		EventsTotal: new(int),
 		EventsErr:   new(int),
@@ -134,6 +134,36 @@ func NewEventStream(ctx context.Context, cfg *config.ProviderConfigHorizon, ms m
 	return &stream, nil
 }
 
+// parseAddresses returns the list of validated Horizon API server addresses from
+// the given configuration. Address, if set, is the first server in the list.
+func parseAddresses(cfg *config.ProviderConfigHorizon) ([]string, error) {
+	var addresses []string
+	if cfg.Address != "" {
+		addresses = append(addresses, cfg.Address)
+	}
+	addresses = append(addresses, cfg.Addresses...)
+
+	if len(addresses) == 0 {
+		return nil, errors.New("address invalid: at least one address must be specified")
+	}
+
+	parsed := make([]string, len(addresses))
+	for i, a := range addresses {
+		u, err := url.Parse(a)
+		if err != nil {
+			return nil, errors.Wrapf(err, "address invalid: %q", a)
+		}
+
+		// catches parsing errors which url.Parse won't err out
+		if u.Host == "" {
+			return nil, fmt.Errorf("address invalid: %q", a)
+		}
+		parsed[i] = u.String()
+	}
+
+	return parsed, nil
+}
+
 // PushMetrics periodically pushes metrics to the metrics server
 func (es *EventStream) PushMetrics(ctx context.Context, ms metrics.Receiver) {
 	ticker := es.clock.Ticker(metrics.PushInterval)
@@ -159,6 +189,8 @@ func (es *EventStream) Stream(ctx context.Context, p processor.Processor) error
 	var (
 		lastEvent *AuditEventSummary
 		since     Timestamp
+		until     Timestamp                   // upper time range bound, 0 if unbounded
+		processed = make(map[int64]Timestamp) // recently processed event IDs
 	)
 
 	if es.backoffConfig == nil {
@@ -175,23 +207,36 @@ func (es *EventStream) Stream(ctx context.Context, p processor.Processor) error
 			return ctx.Err()
 
 		case <-ticker.C:
-			if lastEvent != nil {
+			if lastEvent != nil && Timestamp(lastEvent.Time) > since {
 				since = Timestamp(lastEvent.Time)
 			}
 
 			if since == 0 {
 				es.Debug("retrieving initial set of events")
 			} else {
-				es.Debugw("retrieving events with time range filter", "sinceUnixMilli", since, "sinceConverted", time.Unix(int64(since/1000), 0).String())
+				es.Debugw("retrieving events with time range filter", "sinceUnixMilli", since, "untilUnixMilli", until, "sinceConverted", time.Unix(int64(since/1000), 0).String())
+			}
+
+			ev, err := es.client.GetEvents(ctx, since, until)
+			if errors.Is(err, errPageLimit) {
+				// do not move past events which have not been retrieved
+				if mid, ok := splitRange(since, until, es.clock.Now()); ok {
+					es.Warnw("could not retrieve all events in time range, narrowing time range", "sinceUnixMilli", since, "untilUnixMilli", mid, "error", err)
+					until = mid
+					continue
+				}
+
+				es.Errorw("could not retrieve all events in smallest possible time range, events might be lost", "sinceUnixMilli", since, "untilUnixMilli", until, "error", err)
+				err = nil
 			}
 
-			ev, err := es.client.GetEvents(ctx, since)
 			if err != nil {
 				return errors.Wrap(err, "get events")
 			}
+			es.updateRemote()
 
 			// check if returned event is same as last event
-			if len(ev) == 1 {
+			if len(ev) == 1 && until == 0 {
 				if lastEvent != nil && ev[0].ID == lastEvent.ID {
 					sleep := es.backoffConfig.Duration()
 					es.Logger.Debugw("no new events, backing off", "delaySeconds", sleep)
@@ -202,14 +247,44 @@ func (es *EventStream) Stream(ctx context.Context, p processor.Processor) error
 
 			es.Logger.Debugw("retrieved new events", "count", len(ev))
 			ev = removeDuplicates(ev, lastEvent)
+			ev = removeProcessed(ev, processed)
 			es.Logger.Debugw("remaining new events after filtering out duplicate events", "count", len(ev))
 
-			lastEvent = es.processEvents(ctx, ev, p)
+			// note: ev is in ascending time order after processing
+			last := es.processEvents(ctx, ev, p, processed)
+			if last != nil {
+				lastEvent = last
+				pruneProcessed(processed, Timestamp(lastEvent.Time))
+			}
+
+			// continue with the remaining time range once the narrowed time
+			// range has been processed up to its newest event
+			if until != 0 && (len(ev) == 0 || last != nil && last.ID == ev[len(ev)-1].ID) {
+				since = until
+				until = 0
+			}
 			es.backoffConfig.Reset()
 		}
 	}
 }
 
+// splitRange returns the middle of the time range between since and until to
+// narrow down a time range containing too many events. If until is 0, now is
+// used as the upper bound. False is returned if the time range cannot be
+// narrowed down any further.
+func splitRange(since, until Timestamp, now time.Time) (Timestamp, bool) {
+	if until == 0 {
+		until = Timestamp(now.UnixMilli())
+	}
+
+	mid := since + (until-since)/2
+	if mid <= since {
+		return 0, false
+	}
+
+	return mid, true
+}
+
 // removeDuplicates returns a copy of events with dup element(s) removed
 func removeDuplicates(es []AuditEventSummary, dup *AuditEventSummary) []AuditEventSummary {
 	cleaned := make([]AuditEventSummary, len(es))
@@ -230,11 +305,49 @@ func removeDuplicates(es []AuditEventSummary, dup *AuditEventSummary) []AuditEve
 	return cleaned
 }
 
-// processEvents sends the given events to the specified processor. Errors from
-// the processor will be logged but not returned. There is a risk of poison
-// pills here when all events cannot be processed leading to a constant loop in
-// the invoking function.
-func (es *EventStream) processEvents(ctx context.Context, ev []AuditEventSummary, p processor.Processor) *AuditEventSummary {
+// removeProcessed returns a copy of events without the events contained in
+// processed. Overlapping events, e.g. after failing over to another Horizon
+// API server, are removed this way.
+func removeProcessed(es []AuditEventSummary, processed map[int64]Timestamp) []AuditEventSummary {
+	cleaned := make([]AuditEventSummary, 0, len(es))
+	for i := range es {
+		if _, ok := processed[es[i].ID]; ok {
+			continue
+		}
+		cleaned = append(cleaned, es[i])
+	}
+	return cleaned
+}
+
+// pruneProcessed removes all events older than since from processed. These
+// events are not returned anymore by the time range filter.
+func pruneProcessed(processed map[int64]Timestamp, since Timestamp) {
+	for id, ts := range processed {
+		if ts < since {
+			delete(processed, id)
+		}
+	}
+}
+
+// updateRemote records the active Horizon API server in the metrics stats and
+// logs server changes
+func (es *EventStream) updateRemote() {
+	remote := es.client.Remote()
+
+	es.Lock()
+	defer es.Unlock()
+
+	if es.stats.Address != remote {
+		es.Warnw("switched active Horizon API server", "previous", es.stats.Address, "active", remote)
+		es.stats.Address = remote
+	}
+}
+
+// processEvents sends the given events to the specified processor and records
+// successfully processed events in processed. Errors from the processor will be
+// logged but not returned. There is a risk of poison pills here when all events
+// cannot be processed leading to a constant loop in the invoking function.
+func (es *EventStream) processEvents(ctx context.Context, ev []AuditEventSummary, p processor.Processor, processed map[int64]Timestamp) *AuditEventSummary {
 	var (
 		errCount = 0
 
@@ -262,6 +375,7 @@ func (es *EventStream) processEvents(ctx context.Context, ev []AuditEventSummary
 			continue
 		}
 		lastEvent = &ev[i]
+		processed[ev[i].ID] = Timestamp(ev[i].Time)
 	}
 
 	// update metrics
@@ -283,42 +397,25 @@ func reverse(ev []AuditEventSummary) {
 	}
 }
 
+// newCloudEvent returns a CloudEvent for the given Horizon audit event. The
+// Horizon event ID is used as the CloudEvent ID.
 func newCloudEvent(event AuditEventSummary, source string) (*cloudevents.Event, error) {
-	ce := cloudevents.NewEvent()
-
-	// TODO: revisit CE properties used here
-	ce.SetSource(source)
-	t := time.Unix(event.Time/1000, 0)
-	ce.SetTime(t)
-	id := strconv.FormatInt(event.ID, 10)
-	ce.SetID(id)
-
-	ce.SetType(convertEventType(event.Type))
-
-	var err error
-	err = ce.SetData(events.EventContentType, event)
-	if err != nil {
-		return nil, errors.Wrap(err, "set CloudEvent data")
-	}
-
-	if err = ce.Validate(); err != nil {
-		return nil, errors.Wrap(err, "validation for CloudEvent failed")
+	info := events.HorizonEventInfo{
+		Type:        event.Type,
+		Time:        time.UnixMilli(event.Time).UTC(),
+		Severity:    event.Severity,
+		Module:      event.Module,
+		User:        event.UserID,
+		DesktopPool: event.DesktopPoolName,
 	}
 
-	return &ce, nil
-}
-
-// convertEventType converts a Horizon event type to a normalized cloud event
-// type. For example, VLSI_USERLOGGEDIN is converted to
-// com.vmware.event.router/horizon.vlsi.userloggedin.v0
-func convertEventType(t string) string {
-	t = strings.ToLower(t)
-	return fmt.Sprintf(eventTypeScheme, events.EventCanonicalType, t)
+	id := strconv.FormatInt(event.ID, 10)
+	return events.NewFromHorizon(info, event, source, events.WithID(id))
 }
 
 // Shutdown performs a graceful shutdown of the event stream provider
 func (es *EventStream) Shutdown(_ context.Context) error {
-	if c, ok := es.client.(*horizonClient); ok {
+	if c, ok := es.client.(*failoverClient); ok {
 		err := c.logout(context.Background()) // fresh context to avoid canceled err
 		if err != nil {
 			es.Logger.Warnf("could not log out: %v", err)
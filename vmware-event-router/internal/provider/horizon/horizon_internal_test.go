@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -83,6 +84,31 @@ func TestNewEventStream(t *testing.T) {
 			want:      nil,
 			errString: "invalid",
 		},
+		{
+			name: "no Horizon address provided",
+			args: args{
+				ctx: context.TODO(),
+				cfg: &config.ProviderConfigHorizon{
+					Addresses: []string{},
+				},
+				log: zaptest.NewLogger(t).Sugar(),
+			},
+			want:      nil,
+			errString: "at least one address must be specified",
+		},
+		{
+			name: "invalid Horizon address in list provided",
+			args: args{
+				ctx: context.TODO(),
+				cfg: &config.ProviderConfigHorizon{
+					Address:   "https://myserver.horizon.com",
+					Addresses: []string{"myserver//"},
+				},
+				log: zaptest.NewLogger(t).Sugar(),
+			},
+			want:      nil,
+			errString: "address invalid",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -113,6 +139,27 @@ func Test_removeDuplicates(t *testing.T) {
 	})
 }
 
+func Test_removeProcessed(t *testing.T) {
+	t.Run("no processed events", func(t *testing.T) {
+		ev := createFakeEvents(3)
+		got := removeProcessed(ev, map[int64]Timestamp{})
+		assert.DeepEqual(t, got, ev)
+	})
+
+	t.Run("overlapping events are removed", func(t *testing.T) {
+		ev := createFakeEvents(4)
+		processed := map[int64]Timestamp{10: 0, 12: 0}
+		got := removeProcessed(ev, processed)
+		assert.DeepEqual(t, got, []AuditEventSummary{ev[1], ev[3]})
+	})
+}
+
+func Test_pruneProcessed(t *testing.T) {
+	processed := map[int64]Timestamp{1: 100, 2: 200, 3: 300}
+	pruneProcessed(processed, 200)
+	assert.DeepEqual(t, processed, map[int64]Timestamp{2: 200, 3: 300})
+}
+
 // createFakeEvents creates returns a []AuditEventSummary where the ID of each
 // element is set to the sum 10 plus the current counter
 func createFakeEvents(count int) []AuditEventSummary {
@@ -178,6 +225,182 @@ func TestEventStreamMock_Stream(t *testing.T) {
 	assert.Equal(t, fp.got, fp.expect)
 }
 
+func TestEventStream_StreamPageLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	log := zaptest.NewLogger(t)
+
+	// newest first
+	events := []AuditEventSummary{
+		{ID: 3, Type: "VLSI_USERLOGGEDIN", Time: 3000},
+		{ID: 2, Type: "VLSI_USERLOGGEDIN", Time: 2000},
+		{ID: 1, Type: "VLSI_USERLOGGEDIN", Time: 1000},
+	}
+
+	pc := pageLimitClient{
+		initial: events[2:],
+		events:  events[:2],
+		limited: 2,
+		done:    cancel,
+	}
+
+	stream := EventStream{
+		client:        &pc,
+		clock:         clock.New(),
+		pollInterval:  time.Millisecond * 10,
+		Logger:        log.Sugar(),
+		backoffConfig: &backoff.Backoff{Max: time.Millisecond},
+		stats: metrics.EventStats{
+			EventsTotal: new(int),
+			EventsErr:   new(int),
+			EventsSec:   new(float64),
+		},
+	}
+
+	fp := &fakeProcessor{t: t, log: log.Sugar()}
+	err := stream.Stream(ctx, fp)
+	assert.ErrorContains(t, err, "context canceled")
+
+	// time range is retried from the last processed event until all events are
+	// retrieved
+	assert.Assert(t, len(pc.since) >= 4)
+	assert.DeepEqual(t, pc.since[:4], []Timestamp{0, 1000, 1000, 1000})
+	assert.Equal(t, fp.got, 3)
+}
+
+func TestEventStream_StreamPersistentPageLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	log := zaptest.NewLogger(t)
+
+	// burst of events after downtime, newest first
+	start := time.Now().Add(-time.Minute).UnixMilli()
+	var events []AuditEventSummary
+	for i := 20; i > 0; i-- {
+		events = append(events, AuditEventSummary{
+			ID:   int64(i),
+			Type: "VLSI_USERLOGGEDIN",
+			Time: start + int64(i)*1000,
+		})
+	}
+
+	rc := rangeLimitClient{
+		initial: []AuditEventSummary{{ID: 0, Type: "VLSI_USERLOGGEDIN", Time: start}},
+		events:  events,
+		limit:   5,
+		done:    cancel,
+	}
+
+	stream := EventStream{
+		client:        &rc,
+		clock:         clock.New(),
+		pollInterval:  time.Millisecond * 10,
+		Logger:        log.Sugar(),
+		backoffConfig: &backoff.Backoff{Max: time.Millisecond},
+		stats: metrics.EventStats{
+			EventsTotal: new(int),
+			EventsErr:   new(int),
+			EventsSec:   new(float64),
+		},
+	}
+
+	fp := &fakeProcessor{t: t, log: log.Sugar()}
+	err := stream.Stream(ctx, fp)
+	assert.ErrorContains(t, err, "context canceled")
+
+	// the open time range always exceeds the limit, narrowed time ranges are
+	// used to deliver all events in order
+	assert.Assert(t, rc.limited > 2)
+	want := []string{"0"}
+	for i := 1; i <= 20; i++ {
+		want = append(want, strconv.Itoa(i))
+	}
+	assert.DeepEqual(t, fp.ids, want)
+
+	for i := 1; i < len(rc.since); i++ {
+		assert.Assert(t, rc.since[i] >= rc.since[i-1], "since moved backwards: %v", rc.since)
+	}
+	assert.Equal(t, rc.since[len(rc.since)-1], Timestamp(events[0].Time))
+}
+
+// rangeLimitClient returns the initial events on the first invocation. Further
+// invocations return the events within the requested time range or at most
+// limit (newest) events with errPageLimit, like the Horizon client does when
+// reaching the maximum number of pages. done is called once the newest event
+// is requested without an upper time range bound.
+type rangeLimitClient struct {
+	initial []AuditEventSummary
+	events  []AuditEventSummary // newest first
+	limit   int
+	limited int
+	since   []Timestamp
+	done    func()
+}
+
+func (r *rangeLimitClient) GetEvents(_ context.Context, since, until Timestamp) ([]AuditEventSummary, error) {
+	if since == 0 {
+		return r.initial, nil
+	}
+	r.since = append(r.since, since)
+
+	if until == 0 && since >= Timestamp(r.events[0].Time) {
+		r.done()
+	}
+
+	all := make([]AuditEventSummary, 0, len(r.events)+len(r.initial))
+	all = append(all, r.events...)
+	all = append(all, r.initial...)
+
+	var ev []AuditEventSummary
+	for _, e := range all {
+		ts := Timestamp(e.Time)
+		if ts >= since && (until == 0 || ts <= until) {
+			ev = append(ev, e)
+		}
+	}
+
+	if len(ev) > r.limit {
+		r.limited++
+		return ev[:r.limit], errPageLimit
+	}
+	return ev, nil
+}
+
+func (r *rangeLimitClient) Remote() string {
+	return fakeServer
+}
+
+// pageLimitClient returns the initial events on the first invocation and
+// errPageLimit for the given number of invocations before returning events.
+// done is called once events have been returned.
+type pageLimitClient struct {
+	initial []AuditEventSummary
+	events  []AuditEventSummary
+	limited int
+	since   []Timestamp
+	done    func()
+}
+
+func (p *pageLimitClient) GetEvents(_ context.Context, since, _ Timestamp) ([]AuditEventSummary, error) {
+	p.since = append(p.since, since)
+
+	switch {
+	case len(p.since) == 1:
+		return p.initial, nil
+	case len(p.since) <= p.limited+1:
+		return nil, errPageLimit
+	default:
+		p.done()
+		return p.events, nil
+	}
+}
+
+func (p *pageLimitClient) Remote() string {
+	return fakeServer
+}
+
 type fakeClient struct {
 	invocations int
 	events      []AuditEventSummary
@@ -187,7 +410,7 @@ type fakeClient struct {
 // GetEvents initially returns all events including up to second last events. On
 // second invocation returns second last event. On third invocation returns
 // second last and last event. Further invocations will only return last event.
-func (f *fakeClient) GetEvents(_ context.Context, _ Timestamp) ([]AuditEventSummary, error) {
+func (f *fakeClient) GetEvents(_ context.Context, _, _ Timestamp) ([]AuditEventSummary, error) {
 	f.invocations++
 	f.log.Debugf("GetEvents invocations: %d", f.invocations)
 
@@ -222,6 +445,7 @@ type fakeProcessor struct {
 	log    logger.Logger
 	got    int
 	expect int
+	ids    []string
 }
 
 func (f *fakeProcessor) Process(_ context.Context, ce ce.Event) error {
@@ -231,6 +455,7 @@ func (f *fakeProcessor) Process(_ context.Context, ce ce.Event) error {
 	assert.NilError(f.t, err)
 
 	f.got++
+	f.ids = append(f.ids, ce.ID())
 	f.log.Debugf("processed events invocations: %d", f.got)
 	return nil
 }
@@ -241,35 +466,48 @@ func (f *fakeProcessor) Shutdown(_ context.Context) error {
 	return nil
 }
 
-func Test_convertEventType(t *testing.T) {
-	type args struct {
-		t string
-	}
-	tests := []struct {
-		name string
-		args args
-		want string
-	}{
-		{
-			name: "REST_AUTH_REFRESH_TOKEN_SUCCESS",
-			args: args{t: "REST_AUTH_REFRESH_TOKEN_SUCCESS"},
-			want: "com.vmware.event.router/horizon.rest_auth_refresh_token_success.v0",
-		},
-		{
-			name: "VLSI_USERLOGGEDIN",
-			args: args{t: "VLSI_USERLOGGEDIN"},
-			want: "com.vmware.event.router/horizon.vlsi_userloggedin.v0",
-		},
-		{
-			name: "REST_AUTH_LOGIN_SUCCESS",
-			args: args{t: "REST_AUTH_LOGIN_SUCCESS"},
-			want: "com.vmware.event.router/horizon.rest_auth_login_success.v0",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := convertEventType(tt.args.t)
-			assert.Equal(t, got, tt.want)
+func Test_newCloudEvent(t *testing.T) {
+	t.Run("event with all attributes", func(t *testing.T) {
+		event := AuditEventSummary{
+			ID:              98563,
+			Type:            "VLSI_USERLOGGEDIN",
+			Severity:        "AUDIT_SUCCESS",
+			Module:          "Vlsi",
+			UserID:          "S-1-5-21-1111111111-2222222222-3333333333-500",
+			DesktopPoolName: "win10-pool",
+			Time:            1627369939733,
+		}
+
+		got, err := newCloudEvent(event, fakeServer)
+		assert.NilError(t, err)
+		assert.Equal(t, got.ID(), "98563")
+		assert.Equal(t, got.Source(), fakeServer)
+		assert.Equal(t, got.Type(), "com.vmware.event.router/horizon")
+		assert.Equal(t, got.Subject(), "VLSI_USERLOGGEDIN")
+		assert.Equal(t, got.Time().UnixNano(), int64(1627369939733)*int64(time.Millisecond))
+		assert.DeepEqual(t, got.Extensions(), map[string]interface{}{
+			"severity":    "AUDIT_SUCCESS",
+			"module":      "Vlsi",
+			"user":        "S-1-5-21-1111111111-2222222222-3333333333-500",
+			"desktoppool": "win10-pool",
 		})
-	}
+	})
+
+	t.Run("empty attributes are omitted", func(t *testing.T) {
+		event := AuditEventSummary{
+			ID:       98564,
+			Type:     "REST_AUTH_LOGIN_SUCCESS",
+			Severity: "AUDIT_SUCCESS",
+			Module:   "Rest",
+			Time:     1627369939000,
+		}
+
+		got, err := newCloudEvent(event, fakeServer)
+		assert.NilError(t, err)
+		assert.Equal(t, got.Subject(), "REST_AUTH_LOGIN_SUCCESS")
+		assert.DeepEqual(t, got.Extensions(), map[string]interface{}{
+			"severity": "AUDIT_SUCCESS",
+			"module":   "Rest",
+		})
+	})
 }
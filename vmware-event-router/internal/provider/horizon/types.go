@@ -85,6 +85,19 @@ type BetweenFilter struct {
 	ToValue   interface{} `json:"toValue,omitempty"`
 }
 
+// EqualsFilter is an equality filter on the given attribute name
+type EqualsFilter struct {
+	Type  string      `json:"type,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// AndFilter is a logical filter which matches if all of the given filters match
+type AndFilter struct {
+	Type    string        `json:"type,omitempty"`
+	Filters []interface{} `json:"filters,omitempty"`
+}
+
 // Timestamp is time since unix epoch (UTC) in milliseconds (as defined by
 // Horizon spec)
 type Timestamp int64
@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCheckpointDir = "checkpoints"
+	format               = "cp-kubernetes-%s.json" // cp-kubernetes-<api_server_hostname>.json
+)
+
+// checkpoint represents a checkpoint object
+type checkpoint struct {
+	// checkpoint to cluster (API server) mapping
+	Cluster string `json:"cluster"`
+	// resource version of the last event successfully processed
+	ResourceVersion string `json:"resourceVersion"`
+	// UID of the last event successfully processed
+	LastEventUID string `json:"lastEventUID"`
+	// reason of the last event, e.g. Evicted useful for debugging
+	LastEventReason string `json:"lastEventReason"`
+	// timestamp (UTC) of the last event successfully processed
+	LastEventTimestamp time.Time `json:"lastEventTimestamp"`
+	// timestamp (UTC) when this checkpoint was created
+	CreatedTimestamp time.Time `json:"createdTimestamp"`
+}
+
+// readCheckpoint returns the checkpoint stored at the given path. If no
+// checkpoint exists an empty checkpoint is returned.
+func readCheckpoint(path string) (*checkpoint, error) {
+	var cp checkpoint
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &cp, nil
+		}
+		return nil, errors.Wrap(err, "could not read checkpoint file")
+	}
+
+	if err = json.Unmarshal(b, &cp); err != nil {
+		return nil, errors.Wrap(err, "could not validate last checkpoint")
+	}
+
+	return &cp, nil
+}
+
+// writeCheckpoint writes the given checkpoint to the given path, creating the
+// checkpoint directory if it does not exist
+func writeCheckpoint(path string, cp checkpoint) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "could not create checkpoint directory")
+	}
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal checkpoint to JSON")
+	}
+
+	if err = ioutil.WriteFile(path, b, 0600); err != nil {
+		return errors.Wrap(err, "could not write checkpoint file")
+	}
+
+	return nil
+}
+
+// checkpointPath returns the full path of the checkpoint file for the given
+// API server host and directory
+func checkpointPath(host, dir string) string {
+	return filepath.Join(filepath.Clean(dir), fmt.Sprintf(format, host))
+}
@@ -0,0 +1,507 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/embano1/waitgroup"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1beta1 "k8s.io/api/events/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider"
+)
+
+const (
+	// used as CloudEvent source if the API server address is unknown
+	defaultSource      = "https://kubernetes.default.svc"
+	checkpointInterval = 5 * time.Second
+	waitShutdown       = 5 * time.Second // wait for processing to finish before the final checkpoint
+)
+
+// assert we implement the provider interface
+var _ provider.Provider = (*EventStream)(nil)
+
+// EventStream watches Kubernetes events using a shared informer
+type EventStream struct {
+	client     kubernetes.Interface
+	restConfig *rest.Config
+	api        config.KubernetesEventAPI
+	namespaces map[string]bool // all namespaces if empty
+	reasons    map[string]bool // all reasons if empty
+	source     string
+	logger.Logger
+
+	checkpoint     bool
+	checkpointPath string
+
+	wg waitgroup.WaitGroup // events being processed
+
+	sync.RWMutex
+	begin     uint64      // events with a resource version up to begin are skipped
+	processed uint64      // highest resource version successfully processed
+	last      *checkpoint // highest processed event, nil if unchanged since the last checkpoint
+	stats     metrics.EventStats
+}
+
+// NewEventStream returns a Kubernetes event provider for the given
+// configuration. The in-cluster configuration is used if neither a kubeconfig
+// nor a custom REST configuration or client is provided.
+func NewEventStream(ctx context.Context, cfg *config.ProviderConfigKubernetes, ms metrics.Receiver, log logger.Logger, opts ...Option) (*EventStream, error) {
+	if cfg == nil {
+		return nil, errors.New("kubernetes configuration must be provided")
+	}
+
+	s := EventStream{
+		api:        cfg.API,
+		namespaces: make(map[string]bool),
+		reasons:    make(map[string]bool),
+		checkpoint: cfg.Checkpoint,
+		Logger:     log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		prov := strings.ToUpper(string(config.ProviderKubernetes))
+		s.Logger = zapSugared.Named(fmt.Sprintf("[%s]", prov))
+	}
+
+	switch s.api {
+	case "":
+		s.api = config.KubernetesEventAPICore
+	case config.KubernetesEventAPICore, config.KubernetesEventAPIEvents:
+	default:
+		return nil, errors.Errorf("invalid kubernetes config: unsupported event API %q", cfg.API)
+	}
+
+	for _, ns := range cfg.Namespaces {
+		s.namespaces[ns] = true
+	}
+
+	for _, r := range cfg.Reasons {
+		s.reasons[r] = true
+	}
+
+	for _, opt := range opts {
+		if err := opt(&s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.client == nil {
+		if err := s.newClient(cfg.Kubeconfig); err != nil {
+			return nil, err
+		}
+	}
+
+	s.source = defaultSource
+	if s.restConfig != nil && s.restConfig.Host != "" {
+		s.source = s.restConfig.Host
+	}
+
+	if s.checkpoint {
+		dir := cfg.CheckpointDir
+		if dir == "" {
+			dir = defaultCheckpointDir
+		}
+
+		host := "default"
+		if u, err := url.Parse(s.source); err == nil && u.Hostname() != "" {
+			host = u.Hostname()
+		}
+		s.checkpointPath = checkpointPath(host, dir)
+	}
+
+	s.stats = metrics.EventStats{
+		Provider:    string(config.ProviderKubernetes),
+		Type:        config.EventProvider,
+		Address:     s.source,
+		Started:     time.Now().UTC(),
+		EventsTotal: new(int),
+		EventsErr:   new(int),
+		EventsSec:   new(float64),
+	}
+
+	go s.PushMetrics(ctx, ms)
+
+	return &s, nil
+}
+
+// newClient creates the Kubernetes client from the custom REST configuration,
+// the given kubeconfig or the in-cluster configuration
+func (s *EventStream) newClient(kubeconfig string) error {
+	if s.restConfig == nil {
+		var (
+			kCfg *rest.Config
+			err  error
+		)
+
+		if kubeconfig != "" {
+			kCfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+		} else {
+			kCfg, err = rest.InClusterConfig()
+		}
+		if err != nil {
+			return errors.Wrap(err, "get Kubernetes configuration")
+		}
+		s.restConfig = kCfg
+	}
+
+	client, err := kubernetes.NewForConfig(s.restConfig)
+	if err != nil {
+		return errors.Wrap(err, "create Kubernetes client")
+	}
+	s.client = client
+
+	return nil
+}
+
+// Source returns the CloudEvent source, i.e. the Kubernetes API server address
+func (s *EventStream) Source() string {
+	return s.source
+}
+
+// PushMetrics pushes metrics to the configured metrics receiver
+func (s *EventStream) PushMetrics(ctx context.Context, ms metrics.Receiver) {
+	ticker := time.NewTicker(metrics.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Lock()
+			eventsSec := math.Round((float64(*s.stats.EventsTotal)/time.Since(s.stats.Started).Seconds())*100) / 100 // 0.2f syntax
+			s.stats.EventsSec = &eventsSec
+			ms.Receive(&s.stats)
+			s.Unlock()
+		}
+	}
+}
+
+// Stream watches Kubernetes events and invokes the specified processor for
+// every event matching the configured namespaces and reasons. Events which
+// occurred before the stream was started are skipped unless checkpointing is
+// enabled, in which case streaming resumes after the last processed resource
+// version.
+func (s *EventStream) Stream(ctx context.Context, proc processor.Processor) error {
+	if err := s.setBegin(ctx); err != nil {
+		return err
+	}
+
+	var factoryOpts []informers.SharedInformerOption
+	if len(s.namespaces) == 1 {
+		for ns := range s.namespaces {
+			factoryOpts = append(factoryOpts, informers.WithNamespace(ns))
+		}
+	}
+
+	// no resync, updated events (e.g. increased count) are delivered as updates
+	factory := informers.NewSharedInformerFactoryWithOptions(s.client, 0, factoryOpts...)
+
+	var informer cache.SharedIndexInformer
+	switch s.api {
+	case config.KubernetesEventAPIEvents:
+		informer = factory.Events().V1beta1().Events().Informer()
+	default:
+		informer = factory.Core().V1().Events().Informer()
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.handle(ctx, proc, obj)
+		},
+		UpdateFunc: func(oldObj, obj interface{}) {
+			// relists, e.g. after an expired watch, deliver unchanged cached
+			// events as updates
+			_, oldMeta, _ := eventInfo(oldObj)
+			info, meta, ok := eventInfo(obj)
+			if ok && meta.ResourceVersion == oldMeta.ResourceVersion {
+				s.Debugw("skipping unchanged event", "resourceVersion", meta.ResourceVersion, "reason", info.Reason)
+				return
+			}
+			s.handle(ctx, proc, obj)
+		},
+	})
+
+	s.Infow("starting event informer", "api", s.api, "namespaces", keys(s.namespaces), "reasons", keys(s.reasons), "checkpoint", s.checkpoint)
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		s.stop()
+		return ctx.Err()
+	}
+
+	var cpTick <-chan time.Time
+	if s.checkpoint {
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		cpTick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.stop()
+			return ctx.Err()
+		case <-cpTick:
+			if err := s.writeCheckpoint(); err != nil {
+				s.Errorw("could not create checkpoint", "error", err)
+			}
+		}
+	}
+}
+
+// stop waits for events being processed and creates a final checkpoint
+func (s *EventStream) stop() {
+	if err := s.wg.WaitTimeout(waitShutdown); err != nil {
+		s.Warnw("processing of events did not finish in time", "error", err)
+	}
+
+	if !s.checkpoint {
+		return
+	}
+
+	if err := s.writeCheckpoint(); err != nil {
+		s.Errorw("could not create checkpoint", "error", err)
+	}
+}
+
+// setBegin sets the resource version after which events are emitted, i.e.
+// the checkpointed resource version or the current resource version of the
+// event list
+func (s *EventStream) setBegin(ctx context.Context) error {
+	var rv string
+
+	if s.checkpoint {
+		cp, err := readCheckpoint(s.checkpointPath)
+		if err != nil {
+			return err
+		}
+
+		if cp.ResourceVersion != "" {
+			s.Infow("resuming from checkpoint", "path", s.checkpointPath, "resourceVersion", cp.ResourceVersion, "lastEventTimestamp", cp.LastEventTimestamp)
+			rv = cp.ResourceVersion
+		}
+	}
+
+	if rv == "" {
+		var err error
+		rv, err = s.listResourceVersion(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// resource versions are opaque, if not numeric all events are emitted
+	if v, err := strconv.ParseUint(rv, 10, 64); err == nil {
+		s.begin = v
+	}
+
+	s.Debugw("setting begin of event stream", "resourceVersion", rv)
+	return nil
+}
+
+// listResourceVersion returns the current resource version of the event list
+func (s *EventStream) listResourceVersion(ctx context.Context) (string, error) {
+	ns := metav1.NamespaceAll
+	if len(s.namespaces) == 1 {
+		for n := range s.namespaces {
+			ns = n
+		}
+	}
+
+	opts := metav1.ListOptions{Limit: 1}
+
+	var (
+		meta metav1.ListMeta
+		err  error
+	)
+
+	switch s.api {
+	case config.KubernetesEventAPIEvents:
+		var list *eventsv1beta1.EventList
+		if list, err = s.client.EventsV1beta1().Events(ns).List(ctx, opts); err == nil {
+			meta = list.ListMeta
+		}
+	default:
+		var list *corev1.EventList
+		if list, err = s.client.CoreV1().Events(ns).List(ctx, opts); err == nil {
+			meta = list.ListMeta
+		}
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, "list events")
+	}
+
+	return meta.ResourceVersion, nil
+}
+
+// handle converts the given event into a CloudEvent and invokes the processor
+// if the event matches the configured filters and has not been processed
+// before
+func (s *EventStream) handle(ctx context.Context, proc processor.Processor, obj interface{}) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	info, meta, ok := eventInfo(obj)
+	if !ok {
+		s.Warnw("ignoring unknown object", "object", obj)
+		return
+	}
+
+	if len(s.namespaces) > 0 && !s.namespaces[info.Namespace] {
+		return
+	}
+
+	if len(s.reasons) > 0 && !s.reasons[info.Reason] {
+		return
+	}
+
+	s.RLock()
+	begin := s.begin
+	s.RUnlock()
+
+	// resource versions are opaque, only numeric versions can be ordered
+	rv, rvErr := strconv.ParseUint(meta.ResourceVersion, 10, 64)
+	if rvErr == nil && rv <= begin {
+		s.Debugw("skipping event before begin of event stream", "resourceVersion", meta.ResourceVersion, "reason", info.Reason)
+		return
+	}
+
+	ce, err := events.NewFromKubernetes(info, obj, s.source)
+	if err != nil {
+		s.Errorw("skipping event because it could not be converted to CloudEvent format", "event", obj, "error", err)
+	} else {
+		err = proc.Process(ctx, *ce)
+		if err != nil {
+			s.Errorw("could not process event", "event", ce, "error", err)
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	*s.stats.EventsTotal++
+	if err != nil {
+		*s.stats.EventsErr++
+		return
+	}
+
+	// events are not delivered in resource version order, e.g. during the
+	// initial list, so the checkpoint never moves backwards
+	if rvErr != nil || rv <= s.processed {
+		return
+	}
+
+	s.processed = rv
+	s.last = &checkpoint{
+		ResourceVersion:    meta.ResourceVersion,
+		LastEventUID:       string(meta.UID),
+		LastEventReason:    info.Reason,
+		LastEventTimestamp: info.Time,
+	}
+}
+
+// writeCheckpoint persists the last processed event if it changed since the
+// last checkpoint
+func (s *EventStream) writeCheckpoint() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.last == nil {
+		return nil
+	}
+
+	cp := *s.last
+	cp.Cluster = s.source
+	cp.CreatedTimestamp = time.Now().UTC()
+
+	if err := writeCheckpoint(s.checkpointPath, cp); err != nil {
+		return err
+	}
+
+	s.Debugw("created checkpoint", "path", s.checkpointPath, "resourceVersion", cp.ResourceVersion)
+	s.last = nil
+	return nil
+}
+
+// eventInfo returns the CloudEvent details and object metadata of the given
+// core/v1 or events.k8s.io event
+func eventInfo(obj interface{}) (events.KubernetesEventInfo, metav1.ObjectMeta, bool) {
+	switch e := obj.(type) {
+	case *corev1.Event:
+		return events.KubernetesEventInfo{
+			Reason:     e.Reason,
+			Type:       e.Type,
+			Namespace:  e.Namespace,
+			ObjectKind: e.InvolvedObject.Kind,
+			ObjectName: e.InvolvedObject.Name,
+			Time:       firstSet(e.LastTimestamp.Time, e.EventTime.Time, e.FirstTimestamp.Time, e.CreationTimestamp.Time),
+		}, e.ObjectMeta, true
+
+	case *eventsv1beta1.Event:
+		var series time.Time
+		if e.Series != nil {
+			series = e.Series.LastObservedTime.Time
+		}
+
+		return events.KubernetesEventInfo{
+			Reason:     e.Reason,
+			Type:       e.Type,
+			Namespace:  e.Namespace,
+			ObjectKind: e.Regarding.Kind,
+			ObjectName: e.Regarding.Name,
+			Time:       firstSet(series, e.EventTime.Time, e.DeprecatedLastTimestamp.Time, e.CreationTimestamp.Time),
+		}, e.ObjectMeta, true
+	}
+
+	return events.KubernetesEventInfo{}, metav1.ObjectMeta{}, false
+}
+
+// firstSet returns the first non-zero time or the current time if all given
+// times are zero
+func firstSet(times ...time.Time) time.Time {
+	for _, t := range times {
+		if !t.IsZero() {
+			return t.UTC()
+		}
+	}
+	return time.Now().UTC()
+}
+
+// keys returns the keys of the given set
+func keys(set map[string]bool) []string {
+	var k []string
+	for key := range set {
+		k = append(k, key)
+	}
+	return k
+}
+
+// Shutdown is a no-op
+func (s *EventStream) Shutdown(context.Context) error {
+	return nil
+}
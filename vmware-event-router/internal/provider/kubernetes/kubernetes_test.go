@@ -0,0 +1,359 @@
+//go:build unit
+// +build unit
+
+package kubernetes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1beta1 "k8s.io/api/events/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/kubernetes"
+)
+
+// coreEvent returns a core/v1 event about a pod with the given resource
+// version
+func coreEvent(namespace, name, reason, rv string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       namespace,
+			Name:            name,
+			UID:             types.UID("uid-" + name),
+			ResourceVersion: rv,
+		},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: namespace, Name: "nginx"},
+		Reason:         reason,
+		Type:           corev1.EventTypeWarning,
+		Message:        "The node was low on resource: memory.",
+		LastTimestamp:  metav1.NewTime(time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)),
+	}
+}
+
+func Test_NewEventStream(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		name      string
+		cfg       *config.ProviderConfigKubernetes
+		errString string
+	}{
+		{"no config", nil, "kubernetes configuration must be provided"},
+		{"invalid api", &config.ProviderConfigKubernetes{API: "v2"}, `unsupported event API "v2"`},
+		{"invalid kubeconfig", &config.ProviderConfigKubernetes{Kubeconfig: filepath.Join(t.TempDir(), "missing")}, "get Kubernetes configuration"},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			_, err := kubernetes.NewEventStream(context.TODO(), test.cfg, metricsStub{}, logger.Sugar())
+			assert.ErrorContains(t, err, test.errString)
+		})
+	}
+}
+
+func Test_EventStream(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel))
+
+	t.Run("emits core events matching namespaces and reasons", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		client := fake.NewSimpleClientset()
+		cfg := config.ProviderConfigKubernetes{
+			Namespaces: []string{"default", "prod"},
+			Reasons:    []string{"Evicted", "FailedMount"},
+		}
+
+		s, err := kubernetes.NewEventStream(ctx, &cfg, metricsStub{}, logger.Sugar(), kubernetes.WithClientset(client))
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 10)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return s.Stream(ctx, proc)
+		})
+
+		for _, e := range []*corev1.Event{
+			coreEvent("kube-system", "e1", "Evicted", ""), // filtered namespace
+			coreEvent("default", "e2", "Scheduled", ""),   // filtered reason
+			coreEvent("default", "e3", "Evicted", ""),
+			coreEvent("prod", "e4", "FailedMount", ""),
+		} {
+			_, err = client.CoreV1().Events(e.Namespace).Create(ctx, e, metav1.CreateOptions{})
+			assert.NilError(t, err)
+		}
+
+		got := map[string]ce.Event{}
+		for i := 0; i < 2; i++ {
+			e := <-proc.events
+			got[e.Subject()] = e
+		}
+
+		evicted := got["Evicted"]
+		assert.Equal(t, evicted.Type(), "com.vmware.event.router/kubernetes")
+		assert.Equal(t, evicted.Source(), "https://kubernetes.default.svc")
+		assert.Equal(t, evicted.Extensions()["namespace"], "default")
+		assert.Equal(t, evicted.Extensions()["objectkind"], "Pod")
+		assert.Equal(t, evicted.Extensions()["objectname"], "nginx")
+		assert.Equal(t, evicted.Extensions()["eventtype"], "Warning")
+		assert.Equal(t, evicted.Time(), time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC))
+
+		var data corev1.Event
+		assert.NilError(t, json.Unmarshal(evicted.Data(), &data))
+		assert.Equal(t, data.Name, "e3")
+
+		assert.Equal(t, got["FailedMount"].Extensions()["namespace"], "prod")
+
+		select {
+		case e := <-proc.events:
+			t.Fatalf("unexpected event: %v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+
+	t.Run("emits events.k8s.io events", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		client := fake.NewSimpleClientset()
+		cfg := config.ProviderConfigKubernetes{API: config.KubernetesEventAPIEvents}
+
+		s, err := kubernetes.NewEventStream(ctx, &cfg, metricsStub{}, logger.Sugar(), kubernetes.WithClientset(client))
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 10)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return s.Stream(ctx, proc)
+		})
+
+		event := eventsv1beta1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-01.16a", Namespace: "default"},
+			EventTime:  metav1.NewMicroTime(time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)),
+			Reason:     "NodeNotReady",
+			Regarding:  corev1.ObjectReference{Kind: "Node", Name: "worker-01"},
+			Type:       corev1.EventTypeNormal,
+		}
+
+		_, err = client.EventsV1beta1().Events("default").Create(ctx, &event, metav1.CreateOptions{})
+		assert.NilError(t, err)
+
+		e := <-proc.events
+		assert.Equal(t, e.Subject(), "NodeNotReady")
+		assert.Equal(t, e.Extensions()["objectkind"], "Node")
+		assert.Equal(t, e.Extensions()["objectname"], "worker-01")
+		assert.Equal(t, e.Time(), time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC))
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+
+	t.Run("skips updates with unchanged resource version", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		client := fake.NewSimpleClientset()
+		s, err := kubernetes.NewEventStream(ctx, &config.ProviderConfigKubernetes{}, metricsStub{}, logger.Sugar(), kubernetes.WithClientset(client))
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 10)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return s.Stream(ctx, proc)
+		})
+
+		event := coreEvent("default", "e1", "Evicted", "10")
+		_, err = client.CoreV1().Events("default").Create(ctx, event, metav1.CreateOptions{})
+		assert.NilError(t, err)
+
+		e := <-proc.events
+		assert.Equal(t, e.Subject(), "Evicted")
+
+		// e.g. delivered again after an informer relist
+		_, err = client.CoreV1().Events("default").Update(ctx, event, metav1.UpdateOptions{})
+		assert.NilError(t, err)
+
+		select {
+		case e := <-proc.events:
+			t.Fatalf("unexpected event: %v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		updated := event.DeepCopy()
+		updated.ResourceVersion = "11"
+		updated.Count = 2
+		_, err = client.CoreV1().Events("default").Update(ctx, updated, metav1.UpdateOptions{})
+		assert.NilError(t, err)
+
+		e = <-proc.events
+		var data corev1.Event
+		assert.NilError(t, json.Unmarshal(e.Data(), &data))
+		assert.Equal(t, data.Count, int32(2))
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+
+	t.Run("resumes from checkpointed resource version", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := config.ProviderConfigKubernetes{
+			Checkpoint:    true,
+			CheckpointDir: dir,
+		}
+
+		stream := func(ctx context.Context, objects ...runtime.Object) (*fake.Clientset, *recordingProcessor, *errgroup.Group) {
+			client := fake.NewSimpleClientset(objects...)
+
+			s, err := kubernetes.NewEventStream(ctx, &cfg, metricsStub{}, logger.Sugar(), kubernetes.WithClientset(client))
+			assert.NilError(t, err)
+
+			proc := &recordingProcessor{events: make(chan ce.Event, 10)}
+
+			var eg errgroup.Group
+			eg.Go(func() error {
+				return s.Stream(ctx, proc)
+			})
+
+			return client, proc, &eg
+		}
+
+		// first run without checkpoint
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		client, proc, eg := stream(ctx)
+
+		_, err := client.CoreV1().Events("default").Create(ctx, coreEvent("default", "e1", "Evicted", "10"), metav1.CreateOptions{})
+		assert.NilError(t, err)
+
+		e := <-proc.events
+		assert.Equal(t, e.Subject(), "Evicted")
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "cp-kubernetes-kubernetes.default.svc.json"))
+		assert.NilError(t, err)
+
+		var cp struct {
+			ResourceVersion string `json:"resourceVersion"`
+			LastEventUID    string `json:"lastEventUID"`
+		}
+		assert.NilError(t, json.Unmarshal(b, &cp))
+		assert.Equal(t, cp.ResourceVersion, "10")
+		assert.Equal(t, cp.LastEventUID, "uid-e1")
+
+		// second run only emits events after the checkpoint
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, proc, eg = stream(ctx,
+			coreEvent("default", "e1", "Evicted", "10"),
+			coreEvent("default", "e2", "FailedMount", "11"),
+		)
+
+		e = <-proc.events
+		assert.Equal(t, e.Subject(), "FailedMount")
+
+		select {
+		case e := <-proc.events:
+			t.Fatalf("unexpected event: %v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+
+	t.Run("checkpoint only advances to higher successfully processed resource version", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		dir := t.TempDir()
+		cfg := config.ProviderConfigKubernetes{
+			Checkpoint:    true,
+			CheckpointDir: dir,
+		}
+
+		client := fake.NewSimpleClientset()
+		s, err := kubernetes.NewEventStream(ctx, &cfg, metricsStub{}, logger.Sugar(), kubernetes.WithClientset(client))
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 10), failReason: "FailedMount"}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return s.Stream(ctx, proc)
+		})
+
+		// events arrive out of resource version order and the latest event fails
+		for _, e := range []*corev1.Event{
+			coreEvent("default", "e1", "Evicted", "12"),
+			coreEvent("default", "e2", "Evicted", "11"),
+			coreEvent("default", "e3", "FailedMount", "13"),
+		} {
+			_, err = client.CoreV1().Events(e.Namespace).Create(ctx, e, metav1.CreateOptions{})
+			assert.NilError(t, err)
+			<-proc.events
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, "cp-kubernetes-kubernetes.default.svc.json"))
+		assert.NilError(t, err)
+
+		var cp struct {
+			ResourceVersion string `json:"resourceVersion"`
+			LastEventUID    string `json:"lastEventUID"`
+		}
+		assert.NilError(t, json.Unmarshal(b, &cp))
+		assert.Equal(t, cp.ResourceVersion, "12")
+		assert.Equal(t, cp.LastEventUID, "uid-e1")
+	})
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+type recordingProcessor struct {
+	events     chan ce.Event
+	failReason string // return an error for events with this reason
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.events <- e
+	if r.failReason != "" && e.Subject() == r.failReason {
+		return errors.New("processing failed")
+	}
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
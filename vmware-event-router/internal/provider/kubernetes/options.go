@@ -0,0 +1,33 @@
+package kubernetes
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Option configures the Kubernetes event provider
+type Option func(*EventStream) error
+
+// WithRestConfig provides a custom Kubernetes REST configuration, e.g. for
+// out-of-cluster configurations
+func WithRestConfig(cfg *rest.Config) Option {
+	return func(s *EventStream) error {
+		if cfg == nil {
+			return errors.New("no config provided")
+		}
+		s.restConfig = cfg
+		return nil
+	}
+}
+
+// WithClientset provides a custom Kubernetes client, e.g. for testing
+func WithClientset(client kubernetes.Interface) Option {
+	return func(s *EventStream) error {
+		if client == nil {
+			return errors.New("no client provided")
+		}
+		s.client = client
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package nats
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+)
+
+// decodeMessage returns the CloudEvent for the given message. Messages
+// containing a CloudEvent in JSON format (structured mode) are returned
+// unmodified, all other JSON messages are wrapped into a CloudEvent with the
+// given source.
+func decodeMessage(msg *nats.Msg, source string) (*ce.Event, error) {
+	data := bytes.TrimSpace(msg.Data)
+	if !json.Valid(data) {
+		return nil, errors.New("message is not valid JSON")
+	}
+
+	var attrs struct {
+		SpecVersion string `json:"specversion"`
+	}
+
+	// arrays and other values are wrapped as they are not a cloud event
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &attrs); err != nil {
+			return nil, errors.Wrap(err, "decode message")
+		}
+	}
+
+	if attrs.SpecVersion != "" {
+		var e ce.Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, errors.Wrap(err, "decode cloud event")
+		}
+
+		if err := e.Validate(); err != nil {
+			return nil, errors.Wrap(err, "invalid cloud event")
+		}
+
+		return &e, nil
+	}
+
+	info := events.NATSEventInfo{
+		Subject: msg.Subject,
+		Time:    messageTime(msg),
+	}
+
+	return events.NewFromNATS(info, json.RawMessage(data), source)
+}
+
+// messageTime returns the time a JetStream message was stored or the current
+// time for other messages
+func messageTime(msg *nats.Msg) time.Time {
+	if meta, err := msg.Metadata(); err == nil {
+		return meta.Timestamp.UTC()
+	}
+	return time.Now().UTC()
+}
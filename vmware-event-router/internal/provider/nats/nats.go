@@ -0,0 +1,299 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider"
+)
+
+const (
+	connectionName = "vmware-event-router"
+	// buffered messages per subscription before the server considers the
+	// subscriber slow
+	pendingMessages = 256
+)
+
+// assert we implement the provider interface
+var _ provider.Provider = (*Consumer)(nil)
+
+// Consumer is an event provider consuming messages from NATS subjects or a
+// durable JetStream consumer
+type Consumer struct {
+	conn       *nats.Conn
+	subjects   []string
+	queueGroup string
+	jetStream  *config.NATSJetStream
+	source     string
+	logger.Logger
+
+	sync.RWMutex
+	stats metrics.EventStats
+}
+
+// NewConsumer returns a NATS consumer connected to the configured server
+func NewConsumer(ctx context.Context, cfg *config.ProviderConfigNATS, ms metrics.Receiver, log logger.Logger) (*Consumer, error) {
+	if cfg == nil {
+		return nil, errors.New("nats configuration must be provided")
+	}
+
+	if cfg.Address == "" {
+		return nil, errors.New("invalid nats config: address must be specified")
+	}
+
+	if len(cfg.Subjects) == 0 {
+		return nil, errors.New("invalid nats config: at least one subject must be specified")
+	}
+
+	c := Consumer{
+		subjects:   cfg.Subjects,
+		queueGroup: cfg.QueueGroup,
+		jetStream:  cfg.JetStream,
+		source:     cfg.Source,
+		Logger:     log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		prov := strings.ToUpper(string(config.ProviderNATS))
+		c.Logger = zapSugared.Named(fmt.Sprintf("[%s]", prov))
+	}
+
+	if js := cfg.JetStream; js != nil {
+		if js.Durable == "" {
+			return nil, errors.New("invalid nats config: jetStream durable must be specified")
+		}
+
+		if len(cfg.Subjects) != 1 {
+			return nil, errors.Errorf("invalid nats config: jetStream requires exactly one subject: %v", cfg.Subjects)
+		}
+
+		if js.MaxDeliver < 0 {
+			return nil, errors.Errorf("invalid nats config: jetStream maxDeliver must not be negative: %d", js.MaxDeliver)
+		}
+	}
+
+	if c.source == "" {
+		c.source = strings.Split(cfg.Address, ",")[0]
+	}
+
+	opts := []nats.Option{
+		nats.Name(connectionName),
+		nats.MaxReconnects(-1), // reconnect until the router is stopped
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				c.Warnw("disconnected from NATS server", "error", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			c.Infow("reconnected to NATS server", "address", nc.ConnectedUrl())
+		}),
+	}
+
+	if cfg.Auth != nil {
+		switch cfg.Auth.Type {
+		case config.BasicAuth:
+			if cfg.Auth.BasicAuth == nil {
+				return nil, fmt.Errorf("invalid %s credentials: username and password must be set", config.BasicAuth)
+			}
+			opts = append(opts, nats.UserInfo(cfg.Auth.BasicAuth.Username, cfg.Auth.BasicAuth.Password))
+		default:
+			return nil, fmt.Errorf("invalid authentication type specified: %q", cfg.Auth.Type)
+		}
+	}
+
+	conn, err := nats.Connect(cfg.Address, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "connect to NATS server")
+	}
+	c.conn = conn
+
+	c.stats = metrics.EventStats{
+		Provider:    string(config.ProviderNATS),
+		Type:        config.EventProvider,
+		Address:     cfg.Address,
+		Started:     time.Now().UTC(),
+		EventsTotal: new(int),
+		EventsErr:   new(int),
+		EventsSec:   new(float64),
+	}
+
+	go c.PushMetrics(ctx, ms)
+
+	return &c, nil
+}
+
+// Address returns the URL of the connected NATS server
+func (c *Consumer) Address() string {
+	return c.conn.ConnectedUrl()
+}
+
+// PushMetrics pushes metrics to the configured metrics receiver
+func (c *Consumer) PushMetrics(ctx context.Context, ms metrics.Receiver) {
+	ticker := time.NewTicker(metrics.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Lock()
+			eventsSec := math.Round((float64(*c.stats.EventsTotal)/time.Since(c.stats.Started).Seconds())*100) / 100 // 0.2f syntax
+			c.stats.EventsSec = &eventsSec
+			ms.Receive(&c.stats)
+			c.Unlock()
+		}
+	}
+}
+
+// Stream subscribes to the configured subjects and invokes the specified
+// processor for every message. JetStream messages are acknowledged after the
+// processor succeeded and redelivered otherwise (at-least-once), messages
+// which cannot be converted into a CloudEvent are terminated.
+func (c *Consumer) Stream(ctx context.Context, proc processor.Processor) error {
+	msgCh := make(chan *nats.Msg, pendingMessages)
+
+	subs, err := c.subscribe(msgCh)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// JetStream subscriptions are not unsubscribed to retain the durable
+		// consumer, they are closed with the connection during shutdown
+		if c.jetStream != nil {
+			return
+		}
+
+		for _, sub := range subs {
+			if err := sub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+				c.Warnw("could not unsubscribe", "subject", sub.Subject, "error", err)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-msgCh:
+			c.handle(ctx, proc, msg)
+		}
+	}
+}
+
+// subscribe subscribes to the configured subjects delivering messages to the
+// given channel
+func (c *Consumer) subscribe(msgCh chan *nats.Msg) ([]*nats.Subscription, error) {
+	if js := c.jetStream; js != nil {
+		jsCtx, err := c.conn.JetStream()
+		if err != nil {
+			return nil, errors.Wrap(err, "get JetStream context")
+		}
+
+		opts := []nats.SubOpt{nats.Durable(js.Durable), nats.ManualAck(), nats.AckExplicit()}
+		if js.Stream != "" {
+			opts = append(opts, nats.BindStream(js.Stream))
+		}
+		if js.MaxDeliver > 0 {
+			opts = append(opts, nats.MaxDeliver(js.MaxDeliver))
+		}
+
+		var sub *nats.Subscription
+		if c.queueGroup != "" {
+			sub, err = jsCtx.ChanQueueSubscribe(c.subjects[0], c.queueGroup, msgCh, opts...)
+		} else {
+			sub, err = jsCtx.ChanSubscribe(c.subjects[0], msgCh, opts...)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "subscribe to JetStream subject %q", c.subjects[0])
+		}
+
+		c.Infow("subscribed to JetStream consumer", "subject", c.subjects[0], "stream", js.Stream, "durable", js.Durable, "queueGroup", c.queueGroup)
+		return []*nats.Subscription{sub}, nil
+	}
+
+	var subs []*nats.Subscription
+	for _, subject := range c.subjects {
+		var (
+			sub *nats.Subscription
+			err error
+		)
+
+		if c.queueGroup != "" {
+			sub, err = c.conn.ChanQueueSubscribe(subject, c.queueGroup, msgCh)
+		} else {
+			sub, err = c.conn.ChanSubscribe(subject, msgCh)
+		}
+		if err != nil {
+			for _, s := range subs {
+				_ = s.Unsubscribe()
+			}
+			return nil, errors.Wrapf(err, "subscribe to subject %q", subject)
+		}
+
+		subs = append(subs, sub)
+		c.Infow("subscribed to subject", "subject", subject, "queueGroup", c.queueGroup)
+	}
+
+	return subs, nil
+}
+
+// handle converts the given message into a CloudEvent, invokes the processor
+// and acknowledges JetStream messages
+func (c *Consumer) handle(ctx context.Context, proc processor.Processor, msg *nats.Msg) {
+	var ackErr error
+
+	e, err := decodeMessage(msg, c.source)
+	if err != nil {
+		c.Errorw("skipping message because it could not be converted to CloudEvent format", "subject", msg.Subject, "error", err)
+		if c.jetStream != nil {
+			ackErr = msg.Term()
+		}
+	} else {
+		err = proc.Process(ctx, *e)
+		if err != nil {
+			c.Errorw("could not process event", "eventID", e.ID(), "subject", msg.Subject, "error", err)
+		}
+
+		if c.jetStream != nil {
+			if err != nil {
+				ackErr = msg.Nak()
+			} else {
+				ackErr = msg.Ack()
+			}
+		}
+	}
+
+	if ackErr != nil {
+		c.Warnw("could not acknowledge message", "subject", msg.Subject, "error", ackErr)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	*c.stats.EventsTotal++
+	if err != nil {
+		*c.stats.EventsErr++
+	}
+}
+
+// Shutdown closes the connection to the NATS server. Unacknowledged JetStream
+// messages are redelivered after the acknowledgement wait time of the
+// consumer.
+func (c *Consumer) Shutdown(context.Context) error {
+	c.conn.Close()
+	return nil
+}
@@ -0,0 +1,271 @@
+//go:build unit
+// +build unit
+
+package nats_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	natsprovider "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/nats"
+)
+
+const cloudEvent = `{"specversion":"1.0","id":"42","source":"https://vcenter-01/sdk","type":"com.vmware.event.router/event","subject":"VmPoweredOnEvent","datacontenttype":"application/json","data":{"key":42}}`
+
+// runServer starts an embedded NATS server with JetStream enabled
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	assert.NilError(t, err)
+
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+
+	return s
+}
+
+func Test_NewConsumer(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	s := runServer(t)
+
+	tests := []struct {
+		name      string
+		cfg       *config.ProviderConfigNATS
+		errString string
+	}{
+		{"no config", nil, "nats configuration must be provided"},
+		{"no address", &config.ProviderConfigNATS{Subjects: []string{"vsphere"}}, "address must be specified"},
+		{"no subjects", &config.ProviderConfigNATS{Address: s.ClientURL()}, "at least one subject must be specified"},
+		{"no durable", &config.ProviderConfigNATS{Address: s.ClientURL(), Subjects: []string{"vsphere"}, JetStream: &config.NATSJetStream{}}, "jetStream durable must be specified"},
+		{"multiple jetstream subjects", &config.ProviderConfigNATS{Address: s.ClientURL(), Subjects: []string{"a", "b"}, JetStream: &config.NATSJetStream{Durable: "router"}}, "jetStream requires exactly one subject"},
+		{"invalid auth", &config.ProviderConfigNATS{Address: s.ClientURL(), Subjects: []string{"vsphere"}, Auth: &config.AuthMethod{Type: config.AWSAccessKeyAuth}}, `invalid authentication type specified: "aws_access_key"`},
+		{"server not reachable", &config.ProviderConfigNATS{Address: "nats://127.0.0.1:1", Subjects: []string{"vsphere"}}, "connect to NATS server"},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			_, err := natsprovider.NewConsumer(context.TODO(), test.cfg, metricsStub{}, logger.Sugar())
+			assert.ErrorContains(t, err, test.errString)
+		})
+	}
+}
+
+func Test_Consumer(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel))
+	s := runServer(t)
+
+	nc, err := nats.Connect(s.ClientURL())
+	assert.NilError(t, err)
+	defer nc.Close()
+
+	t.Run("converts cloud events and raw JSON messages", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		cfg := config.ProviderConfigNATS{
+			Address:  s.ClientURL(),
+			Subjects: []string{"vsphere.>", "tools.inventory"},
+			Source:   "nats://tools",
+		}
+
+		c, err := natsprovider.NewConsumer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		proc := newRecordingProcessor()
+		subs := s.NumSubscriptions()
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return c.Stream(ctx, proc)
+		})
+
+		// wait for subscriptions
+		assert.NilError(t, waitFor(ctx, func() bool { return s.NumSubscriptions() == subs+2 }))
+
+		assert.NilError(t, nc.Publish("vsphere.events", []byte(cloudEvent)))
+		assert.NilError(t, nc.Publish("vsphere.events", []byte("not json")))
+		assert.NilError(t, nc.Publish("tools.inventory", []byte(`{"vm":"vm-42","action":"snapshot"}`)))
+		assert.NilError(t, nc.Publish("other", []byte(`{}`)))
+
+		e := proc.next(t, ctx)
+		assert.Equal(t, e.ID(), "42")
+		assert.Equal(t, e.Source(), "https://vcenter-01/sdk")
+		assert.Equal(t, e.Subject(), "VmPoweredOnEvent")
+
+		e = proc.next(t, ctx)
+		assert.Equal(t, e.Type(), "com.vmware.event.router/nats")
+		assert.Equal(t, e.Source(), "nats://tools")
+		assert.Equal(t, e.Subject(), "tools.inventory")
+		assert.Equal(t, string(e.Data()), `{"vm":"vm-42","action":"snapshot"}`)
+
+		select {
+		case e := <-proc.events:
+			t.Fatalf("unexpected event: %v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+		assert.NilError(t, c.Shutdown(context.Background()))
+	})
+
+	t.Run("acknowledges JetStream messages after successful processing", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		js, err := nc.JetStream()
+		assert.NilError(t, err)
+
+		_, err = js.AddStream(&nats.StreamConfig{Name: "VSPHERE", Subjects: []string{"js.vsphere.>"}})
+		assert.NilError(t, err)
+
+		cfg := config.ProviderConfigNATS{
+			Address:  s.ClientURL(),
+			Subjects: []string{"js.vsphere.events"},
+			JetStream: &config.NATSJetStream{
+				Stream:  "VSPHERE",
+				Durable: "router",
+			},
+		}
+
+		c, err := natsprovider.NewConsumer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		// first delivery of the first event fails
+		proc := newRecordingProcessor()
+		proc.failures["42"] = 1
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return c.Stream(ctx, proc)
+		})
+
+		second := `{"specversion":"1.0","id":"43","source":"https://vcenter-01/sdk","type":"com.vmware.event.router/event","subject":"VmPoweredOffEvent"}`
+		for _, msg := range []string{cloudEvent, "not json", second} {
+			_, err = js.Publish("js.vsphere.events", []byte(msg))
+			assert.NilError(t, err)
+		}
+
+		got := map[string]bool{}
+		for len(got) < 2 {
+			e := proc.next(t, ctx)
+			got[e.ID()] = true
+		}
+		assert.Equal(t, proc.attempts("42"), 2, "failed event is redelivered")
+		assert.Equal(t, proc.attempts("43"), 1)
+
+		// all messages are acknowledged or terminated
+		assert.NilError(t, waitFor(ctx, func() bool {
+			info, err := js.ConsumerInfo("VSPHERE", "router")
+			return err == nil && info.NumAckPending == 0 && info.NumPending == 0 && info.NumRedelivered == 0
+		}))
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+		assert.NilError(t, c.Shutdown(context.Background()))
+
+		// durable consumer is retained
+		info, err := js.ConsumerInfo("VSPHERE", "router")
+		assert.NilError(t, err)
+		assert.Equal(t, info.Delivered.Stream, uint64(3))
+	})
+}
+
+// waitFor polls the given condition until it is true or the context is
+// cancelled
+func waitFor(ctx context.Context, cond func() bool) error {
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+// recordingProcessor records successfully processed events and fails the
+// configured number of attempts per event ID
+type recordingProcessor struct {
+	events chan ce.Event
+
+	mu       sync.Mutex
+	failures map[string]int
+	counts   map[string]int
+}
+
+func newRecordingProcessor() *recordingProcessor {
+	return &recordingProcessor{
+		events:   make(chan ce.Event, 10),
+		failures: make(map[string]int),
+		counts:   make(map[string]int),
+	}
+}
+
+// next returns the next processed event
+func (r *recordingProcessor) next(t *testing.T, ctx context.Context) ce.Event {
+	t.Helper()
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for event")
+	case e := <-r.events:
+		return e
+	}
+	return ce.Event{}
+}
+
+func (r *recordingProcessor) attempts(id string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[id]
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.mu.Lock()
+	r.counts[e.ID()]++
+	fail := r.counts[e.ID()] <= r.failures[e.ID()]
+	r.mu.Unlock()
+
+	if fail {
+		return errors.New("processing failed")
+	}
+
+	r.events <- e
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
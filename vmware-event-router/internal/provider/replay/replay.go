@@ -0,0 +1,405 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/record"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// assert we implement the provider interface
+var _ provider.Provider = (*Replayer)(nil)
+
+// Replayer is an event provider replaying recorded CloudEvents and vSphere
+// events from files
+type Replayer struct {
+	files  []string
+	timing config.ReplayTiming
+	speed  float64
+	source string
+	wait   func(ctx context.Context, d time.Duration) error
+	logger.Logger
+
+	sync.RWMutex
+	stats metrics.EventStats
+}
+
+// NewReplayer returns a replay event provider for the configured file or
+// directory
+func NewReplayer(ctx context.Context, cfg *config.ProviderConfigReplay, ms metrics.Receiver, log logger.Logger) (*Replayer, error) {
+	if cfg == nil {
+		return nil, errors.New("replay configuration must be provided")
+	}
+
+	if cfg.Path == "" {
+		return nil, errors.New("invalid replay config: path must be specified")
+	}
+
+	r := Replayer{
+		timing: cfg.Timing,
+		speed:  cfg.Speed,
+		source: cfg.Source,
+		wait:   wait,
+		Logger: log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		prov := strings.ToUpper(string(config.ProviderReplay))
+		r.Logger = zapSugared.Named(fmt.Sprintf("[%s]", prov))
+	}
+
+	switch cfg.Timing {
+	case "":
+		r.timing = config.ReplayTimingOriginal
+	case config.ReplayTimingOriginal, config.ReplayTimingFast:
+	default:
+		return nil, errors.Errorf("invalid replay config: unsupported timing %q", cfg.Timing)
+	}
+
+	switch {
+	case cfg.Speed == 0:
+		r.speed = 1
+	case cfg.Speed < 0 || math.IsInf(cfg.Speed, 0) || math.IsNaN(cfg.Speed):
+		return nil, errors.Errorf("invalid replay config: speed must be greater than 0: %v", cfg.Speed)
+	}
+
+	files, err := listFiles(cfg.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid replay config")
+	}
+	r.files = files
+
+	r.stats = metrics.EventStats{
+		Provider:    string(config.ProviderReplay),
+		Type:        config.EventProvider,
+		Address:     cfg.Path,
+		Started:     time.Now().UTC(),
+		EventsTotal: new(int),
+		EventsErr:   new(int),
+		EventsSec:   new(float64),
+	}
+
+	go r.PushMetrics(ctx, ms)
+
+	return &r, nil
+}
+
+// listFiles returns the given file or the regular, non-hidden files in the
+// given directory in lexical order
+func listFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.Mode().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(path, e.Name()))
+	}
+
+	if len(files) == 0 {
+		return nil, errors.Errorf("no files found in directory %q", path)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// Files returns the files to replay in replay order
+func (r *Replayer) Files() []string {
+	return r.files
+}
+
+// PushMetrics pushes metrics to the configured metrics receiver
+func (r *Replayer) PushMetrics(ctx context.Context, ms metrics.Receiver) {
+	ticker := time.NewTicker(metrics.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Lock()
+			eventsSec := math.Round((float64(*r.stats.EventsTotal)/time.Since(r.stats.Started).Seconds())*100) / 100 // 0.2f syntax
+			r.stats.EventsSec = &eventsSec
+			ms.Receive(&r.stats)
+			r.Unlock()
+		}
+	}
+}
+
+// Stream replays the events from all files once invoking the specified
+// processor for every event. Files which cannot be read are skipped. After all
+// events have been replayed Stream blocks until the given context is cancelled
+// so that in-flight invocations and metrics are not cut short.
+func (r *Replayer) Stream(ctx context.Context, proc processor.Processor) error {
+	r.Infow("starting replay", "files", len(r.files), "timing", r.timing, "speed", r.speed)
+
+	var last time.Time // time of the previously replayed event
+	for _, f := range r.files {
+		err := r.replayFile(ctx, proc, f, &last)
+		if ctx.Err() != nil {
+			r.Info("stopping replay")
+			return ctx.Err()
+		}
+
+		if err != nil {
+			r.Errorw("could not replay file", "file", f, "error", err)
+			r.countError()
+		}
+	}
+
+	r.RLock()
+	total, errs := *r.stats.EventsTotal, *r.stats.EventsErr
+	r.RUnlock()
+	r.Infow("replay completed", "events", total, "errors", errs)
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// replayFile replays all events in the given file. The file must contain a
+// JSON array or a sequence of JSON objects, e.g. one per line (JSONL), and can
+// be gzipped.
+func (r *Replayer) replayFile(ctx context.Context, proc processor.Processor, file string, last *time.Time) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return errors.Wrap(err, "open gzip file")
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	first, err := firstByte(br)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	src := r.source
+	if src == "" {
+		src = fileURI(file)
+	}
+
+	r.Debugw("replaying file", "file", file)
+	dec := json.NewDecoder(br)
+
+	if first == '[' {
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for idx := 0; ; idx++ {
+		if first == '[' && !dec.More() {
+			return nil
+		}
+
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			// syntax errors leave the decoder in an undefined state
+			return errors.Wrapf(err, "decode event %d", idx)
+		}
+
+		e, vsphere, err := decodeEvent(raw, src)
+		if err != nil {
+			r.Warnw("skipping invalid event", "file", file, "index", idx, "error", err)
+			r.countError()
+			continue
+		}
+
+		if err = r.waitFor(ctx, e.Time(), last); err != nil {
+			return err
+		}
+
+		r.Debugw("replaying event", "eventID", e.ID(), "type", e.Type(), "subject", e.Subject())
+
+		pctx := ctx
+		if vsphere != nil {
+			pctx = events.WithVSphereEvent(ctx, vsphere)
+		}
+
+		err = proc.Process(pctx, *e)
+		if err != nil {
+			r.Errorw("could not process event", "eventID", e.ID(), "error", err)
+		}
+
+		r.Lock()
+		*r.stats.EventsTotal++
+		if err != nil {
+			*r.stats.EventsErr++
+		}
+		r.Unlock()
+	}
+}
+
+// waitFor waits for the time between the previous and the given event time
+// divided by the replay speed and sets last to t. Events without time or
+// older than the previous event are replayed immediately.
+func (r *Replayer) waitFor(ctx context.Context, t time.Time, last *time.Time) error {
+	if t.IsZero() {
+		return nil
+	}
+
+	prev := *last
+	if t.After(prev) {
+		*last = t
+	}
+
+	if r.timing == config.ReplayTimingFast || prev.IsZero() || !t.After(prev) {
+		return nil
+	}
+
+	d := time.Duration(float64(t.Sub(prev)) / r.speed)
+	return r.wait(ctx, d)
+}
+
+// countError increments the error counter
+func (r *Replayer) countError() {
+	r.Lock()
+	defer r.Unlock()
+	*r.stats.EventsErr++
+}
+
+// Shutdown is a no-op
+func (r *Replayer) Shutdown(context.Context) error {
+	return nil
+}
+
+// decodeEvent returns a CloudEvent for the given JSON object. Objects with a
+// specversion attribute are decoded as CloudEvents. Objects with a _typeName
+// attribute (vSphere JSON API) are decoded as the named vSphere event type,
+// e.g. VmPoweredOnEvent, and converted into a CloudEvent with the given source.
+// Objects with a cloudEvent attribute are decoded as recorded events (see
+// record.Entry). The vSphere event is returned if the CloudEvent was created
+// from one.
+func decodeEvent(raw json.RawMessage, source string) (*ce.Event, types.BaseEvent, error) {
+	var probe struct {
+		SpecVersion string          `json:"specversion"`
+		TypeName    string          `json:"_typeName"`
+		CloudEvent  json.RawMessage `json:"cloudEvent"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case probe.SpecVersion != "":
+		var e ce.Event
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, nil, errors.Wrap(err, "decode cloud event")
+		}
+		if err := e.Validate(); err != nil {
+			return nil, nil, errors.Wrap(err, "invalid cloud event")
+		}
+		return &e, nil, nil
+
+	case probe.TypeName != "":
+		event, err := record.UnmarshalVSphereEvent(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		e, err := events.NewFromVSphere(event, source)
+		return e, event, err
+
+	case len(probe.CloudEvent) > 0:
+		var entry record.Entry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, nil, errors.Wrap(err, "decode recorded event")
+		}
+		return entry.Event()
+
+	default:
+		return nil, nil, errors.Errorf("neither a cloud event (specversion), a vSphere event (%s) nor a recorded event (cloudEvent)", record.TypeNameKey)
+	}
+}
+
+// firstByte returns the first non-whitespace byte without consuming it
+func firstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		return b, br.UnreadByte()
+	}
+}
+
+// fileURI returns the file URI for the given path, e.g. file:///data/events.jsonl
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "file://" + filepath.ToSlash(path)
+}
+
+// wait blocks for the given duration or until the context is cancelled
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+//go:build unit
+// +build unit
+
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+func Test_decodeEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantType    string
+		wantSubject string
+		wantSource  string
+		wantVSphere bool
+		errString   string
+	}{
+		{
+			name:        "cloud event",
+			raw:         `{"specversion":"1.0","id":"1","source":"https://vcenter-01/sdk","type":"com.vmware.event.router/event","subject":"VmPoweredOnEvent"}`,
+			wantType:    "com.vmware.event.router/event",
+			wantSubject: "VmPoweredOnEvent",
+			wantSource:  "https://vcenter-01/sdk",
+		},
+		{
+			name:        "vSphere event",
+			raw:         `{"_typeName":"VmPoweredOnEvent","Key":1,"CreatedTime":"2021-09-01T12:00:00Z"}`,
+			wantType:    "com.vmware.event.router/event",
+			wantSubject: "VmPoweredOnEvent",
+			wantSource:  "file:///replay/events.jsonl",
+			wantVSphere: true,
+		},
+		{
+			name:        "vSphere extended event",
+			raw:         `{"_typeName":"ExtendedEvent","eventTypeId":"com.vmware.applmgmt.backup.job.failed.event"}`,
+			wantType:    "com.vmware.event.router/extendedevent",
+			wantSubject: "com.vmware.applmgmt.backup.job.failed.event",
+			wantSource:  "file:///replay/events.jsonl",
+			wantVSphere: true,
+		},
+		{
+			name:        "recorded vSphere event",
+			raw:         `{"recorded":"2021-09-01T12:00:01Z","cloudEvent":{"specversion":"1.0","id":"1","source":"https://vcenter-01/sdk","type":"com.vmware.event.router/event","subject":"VmPoweredOnEvent"},"vsphereEvent":{"_typeName":"VmPoweredOnEvent","Key":1}}`,
+			wantType:    "com.vmware.event.router/event",
+			wantSubject: "VmPoweredOnEvent",
+			wantSource:  "https://vcenter-01/sdk",
+			wantVSphere: true,
+		},
+		{
+			name:        "recorded cloud event",
+			raw:         `{"recorded":"2021-09-01T12:00:01Z","cloudEvent":{"specversion":"1.0","id":"1","source":"syslog://10.0.0.1","type":"com.vmware.event.router/syslog","subject":"Hostd"}}`,
+			wantType:    "com.vmware.event.router/syslog",
+			wantSubject: "Hostd",
+			wantSource:  "syslog://10.0.0.1",
+		},
+		{
+			name:      "invalid cloud event",
+			raw:       `{"specversion":"1.0","id":"1"}`,
+			errString: "invalid cloud event",
+		},
+		{
+			name:      "unknown vSphere type",
+			raw:       `{"_typeName":"NoSuchEvent"}`,
+			errString: `unknown vSphere type "NoSuchEvent"`,
+		},
+		{
+			name:      "vSphere type is not an event",
+			raw:       `{"_typeName":"ManagedObjectReference"}`,
+			errString: `vSphere type "ManagedObjectReference" is not an event`,
+		},
+		{
+			name:      "unknown object",
+			raw:       `{"type":"VmPoweredOnEvent"}`,
+			errString: "neither a cloud event",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, vsphere, err := decodeEvent(json.RawMessage(tt.raw), "file:///replay/events.jsonl")
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, e.Type(), tt.wantType)
+			assert.Equal(t, e.Subject(), tt.wantSubject)
+			assert.Equal(t, e.Source(), tt.wantSource)
+			assert.Equal(t, vsphere != nil, tt.wantVSphere)
+		})
+	}
+}
+
+func Test_waitFor(t *testing.T) {
+	start := time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		start,
+		start.Add(10 * time.Second),
+		{},                         // no time
+		start.Add(5 * time.Second), // out of order
+		start.Add(30 * time.Second),
+	}
+
+	tests := []struct {
+		name   string
+		timing config.ReplayTiming
+		speed  float64
+		want   []time.Duration
+	}{
+		{
+			name:   "original timing",
+			timing: config.ReplayTimingOriginal,
+			speed:  1,
+			want:   []time.Duration{10 * time.Second, 20 * time.Second},
+		},
+		{
+			name:   "original timing with speed multiplier",
+			timing: config.ReplayTimingOriginal,
+			speed:  4,
+			want:   []time.Duration{2500 * time.Millisecond, 5 * time.Second},
+		},
+		{
+			name:   "fast",
+			timing: config.ReplayTimingFast,
+			speed:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []time.Duration
+			r := Replayer{
+				timing: tt.timing,
+				speed:  tt.speed,
+				wait: func(_ context.Context, d time.Duration) error {
+					got = append(got, d)
+					return nil
+				},
+			}
+
+			var last time.Time
+			for _, ts := range times {
+				assert.NilError(t, r.waitFor(context.TODO(), ts, &last))
+			}
+
+			assert.DeepEqual(t, got, tt.want)
+			assert.Equal(t, last, times[4])
+		})
+	}
+}
@@ -0,0 +1,190 @@
+//go:build unit
+// +build unit
+
+package replay_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/replay"
+)
+
+const (
+	cloudEvents = `{"specversion":"1.0","id":"1","source":"https://vcenter-01/sdk","type":"com.vmware.event.router/event","subject":"VmPoweredOnEvent","time":"2021-09-01T12:00:00Z","datacontenttype":"application/json","data":{"Key":1}}
+{"specversion":"1.0","id":"2","source":"https://vcenter-01/sdk","type":"com.vmware.event.router/event","subject":"VmPoweredOffEvent","time":"2021-09-01T12:00:01Z","datacontenttype":"application/json","data":{"Key":2}}
+{"not":"an event"}
+`
+	vSphereEvents = `[
+  {"_typeName":"VmPoweredOnEvent","key":3,"createdTime":"2021-09-01T12:00:02Z","fullFormattedMessage":"Test VM on esx-01 is powered on","vm":{"name":"Test VM"}},
+  {"_typeName":"EventEx","key":4,"createdTime":"2021-09-01T12:00:03Z","eventTypeId":"com.vmware.vc.HA.DasHostFailedEvent"}
+]`
+)
+
+func writeFile(t *testing.T, dir, name, content string, gzipped bool) {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(dir, name))
+	assert.NilError(t, err)
+	defer f.Close()
+
+	if !gzipped {
+		_, err = f.WriteString(content)
+		assert.NilError(t, err)
+		return
+	}
+
+	zw := gzip.NewWriter(f)
+	_, err = zw.Write([]byte(content))
+	assert.NilError(t, err)
+	assert.NilError(t, zw.Close())
+}
+
+func Test_Replayer(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel))
+
+	t.Run("fails to start with invalid replay config", func(t *testing.T) {
+		empty := t.TempDir()
+
+		tests := []struct {
+			name      string
+			cfg       *config.ProviderConfigReplay
+			errString string
+		}{
+			{"no config", nil, "replay configuration must be provided"},
+			{"no path", &config.ProviderConfigReplay{}, "path must be specified"},
+			{"missing path", &config.ProviderConfigReplay{Path: "/does/not/exist.jsonl"}, "invalid replay config: stat"},
+			{"empty directory", &config.ProviderConfigReplay{Path: empty}, "no files found in directory"},
+			{"unsupported timing", &config.ProviderConfigReplay{Path: empty, Timing: "realtime"}, `unsupported timing "realtime"`},
+			{"negative speed", &config.ProviderConfigReplay{Path: empty, Speed: -1}, "speed must be greater than 0"},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				_, err := replay.NewReplayer(context.TODO(), test.cfg, metricsStub{}, logger.Sugar())
+				assert.ErrorContains(t, err, test.errString)
+			})
+		}
+	})
+
+	t.Run("replays events from a directory", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		dir := t.TempDir()
+		writeFile(t, dir, "01-cloudevents.jsonl", cloudEvents, false)
+		writeFile(t, dir, "02-vsphere.json.gz", vSphereEvents, true)
+		writeFile(t, dir, ".hidden", "ignored", false)
+
+		cfg := config.ProviderConfigReplay{
+			Path:   dir,
+			Timing: config.ReplayTimingFast,
+			Source: "https://vcenter-01/sdk",
+		}
+
+		r, err := replay.NewReplayer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+		assert.DeepEqual(t, r.Files(), []string{
+			filepath.Join(dir, "01-cloudevents.jsonl"),
+			filepath.Join(dir, "02-vsphere.json.gz"),
+		})
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 4)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return r.Stream(ctx, proc)
+		})
+
+		want := []struct {
+			id      string
+			typ     string
+			subject string
+		}{
+			{"1", "com.vmware.event.router/event", "VmPoweredOnEvent"},
+			{"2", "com.vmware.event.router/event", "VmPoweredOffEvent"},
+			{"", "com.vmware.event.router/event", "VmPoweredOnEvent"},
+			{"", "com.vmware.event.router/eventex", "com.vmware.vc.HA.DasHostFailedEvent"},
+		}
+
+		for _, w := range want {
+			e := <-proc.events
+			if w.id != "" {
+				assert.Equal(t, e.ID(), w.id)
+			}
+			assert.Equal(t, e.Type(), w.typ)
+			assert.Equal(t, e.Subject(), w.subject)
+			assert.Equal(t, e.Source(), "https://vcenter-01/sdk")
+		}
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+
+	t.Run("replays a single file with original timing", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		file := filepath.Join(t.TempDir(), "events.jsonl")
+		assert.NilError(t, ioutil.WriteFile(file, []byte(cloudEvents), 0600))
+
+		cfg := config.ProviderConfigReplay{
+			Path:  file,
+			Speed: 10,
+		}
+
+		r, err := replay.NewReplayer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 2)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return r.Stream(ctx, proc)
+		})
+
+		first := <-proc.events
+		start := time.Now()
+		second := <-proc.events
+
+		assert.Equal(t, first.ID(), "1")
+		assert.Equal(t, second.ID(), "2")
+		assert.Assert(t, time.Since(start) >= 50*time.Millisecond, "events one second apart must be replayed 100ms apart")
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+type recordingProcessor struct {
+	events chan ce.Event
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.events <- e
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package snmp
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pkg/errors"
+)
+
+// defaultMIBMappings contains the SNMPv2-MIB objects present in every trap and
+// the generic traps
+var defaultMIBMappings = map[string]string{
+	".1.3.6.1.2.1.1.3":     "sysUpTime",
+	".1.3.6.1.6.3.1.1.4.1": "snmpTrapOID",
+	".1.3.6.1.6.3.1.1.4.3": "snmpTrapEnterprise",
+	".1.3.6.1.6.3.18.1.3":  "snmpTrapAddress",
+	".1.3.6.1.6.3.18.1.4":  "snmpTrapCommunity",
+	".1.3.6.1.6.3.1.1.5.1": "coldStart",
+	".1.3.6.1.6.3.1.1.5.2": "warmStart",
+	".1.3.6.1.6.3.1.1.5.3": "linkDown",
+	".1.3.6.1.6.3.1.1.5.4": "linkUp",
+	".1.3.6.1.6.3.1.1.5.5": "authenticationFailure",
+	".1.3.6.1.2.1.2.2.1.1": "ifIndex",
+	".1.3.6.1.2.1.2.2.1.7": "ifAdminStatus",
+	".1.3.6.1.2.1.2.2.1.8": "ifOperStatus",
+	".1.3.6.1.4.1.6876":    "vmware",
+}
+
+// mibResolver resolves numeric OIDs to names
+type mibResolver struct {
+	names map[string]string // numeric OID with leading dot to name
+}
+
+// newMIBResolver returns a resolver using the default mappings and the
+// mappings loaded from the given files. Mappings in files take precedence over
+// the defaults.
+func newMIBResolver(files []string) (*mibResolver, error) {
+	r := mibResolver{names: make(map[string]string, len(defaultMIBMappings))}
+	for oid, name := range defaultMIBMappings {
+		r.names[oid] = name
+	}
+
+	for _, f := range files {
+		mappings, err := loadMIBMappings(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load MIB mappings %q", f)
+		}
+
+		for oid, name := range mappings {
+			if !isNumericOID(oid) || name == "" {
+				return nil, errors.Errorf("load MIB mappings %q: invalid mapping %q: %q", f, oid, name)
+			}
+			r.names[normalizeOID(oid)] = name
+		}
+	}
+
+	return &r, nil
+}
+
+// loadMIBMappings reads OID to name mappings from the given file. Files with a
+// .yaml, .yml or .json extension must contain a map of OIDs to names. All
+// other files are read in the "snmptranslate -Tz" format, i.e. one quoted name
+// and OID per line.
+func loadMIBMappings(file string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		var mappings map[string]string
+		if err = yaml.Unmarshal(b, &mappings); err != nil {
+			return nil, err
+		}
+		return mappings, nil
+	default:
+		return parseTzMappings(b)
+	}
+}
+
+// parseTzMappings parses the output of "snmptranslate -Tz", e.g.
+// "linkDown"	"1.3.6.1.6.3.1.1.5.3"
+func parseTzMappings(b []byte) (map[string]string, error) {
+	mappings := make(map[string]string)
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("line %d: expected quoted name and OID", line)
+		}
+
+		name, oid := strings.Trim(fields[0], `"`), strings.Trim(fields[1], `"`)
+		if name == "" || !isNumericOID(oid) {
+			return nil, errors.Errorf("line %d: invalid mapping %q", line, text)
+		}
+		mappings[oid] = name
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// resolve returns the name of the given OID using the longest matching
+// mapping. Remaining sub-identifiers (the instance) are appended to the name,
+// e.g. sysUpTime.0. An empty string is returned if no mapping matches.
+func (r *mibResolver) resolve(oid string) string {
+	oid = normalizeOID(oid)
+
+	for prefix := oid; prefix != ""; {
+		if name, ok := r.names[prefix]; ok {
+			return name + strings.TrimPrefix(oid, prefix)
+		}
+
+		i := strings.LastIndexByte(prefix, '.')
+		if i <= 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+
+	return ""
+}
+
+// normalizeOID returns the given numeric OID with a leading dot
+func normalizeOID(oid string) string {
+	if oid == "" || strings.HasPrefix(oid, ".") {
+		return oid
+	}
+	return "." + oid
+}
+
+// isNumericOID returns true if the given string is a numeric OID, e.g.
+// 1.3.6.1 or .1.3.6.1
+func isNumericOID(oid string) bool {
+	oid = strings.TrimPrefix(oid, ".")
+	if oid == "" {
+		return false
+	}
+
+	for _, part := range strings.Split(oid, ".") {
+		if part == "" {
+			return false
+		}
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+//go:build unit
+// +build unit
+
+package snmp
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"gotest.tools/assert"
+)
+
+func writeMappings(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, ioutil.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func Test_newMIBResolver(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		oid       string
+		want      string
+		errString string
+	}{
+		{
+			name:    "yaml mappings",
+			file:    "vmware.yaml",
+			content: "\".1.3.6.1.4.1.6876.4.1.0.201\": vmwVmPoweredOn\n",
+			oid:     ".1.3.6.1.4.1.6876.4.1.0.201",
+			want:    "vmwVmPoweredOn",
+		},
+		{
+			name:    "json mappings without leading dot",
+			file:    "ilo.json",
+			content: `{"1.3.6.1.4.1.232.0.6048": "cpqHe3FltTolPowerSupplyFailed"}`,
+			oid:     ".1.3.6.1.4.1.232.0.6048",
+			want:    "cpqHe3FltTolPowerSupplyFailed",
+		},
+		{
+			name:    "snmptranslate mappings override defaults",
+			file:    "mibs.txt",
+			content: "# generated\n\"ifDown\"\t\t\"1.3.6.1.6.3.1.1.5.3\"\n\n\"sysName\"\t\t\"1.3.6.1.2.1.1.5\"\n",
+			oid:     ".1.3.6.1.6.3.1.1.5.3",
+			want:    "ifDown",
+		},
+		{
+			name:      "invalid snmptranslate line",
+			file:      "mibs.txt",
+			content:   "\"sysName\" \"1.3.6.1.2.1.1.5\" extra\n",
+			errString: "line 1: expected quoted name and OID",
+		},
+		{
+			name:      "invalid OID in yaml",
+			file:      "mibs.yaml",
+			content:   "sysName: \"1.3.6.1.2.1.1.5\"\n",
+			errString: "invalid mapping",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newMIBResolver([]string{writeMappings(t, tt.file, tt.content)})
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, r.resolve(tt.oid), tt.want)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := newMIBResolver([]string{"/does/not/exist.yaml"})
+		assert.ErrorContains(t, err, "load MIB mappings")
+	})
+}
+
+func Test_mibResolver_resolve(t *testing.T) {
+	r, err := newMIBResolver(nil)
+	assert.NilError(t, err)
+
+	tests := []struct {
+		oid  string
+		want string
+	}{
+		{oid: ".1.3.6.1.6.3.1.1.5.3", want: "linkDown"},
+		{oid: "1.3.6.1.6.3.1.1.5.3", want: "linkDown"},
+		{oid: ".1.3.6.1.2.1.1.3.0", want: "sysUpTime.0"},
+		{oid: ".1.3.6.1.2.1.2.2.1.8.12", want: "ifOperStatus.12"},
+		{oid: ".1.3.6.1.4.1.6876.4.1.0.201", want: "vmware.4.1.0.201"},
+		{oid: ".1.3.6.1.2.1.1.30", want: ""},
+		{oid: ".1.3.6.1.4.1.232", want: ""},
+		{oid: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.oid, func(t *testing.T) {
+			assert.Equal(t, r.resolve(tt.oid), tt.want)
+		})
+	}
+}
+
+func Test_peekVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		packet    []byte
+		want      gosnmp.SnmpVersion
+		errString string
+	}{
+		{name: "v2c short length", packet: []byte{0x30, 0x29, 0x02, 0x01, 0x01, 0x04}, want: gosnmp.Version2c},
+		{name: "v3 long length", packet: []byte{0x30, 0x81, 0x90, 0x02, 0x01, 0x03, 0x30}, want: gosnmp.Version3},
+		{name: "not a sequence", packet: []byte{0x02, 0x01, 0x01}, errString: "invalid SNMP packet header"},
+		{name: "missing version", packet: []byte{0x30, 0x03, 0x04, 0x01, 0x01}, errString: "invalid SNMP version"},
+		{name: "truncated", packet: []byte{0x30}, errString: "invalid SNMP packet header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := peekVersion(tt.packet)
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func Test_octetString(t *testing.T) {
+	assert.Equal(t, octetString([]byte("Power supply 1 failed")), "Power supply 1 failed")
+	assert.Equal(t, octetString([]byte{0x00, 0x50, 0x56, 0xab, 0xcd, 0xef}), "005056abcdef")
+	assert.Equal(t, octetString([]byte{0xff, 0xfe}), "fffe")
+}
@@ -0,0 +1,13 @@
+package snmp
+
+import "time"
+
+// Option allows for customization of the snmp event provider
+type Option func(s *Server)
+
+// WithClock sets the function returning the current time used as event time
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
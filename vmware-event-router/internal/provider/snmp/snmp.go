@@ -0,0 +1,598 @@
+package snmp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/util"
+)
+
+const (
+	// maximum size of a trap datagram
+	maxPacketSize = 64 * 1024
+
+	// minimum passphrase length (RFC 3414)
+	minPassphraseLength = 8
+
+	sourceScheme = "snmp://"
+
+	sysUpTimeOID       = ".1.3.6.1.2.1.1.3.0"
+	snmpTrapOID        = ".1.3.6.1.6.3.1.1.4.1.0"
+	snmpTrapAddressOID = ".1.3.6.1.6.3.18.1.3.0"
+)
+
+var (
+	// ErrUnauthorized is returned for traps with an unknown community or user
+	ErrUnauthorized = errors.New("unauthorized trap")
+
+	authProtocols = map[config.SNMPAuthProtocol]gosnmp.SnmpV3AuthProtocol{
+		"":                    gosnmp.NoAuth,
+		config.SNMPAuthNone:   gosnmp.NoAuth,
+		config.SNMPAuthMD5:    gosnmp.MD5,
+		config.SNMPAuthSHA:    gosnmp.SHA,
+		config.SNMPAuthSHA224: gosnmp.SHA224,
+		config.SNMPAuthSHA256: gosnmp.SHA256,
+		config.SNMPAuthSHA384: gosnmp.SHA384,
+		config.SNMPAuthSHA512: gosnmp.SHA512,
+	}
+
+	privProtocols = map[config.SNMPPrivProtocol]gosnmp.SnmpV3PrivProtocol{
+		"":                     gosnmp.NoPriv,
+		config.SNMPPrivNone:    gosnmp.NoPriv,
+		config.SNMPPrivDES:     gosnmp.DES,
+		config.SNMPPrivAES:     gosnmp.AES,
+		config.SNMPPrivAES192:  gosnmp.AES192,
+		config.SNMPPrivAES256:  gosnmp.AES256,
+		config.SNMPPrivAES192C: gosnmp.AES192C,
+		config.SNMPPrivAES256C: gosnmp.AES256C,
+	}
+)
+
+// Trap is a received SNMP trap or inform used as CloudEvent data
+type Trap struct {
+	// Version is the SNMP version, i.e. 2c or 3
+	Version string `json:"version"`
+	// PDUType is the PDU type, i.e. trap or inform
+	PDUType string `json:"pduType"`
+	// Username is the SNMPv3 user which sent the trap
+	Username string `json:"username,omitempty"`
+	// AgentAddress is the address of the agent which sent the trap
+	AgentAddress string `json:"agentAddress"`
+	// TrapOID is the numeric trap OID
+	TrapOID string `json:"trapOID"`
+	// TrapName is the trap OID name resolved through MIB mappings
+	TrapName string `json:"trapName,omitempty"`
+	// Uptime is the agent uptime in hundredths of a second
+	Uptime uint32 `json:"uptime"`
+	// Variables are the variable bindings of the trap
+	Variables []Variable `json:"variables"`
+}
+
+// Variable is a variable binding of a trap
+type Variable struct {
+	// OID is the numeric OID of the variable
+	OID string `json:"oid"`
+	// Name is the OID name resolved through MIB mappings
+	Name string `json:"name,omitempty"`
+	// Type is the ASN.1 type of the value, e.g. OctetString
+	Type string `json:"type"`
+	// Value is the variable value. Octet strings which are not printable are
+	// hex-encoded.
+	Value interface{} `json:"value"`
+	// ValueName is the resolved name of an ObjectIdentifier value
+	ValueName string `json:"valueName,omitempty"`
+}
+
+// user is an SNMPv3 user accepted by the trap receiver
+type user struct {
+	name     string
+	engineID string
+	level    gosnmp.SnmpV3MsgFlags
+	params   *gosnmp.GoSNMP
+}
+
+// Server is an SNMP trap receiver event provider
+type Server struct {
+	conn        net.PacketConn
+	v2c         *gosnmp.GoSNMP // nil if SNMPv2c is disabled
+	communities map[string]struct{}
+	users       []user
+	mib         *mibResolver
+	now         func() time.Time
+	logger.Logger
+
+	sync.RWMutex
+	stats metrics.EventStats
+}
+
+// NewServer returns an SNMP trap receiver listening on the configured UDP
+// address
+func NewServer(ctx context.Context, cfg *config.ProviderConfigSNMP, ms metrics.Receiver, log logger.Logger, opts ...Option) (*Server, error) {
+	if cfg == nil {
+		return nil, errors.New("snmp configuration must be provided")
+	}
+
+	if err := util.ValidateAddress(cfg.BindAddress); err != nil {
+		return nil, errors.Wrap(err, "invalid snmp config")
+	}
+
+	if len(cfg.Communities) == 0 && len(cfg.Users) == 0 {
+		return nil, errors.New("invalid snmp config: at least one community or user must be specified")
+	}
+
+	srv := Server{
+		communities: make(map[string]struct{}),
+		now:         time.Now,
+		Logger:      log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		prov := strings.ToUpper(string(config.ProviderSNMP))
+		srv.Logger = zapSugared.Named(fmt.Sprintf("[%s]", prov))
+	}
+
+	snmpLog := gosnmp.NewLogger(debugLogger{srv.Logger})
+
+	for _, c := range cfg.Communities {
+		if c == "" {
+			return nil, errors.New("invalid snmp config: community must not be empty")
+		}
+		srv.communities[c] = struct{}{}
+	}
+
+	if len(srv.communities) > 0 {
+		srv.v2c = &gosnmp.GoSNMP{Version: gosnmp.Version2c, Logger: snmpLog}
+	}
+
+	for _, u := range cfg.Users {
+		usr, err := newUser(u, snmpLog)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid snmp config")
+		}
+		srv.users = append(srv.users, usr)
+	}
+
+	mib, err := newMIBResolver(cfg.MIBMappings)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid snmp config")
+	}
+	srv.mib = mib
+
+	conn, err := net.ListenPacket("udp", cfg.BindAddress)
+	if err != nil {
+		return nil, errors.Wrap(err, "start listener")
+	}
+	srv.conn = conn
+
+	srv.stats = metrics.EventStats{
+		Provider:    string(config.ProviderSNMP),
+		Type:        config.EventProvider,
+		Address:     cfg.BindAddress,
+		Started:     time.Now().UTC(),
+		EventsTotal: new(int),
+		EventsErr:   new(int),
+		EventsSec:   new(float64),
+	}
+
+	// apply options (use defaults otherwise)
+	for _, opt := range opts {
+		opt(&srv)
+	}
+
+	go srv.PushMetrics(ctx, ms)
+
+	return &srv, nil
+}
+
+// newUser validates the given SNMPv3 user configuration and returns the user
+// with its security parameters
+func newUser(cfg config.SNMPUser, log gosnmp.Logger) (user, error) {
+	if cfg.Username == "" {
+		return user{}, errors.New("username must be specified")
+	}
+
+	engineID, err := hex.DecodeString(strings.TrimPrefix(cfg.EngineID, "0x"))
+	if err != nil || len(engineID) == 0 {
+		return user{}, errors.Errorf("user %q: invalid engine ID %q", cfg.Username, cfg.EngineID)
+	}
+
+	auth, ok := authProtocols[cfg.AuthProtocol]
+	if !ok {
+		return user{}, errors.Errorf("user %q: unsupported authentication protocol %q", cfg.Username, cfg.AuthProtocol)
+	}
+
+	priv, ok := privProtocols[cfg.PrivProtocol]
+	if !ok {
+		return user{}, errors.Errorf("user %q: unsupported privacy protocol %q", cfg.Username, cfg.PrivProtocol)
+	}
+
+	level := gosnmp.NoAuthNoPriv
+	if auth != gosnmp.NoAuth {
+		if len(cfg.AuthPassphrase) < minPassphraseLength {
+			return user{}, errors.Errorf("user %q: authentication passphrase must have at least %d characters", cfg.Username, minPassphraseLength)
+		}
+		level = gosnmp.AuthNoPriv
+	}
+
+	if priv != gosnmp.NoPriv {
+		if auth == gosnmp.NoAuth {
+			return user{}, errors.Errorf("user %q: privacy protocol requires an authentication protocol", cfg.Username)
+		}
+
+		if len(cfg.PrivPassphrase) < minPassphraseLength {
+			return user{}, errors.Errorf("user %q: privacy passphrase must have at least %d characters", cfg.Username, minPassphraseLength)
+		}
+		level = gosnmp.AuthPriv
+	}
+
+	return user{
+		name:     cfg.Username,
+		engineID: string(engineID),
+		level:    level,
+		params: &gosnmp.GoSNMP{
+			Version:       gosnmp.Version3,
+			SecurityModel: gosnmp.UserSecurityModel,
+			MsgFlags:      level,
+			Logger:        log,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{
+				UserName:                 cfg.Username,
+				AuthoritativeEngineID:    string(engineID),
+				AuthenticationProtocol:   auth,
+				AuthenticationPassphrase: cfg.AuthPassphrase,
+				PrivacyProtocol:          priv,
+				PrivacyPassphrase:        cfg.PrivPassphrase,
+			},
+		},
+	}, nil
+}
+
+// Address returns the listener address and port, e.g. "10.0.0.1:162"
+func (s *Server) Address() string {
+	return s.conn.LocalAddr().String()
+}
+
+// PushMetrics pushes metrics to the configured metrics receiver
+func (s *Server) PushMetrics(ctx context.Context, ms metrics.Receiver) {
+	ticker := time.NewTicker(metrics.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Lock()
+			eventsSec := math.Round((float64(*s.stats.EventsTotal)/time.Since(s.stats.Started).Seconds())*100) / 100 // 0.2f syntax
+			s.stats.EventsSec = &eventsSec
+			ms.Receive(&s.stats)
+			s.Unlock()
+		}
+	}
+}
+
+// Stream starts the trap receiver invoking the specified processor for every
+// received trap. Stream will return when the given context is cancelled.
+func (s *Server) Stream(ctx context.Context, proc processor.Processor) error {
+	s.Infow("starting snmp trap receiver", "address", s.Address(), "communities", len(s.communities), "users", len(s.users))
+
+	go func() {
+		<-ctx.Done()
+		_ = s.conn.Close()
+	}()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				s.Info("stopping snmp trap receiver")
+				return ctx.Err()
+			}
+			return errors.Wrap(err, "read snmp trap")
+		}
+
+		s.handle(ctx, proc, buf[:n], addr)
+	}
+}
+
+// handle decodes the given packet and invokes the processor for the resulting
+// CloudEvent. Informs are acknowledged after the processor returned.
+func (s *Server) handle(ctx context.Context, proc processor.Processor, b []byte, remote net.Addr) {
+	pkt, err := s.decode(b)
+	if err != nil {
+		s.Warnw("could not decode snmp trap", "remote", remote, "error", err)
+		s.countError()
+		return
+	}
+
+	trap, err := s.toTrap(pkt, remote)
+	if err != nil {
+		s.Warnw("invalid snmp trap", "remote", remote, "error", err)
+		s.countError()
+		return
+	}
+
+	info := events.SNMPEventInfo{
+		TrapOID:  trap.TrapOID,
+		TrapName: trap.TrapName,
+		Time:     s.now().UTC(),
+	}
+
+	e, err := events.NewFromSNMP(info, trap, source(remote))
+	if err != nil {
+		s.Errorw("could not create cloud event for snmp trap", "remote", remote, "error", err)
+		s.countError()
+		return
+	}
+
+	s.Debugw("processing snmp trap", "remote", remote, "version", trap.Version, "trapOID", trap.TrapOID, "trapName", trap.TrapName)
+
+	err = proc.Process(ctx, *e)
+	if err != nil {
+		s.Errorw("could not process event", "eventID", e.ID(), "error", err)
+	}
+
+	if pkt.PDUType == gosnmp.InformRequest {
+		s.acknowledge(pkt, remote)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	*s.stats.EventsTotal++
+	if err != nil {
+		*s.stats.EventsErr++
+	}
+}
+
+// decode authenticates and decodes the given SNMPv2c or SNMPv3 packet
+func (s *Server) decode(b []byte) (*gosnmp.SnmpPacket, error) {
+	version, err := peekVersion(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case gosnmp.Version2c:
+		if s.v2c == nil {
+			return nil, errors.Wrap(ErrUnauthorized, "SNMPv2c is disabled")
+		}
+
+		pkt := unmarshalTrap(s.v2c, b)
+		if pkt == nil {
+			return nil, errors.New("invalid SNMPv2c packet")
+		}
+
+		if _, ok := s.communities[pkt.Community]; !ok {
+			return nil, errors.Wrap(ErrUnauthorized, "unknown community")
+		}
+		return pkt, nil
+
+	case gosnmp.Version3:
+		for _, u := range s.users {
+			pkt := unmarshalTrap(u.params, b)
+			if pkt == nil {
+				continue
+			}
+
+			sp, ok := pkt.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+			if !ok || sp.UserName != u.name || sp.AuthoritativeEngineID != u.engineID {
+				continue
+			}
+
+			// reject traps with a lower security level than configured
+			if pkt.MsgFlags&gosnmp.AuthPriv < u.level {
+				continue
+			}
+			return pkt, nil
+		}
+		return nil, errors.Wrap(ErrUnauthorized, "unknown user or authentication failed")
+
+	default:
+		return nil, errors.Errorf("unsupported SNMP version %s", version)
+	}
+}
+
+// unmarshalTrap decodes the given packet with the given parameters. nil is
+// returned if the packet is invalid or cannot be authenticated.
+func unmarshalTrap(params *gosnmp.GoSNMP, b []byte) (pkt *gosnmp.SnmpPacket) {
+	// guard against panics on malformed packets
+	defer func() {
+		if r := recover(); r != nil {
+			pkt = nil
+		}
+	}()
+
+	return params.UnmarshalTrap(b, false)
+}
+
+// peekVersion returns the SNMP version of the given BER-encoded message, i.e.
+// the first integer of the message sequence
+func peekVersion(b []byte) (gosnmp.SnmpVersion, error) {
+	if len(b) < 2 || b[0] != byte(gosnmp.Sequence) {
+		return 0, errors.New("invalid SNMP packet header")
+	}
+
+	// skip sequence length
+	i := 2
+	if b[1] > 0x80 {
+		i += int(b[1] & 0x7f)
+	}
+
+	if len(b) < i+3 || b[i] != byte(gosnmp.Integer) || b[i+1] != 1 {
+		return 0, errors.New("invalid SNMP version")
+	}
+
+	return gosnmp.SnmpVersion(b[i+2]), nil
+}
+
+// toTrap converts the given packet into trap data
+func (s *Server) toTrap(pkt *gosnmp.SnmpPacket, remote net.Addr) (*Trap, error) {
+	trap := Trap{
+		Version:      pkt.Version.String(),
+		AgentAddress: host(remote),
+		Variables:    make([]Variable, 0, len(pkt.Variables)),
+	}
+
+	switch pkt.PDUType {
+	case gosnmp.SNMPv2Trap:
+		trap.PDUType = "trap"
+	case gosnmp.InformRequest:
+		trap.PDUType = "inform"
+	default:
+		return nil, errors.Errorf("unsupported PDU type %#x", byte(pkt.PDUType))
+	}
+
+	if sp, ok := pkt.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok && pkt.Version == gosnmp.Version3 {
+		trap.Username = sp.UserName
+	}
+
+	for _, v := range pkt.Variables {
+		oid := normalizeOID(v.Name)
+
+		switch oid {
+		case sysUpTimeOID:
+			if t, ok := v.Value.(uint32); ok {
+				trap.Uptime = t
+			}
+		case snmpTrapOID:
+			if t, ok := v.Value.(string); ok {
+				trap.TrapOID = normalizeOID(t)
+			}
+		case snmpTrapAddressOID:
+			if a, ok := v.Value.(string); ok && a != "" {
+				trap.AgentAddress = a
+			}
+		}
+
+		trap.Variables = append(trap.Variables, s.toVariable(v))
+	}
+
+	if trap.TrapOID == "" {
+		return nil, errors.New("missing snmpTrapOID variable")
+	}
+	trap.TrapName = s.mib.resolve(trap.TrapOID)
+
+	return &trap, nil
+}
+
+// toVariable converts the given variable binding resolving OID names
+func (s *Server) toVariable(pdu gosnmp.SnmpPDU) Variable {
+	v := Variable{
+		OID:   normalizeOID(pdu.Name),
+		Name:  s.mib.resolve(pdu.Name),
+		Type:  pdu.Type.String(),
+		Value: pdu.Value,
+	}
+
+	switch val := pdu.Value.(type) {
+	case []byte:
+		v.Value = octetString(val)
+	case string:
+		if pdu.Type == gosnmp.ObjectIdentifier {
+			v.Value = normalizeOID(val)
+			v.ValueName = s.mib.resolve(val)
+		}
+	}
+
+	return v
+}
+
+// octetString returns the given octet string as text if printable, otherwise
+// hex-encoded
+func octetString(b []byte) string {
+	if !utf8.Valid(b) {
+		return hex.EncodeToString(b)
+	}
+
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return hex.EncodeToString(b)
+		}
+	}
+	return string(b)
+}
+
+// acknowledge sends the response for the given inform request
+func (s *Server) acknowledge(pkt *gosnmp.SnmpPacket, remote net.Addr) {
+	pkt.PDUType = gosnmp.GetResponse
+	pkt.Error = gosnmp.NoError
+	pkt.ErrorIndex = 0
+
+	b, err := pkt.MarshalMsg()
+	if err != nil {
+		s.Errorw("could not create inform response", "remote", remote, "error", err)
+		return
+	}
+
+	if _, err = s.conn.WriteTo(b, remote); err != nil {
+		s.Errorw("could not send inform response", "remote", remote, "error", err)
+	}
+}
+
+// countError records a trap which could not be converted into an event
+func (s *Server) countError() {
+	s.Lock()
+	defer s.Unlock()
+
+	*s.stats.EventsTotal++
+	*s.stats.EventsErr++
+}
+
+// host returns the host of the given address
+func host(addr net.Addr) string {
+	h := addr.String()
+	if hh, _, err := net.SplitHostPort(h); err == nil {
+		h = hh
+	}
+	return h
+}
+
+// source returns the CloudEvent source for the given remote address, e.g.
+// snmp://10.0.0.20
+func source(remote net.Addr) string {
+	h := host(remote)
+
+	// enclose IPv6 addresses
+	if strings.Contains(h, ":") {
+		h = "[" + h + "]"
+	}
+
+	return sourceScheme + h
+}
+
+// debugLogger logs gosnmp messages in DEBUG level
+type debugLogger struct {
+	logger.Logger
+}
+
+func (l debugLogger) Print(v ...interface{}) {
+	l.Debug(v...)
+}
+
+func (l debugLogger) Printf(format string, v ...interface{}) {
+	l.Debugf(format, v...)
+}
+
+// Shutdown is a no-op. The trap receiver will shut down when the context in
+// Stream() is cancelled.
+func (s *Server) Shutdown(_ context.Context) error {
+	return nil
+}
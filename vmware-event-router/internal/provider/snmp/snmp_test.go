@@ -0,0 +1,252 @@
+//go:build unit
+// +build unit
+
+package snmp_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/snmp"
+)
+
+const (
+	engineID = "80001f8880e9bd0c1d12667a5100000000"
+	linkDown = ".1.3.6.1.6.3.1.1.5.3"
+)
+
+// linkDownTrap returns a linkDown trap for the given interface
+func linkDownTrap(ifIndex int) gosnmp.SnmpTrap {
+	return gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(4200)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: linkDown},
+			{Name: ".1.3.6.1.2.1.2.2.1.1." + strconv.Itoa(ifIndex), Type: gosnmp.Integer, Value: ifIndex},
+			{Name: ".1.3.6.1.2.1.2.2.1.8." + strconv.Itoa(ifIndex), Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.3.6.1.2.1.2.2.1.2." + strconv.Itoa(ifIndex), Type: gosnmp.OctetString, Value: "vmnic0"},
+		},
+	}
+}
+
+// sender returns a gosnmp client sending traps to the given address
+func sender(t *testing.T, address string, configure func(x *gosnmp.GoSNMP)) *gosnmp.GoSNMP {
+	t.Helper()
+
+	h, p, err := net.SplitHostPort(address)
+	assert.NilError(t, err)
+	port, err := strconv.Atoi(p)
+	assert.NilError(t, err)
+
+	x := &gosnmp.GoSNMP{
+		Target:  h,
+		Port:    uint16(port),
+		Version: gosnmp.Version2c,
+		Timeout: time.Second,
+		Retries: 0,
+	}
+	configure(x)
+
+	assert.NilError(t, x.Connect())
+	t.Cleanup(func() { _ = x.Conn.Close() })
+	return x
+}
+
+func Test_SNMPServer(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel))
+
+	t.Run("fails to start with invalid snmp config", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			cfg       *config.ProviderConfigSNMP
+			errString string
+		}{
+			{"no config", nil, "snmp configuration must be provided"},
+			{"invalid bind address", &config.ProviderConfigSNMP{BindAddress: "abc:0", Communities: []string{"public"}}, "invalid snmp config: invalid character detected"},
+			{"no community or user", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0"}, "at least one community or user must be specified"},
+			{"empty community", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0", Communities: []string{""}}, "community must not be empty"},
+			{"invalid engine ID", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0", Users: []config.SNMPUser{{Username: "u", EngineID: "xyz"}}}, `invalid engine ID "xyz"`},
+			{"unsupported auth protocol", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0", Users: []config.SNMPUser{{Username: "u", EngineID: engineID, AuthProtocol: "sha1024"}}}, `unsupported authentication protocol "sha1024"`},
+			{"short auth passphrase", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0", Users: []config.SNMPUser{{Username: "u", EngineID: engineID, AuthProtocol: config.SNMPAuthSHA, AuthPassphrase: "short"}}}, "authentication passphrase must have at least 8 characters"},
+			{"privacy without auth", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0", Users: []config.SNMPUser{{Username: "u", EngineID: engineID, PrivProtocol: config.SNMPPrivAES, PrivPassphrase: "privpassword"}}}, "privacy protocol requires an authentication protocol"},
+			{"missing MIB mappings", &config.ProviderConfigSNMP{BindAddress: "127.0.0.1:0", Communities: []string{"public"}, MIBMappings: []string{"/does/not/exist.yaml"}}, "load MIB mappings"},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				_, err := snmp.NewServer(context.TODO(), test.cfg, metricsStub{}, logger.Sugar())
+				assert.ErrorContains(t, err, test.errString)
+			})
+		}
+	})
+
+	t.Run("receives traps and emits cloud events", func(t *testing.T) {
+		engine, err := hex.DecodeString(engineID)
+		assert.NilError(t, err)
+
+		tests := []struct {
+			name      string
+			configure func(x *gosnmp.GoSNMP)
+			wantUser  string
+		}{
+			{
+				name: "v2c",
+				configure: func(x *gosnmp.GoSNMP) {
+					x.Community = "vmware"
+				},
+			},
+			{
+				name: "v3 authPriv",
+				configure: func(x *gosnmp.GoSNMP) {
+					x.Version = gosnmp.Version3
+					x.SecurityModel = gosnmp.UserSecurityModel
+					x.MsgFlags = gosnmp.AuthPriv
+					x.SecurityParameters = &gosnmp.UsmSecurityParameters{
+						UserName:                 "vmware",
+						AuthoritativeEngineID:    string(engine),
+						AuthoritativeEngineBoots: 1,
+						AuthoritativeEngineTime:  1,
+						AuthenticationProtocol:   gosnmp.SHA,
+						AuthenticationPassphrase: "authpassword",
+						PrivacyProtocol:          gosnmp.AES,
+						PrivacyPassphrase:        "privpassword",
+					}
+				},
+				wantUser: "vmware",
+			},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				cfg := config.ProviderConfigSNMP{
+					BindAddress: "127.0.0.1:0",
+					Communities: []string{"vmware"},
+					Users: []config.SNMPUser{
+						{
+							Username:       "vmware",
+							EngineID:       engineID,
+							AuthProtocol:   config.SNMPAuthSHA,
+							AuthPassphrase: "authpassword",
+							PrivProtocol:   config.SNMPPrivAES,
+							PrivPassphrase: "privpassword",
+						},
+					},
+				}
+
+				srv, err := snmp.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+				assert.NilError(t, err)
+
+				proc := &recordingProcessor{events: make(chan ce.Event, 1)}
+
+				var eg errgroup.Group
+				eg.Go(func() error {
+					return srv.Stream(ctx, proc)
+				})
+
+				x := sender(t, srv.Address(), test.configure)
+				_, err = x.SendTrap(linkDownTrap(3))
+				assert.NilError(t, err)
+
+				e := <-proc.events
+				assert.Equal(t, e.Type(), "com.vmware.event.router/snmp")
+				assert.Equal(t, e.Source(), "snmp://127.0.0.1")
+				assert.Equal(t, e.Subject(), "linkDown")
+				assert.Equal(t, e.Extensions()["trapoid"], linkDown)
+
+				var trap snmp.Trap
+				assert.NilError(t, json.Unmarshal(e.Data(), &trap))
+				assert.Equal(t, trap.PDUType, "trap")
+				assert.Equal(t, trap.Username, test.wantUser)
+				assert.Equal(t, trap.Uptime, uint32(4200))
+				assert.Equal(t, trap.AgentAddress, "127.0.0.1")
+				assert.Equal(t, len(trap.Variables), 5)
+
+				assert.Equal(t, trap.Variables[1].Name, "snmpTrapOID.0")
+				assert.Equal(t, trap.Variables[1].ValueName, "linkDown")
+				assert.Equal(t, trap.Variables[3].Name, "ifOperStatus.3")
+				assert.Equal(t, trap.Variables[3].Type, "Integer")
+				assert.Equal(t, trap.Variables[3].Value, float64(2))
+				assert.Equal(t, trap.Variables[4].Name, "")
+				assert.Equal(t, trap.Variables[4].Value, "vmnic0")
+
+				cancel()
+				assert.Equal(t, eg.Wait(), context.Canceled)
+			})
+		}
+	})
+
+	t.Run("drops unauthorized traps", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cfg := config.ProviderConfigSNMP{
+			BindAddress: "127.0.0.1:0",
+			Communities: []string{"vmware"},
+		}
+
+		srv, err := snmp.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 1)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			return srv.Stream(ctx, proc)
+		})
+
+		// unknown community followed by a valid trap
+		invalid := sender(t, srv.Address(), func(x *gosnmp.GoSNMP) { x.Community = "public" })
+		_, err = invalid.SendTrap(linkDownTrap(1))
+		assert.NilError(t, err)
+
+		valid := sender(t, srv.Address(), func(x *gosnmp.GoSNMP) { x.Community = "vmware" })
+		_, err = valid.SendTrap(linkDownTrap(2))
+		assert.NilError(t, err)
+
+		e := <-proc.events
+
+		var trap snmp.Trap
+		assert.NilError(t, json.Unmarshal(e.Data(), &trap))
+		assert.Equal(t, trap.Variables[2].Value, float64(2), "only the trap with a valid community is processed")
+
+		cancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+type recordingProcessor struct {
+	events chan ce.Event
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.events <- e
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,14 @@
+package syslog
+
+import "time"
+
+// Option allows for customization of the syslog event provider
+type Option func(s *Server)
+
+// WithClock sets the function returning the current time used as event time
+// for messages without timestamp
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
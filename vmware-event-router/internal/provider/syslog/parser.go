@@ -0,0 +1,374 @@
+package syslog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+const (
+	nilValue = "-"
+
+	// maximum PRI value (facility 23, severity 7)
+	maxPriority = 191
+
+	// rfc3164 timestamp layout, e.g. "Jan  2 15:04:05"
+	bsdTimeLayout = time.Stamp
+)
+
+var (
+	// ErrInvalidMessage is returned when a syslog message cannot be parsed
+	ErrInvalidMessage = errors.New("invalid syslog message")
+
+	// utf8BOM is the optional byte order mark of an RFC 5424 message
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// Message is a parsed syslog message used as CloudEvent data
+type Message struct {
+	// Format is the detected message format, i.e. rfc5424 or rfc3164
+	Format config.SyslogFormat `json:"format"`
+	// Facility is the numeric facility code
+	Facility int `json:"facility"`
+	// FacilityName is the keyword of the facility, e.g. local4
+	FacilityName string `json:"facilityName"`
+	// Severity is the numeric severity code
+	Severity int `json:"severity"`
+	// SeverityName is the keyword of the severity, e.g. warning
+	SeverityName string `json:"severityName"`
+	// Timestamp is the message timestamp if present
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// Hostname is the host which sent the message
+	Hostname string `json:"hostname,omitempty"`
+	// Appname is the application which logged the message, e.g. Hostd
+	Appname string `json:"appname,omitempty"`
+	// ProcID is the process ID of the application
+	ProcID string `json:"procid,omitempty"`
+	// MsgID is the RFC 5424 message type identifier
+	MsgID string `json:"msgid,omitempty"`
+	// StructuredData contains the RFC 5424 structured data elements by SD-ID,
+	// e.g. Originator@6876 sent by VMware ESXi
+	StructuredData map[string]map[string]string `json:"structuredData,omitempty"`
+	// Message is the free-form message text
+	Message string `json:"message"`
+}
+
+// parse parses the given raw syslog message in the specified format. In auto
+// format RFC 5424 is detected by the version following the PRI, otherwise the
+// message is parsed as RFC 3164. The given time is used to infer the year of
+// RFC 3164 timestamps.
+func parse(b []byte, format config.SyslogFormat, now time.Time) (*Message, error) {
+	b = bytes.TrimRight(b, "\r\n\x00")
+
+	pri, rest, err := parsePriority(b)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Message{
+		Facility:     pri / 8,
+		FacilityName: facilityNames[pri/8],
+		Severity:     pri % 8,
+		SeverityName: severityNames[pri%8],
+	}
+
+	switch format {
+	case config.SyslogFormatRFC5424:
+		err = parseRFC5424(&m, rest)
+	case config.SyslogFormatRFC3164:
+		parseRFC3164(&m, rest, now)
+	case config.SyslogFormatAuto, "":
+		if isRFC5424(rest) {
+			err = parseRFC5424(&m, rest)
+		} else {
+			parseRFC3164(&m, rest, now)
+		}
+	default:
+		return nil, errors.Errorf("unsupported syslog format %q", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// parsePriority parses the leading PRI part, e.g. "<166>", and returns the
+// priority and the remaining message
+func parsePriority(b []byte) (int, []byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return 0, nil, errors.Wrap(ErrInvalidMessage, "missing priority")
+	}
+
+	end := bytes.IndexByte(b, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, errors.Wrap(ErrInvalidMessage, "invalid priority")
+	}
+
+	pri, err := strconv.Atoi(string(b[1:end]))
+	if err != nil || pri < 0 || pri > maxPriority {
+		return 0, nil, errors.Wrapf(ErrInvalidMessage, "invalid priority %q", b[1:end])
+	}
+
+	return pri, b[end+1:], nil
+}
+
+// isRFC5424 returns true if the message following the PRI starts with a
+// version number followed by a space
+func isRFC5424(b []byte) bool {
+	i := 0
+	for i < len(b) && i < 3 && b[i] >= '0' && b[i] <= '9' {
+		i++
+	}
+	return i > 0 && i < len(b) && b[i] == ' '
+}
+
+// parseRFC5424 parses the header, structured data and message of an RFC 5424
+// message following the PRI
+func parseRFC5424(m *Message, b []byte) error {
+	m.Format = config.SyslogFormatRFC5424
+
+	// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+	fields := make([]string, 6)
+	for i := range fields {
+		var ok bool
+		if fields[i], b, ok = nextField(b); !ok {
+			return errors.Wrap(ErrInvalidMessage, "incomplete RFC 5424 header")
+		}
+
+		if i == 0 && fields[0] != "1" {
+			return errors.Wrapf(ErrInvalidMessage, "unsupported RFC 5424 version %q", fields[0])
+		}
+	}
+
+	if fields[1] != nilValue {
+		t, err := time.Parse(time.RFC3339Nano, fields[1])
+		if err != nil {
+			return errors.Wrapf(ErrInvalidMessage, "invalid timestamp %q", fields[1])
+		}
+		m.Timestamp = &t
+	}
+
+	m.Hostname = nilToEmpty(fields[2])
+	m.Appname = nilToEmpty(fields[3])
+	m.ProcID = nilToEmpty(fields[4])
+	m.MsgID = nilToEmpty(fields[5])
+
+	sd, rest, err := parseStructuredData(b)
+	if err != nil {
+		return err
+	}
+	m.StructuredData = sd
+
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return errors.Wrap(ErrInvalidMessage, "missing space before message")
+		}
+		rest = bytes.TrimPrefix(rest[1:], utf8BOM)
+	}
+	m.Message = toValidUTF8(rest)
+
+	return nil
+}
+
+// parseStructuredData parses the RFC 5424 structured data elements, e.g.
+// [Originator@6876 sub=Vimsvc opID=abc], and returns the remaining message
+func parseStructuredData(b []byte) (map[string]map[string]string, []byte, error) {
+	if bytes.HasPrefix(b, []byte(nilValue)) {
+		return nil, b[1:], nil
+	}
+
+	if len(b) == 0 || b[0] != '[' {
+		return nil, nil, errors.Wrap(ErrInvalidMessage, "invalid structured data")
+	}
+
+	sd := make(map[string]map[string]string)
+	for len(b) > 0 && b[0] == '[' {
+		b = b[1:]
+
+		end := bytes.IndexAny(b, " ]")
+		if end <= 0 {
+			return nil, nil, errors.Wrap(ErrInvalidMessage, "invalid structured data element ID")
+		}
+
+		id := string(b[:end])
+		params := sd[id]
+		if params == nil {
+			params = make(map[string]string)
+			sd[id] = params
+		}
+		b = b[end:]
+
+		for len(b) > 0 && b[0] == ' ' {
+			b = b[1:]
+
+			eq := bytes.IndexByte(b, '=')
+			if eq <= 0 || eq+1 >= len(b) || bytes.ContainsAny(b[:eq], " ]") {
+				return nil, nil, errors.Wrapf(ErrInvalidMessage, "invalid structured data parameter in element %q", id)
+			}
+
+			name := string(b[:eq])
+			value, rest, err := parseParamValue(b[eq+1:])
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "structured data parameter %q", name)
+			}
+
+			params[name] = value
+			b = rest
+		}
+
+		if len(b) == 0 || b[0] != ']' {
+			return nil, nil, errors.Wrapf(ErrInvalidMessage, "unterminated structured data element %q", id)
+		}
+		b = b[1:]
+	}
+
+	return sd, b, nil
+}
+
+// parseParamValue parses a structured data parameter value and returns the
+// unescaped value and the remaining message. Besides quoted values, unquoted
+// values terminated by a space or "]" are accepted as sent by VMware ESXi, e.g.
+// [Originator@6876 sub=Vimsvc.ha-eventmgr opID=abc].
+func parseParamValue(b []byte) (string, []byte, error) {
+	if b[0] != '"' {
+		end := bytes.IndexAny(b, " ]")
+		if end < 0 {
+			return "", nil, errors.Wrap(ErrInvalidMessage, "unterminated parameter value")
+		}
+		return toValidUTF8(b[:end]), b[end:], nil
+	}
+
+	var sb strings.Builder
+	for i := 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			// only '"', '\' and ']' are escaped, other backslashes are kept
+			if i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+				i++
+			}
+			sb.WriteByte(b[i])
+		case '"':
+			return toValidUTF8([]byte(sb.String())), b[i+1:], nil
+		default:
+			sb.WriteByte(b[i])
+		}
+	}
+
+	return "", nil, errors.Wrap(ErrInvalidMessage, "unterminated parameter value")
+}
+
+// parseRFC3164 leniently parses a BSD syslog message following the PRI. Besides
+// the traditional timestamp, e.g. "Jan  2 15:04:05", RFC 3339 timestamps are
+// accepted as sent by many appliances. If no timestamp is found, the whole
+// remaining message is used as message text.
+func parseRFC3164(m *Message, b []byte, now time.Time) {
+	m.Format = config.SyslogFormatRFC3164
+
+	s := toValidUTF8(b)
+	t, rest, ok := parseBSDTimestamp(s, now)
+	if !ok {
+		m.Message = s
+		return
+	}
+	m.Timestamp = &t
+
+	// HOSTNAME TAG[PID]: MSG
+	if i := strings.IndexByte(rest, ' '); i > 0 && !isTag(rest[:i]) {
+		m.Hostname = rest[:i]
+		rest = rest[i+1:]
+	}
+
+	m.Appname, m.ProcID, rest = parseTag(rest)
+	m.Message = strings.TrimPrefix(rest, " ")
+}
+
+// parseBSDTimestamp parses a leading RFC 3164 or RFC 3339 timestamp followed
+// by a space. The year of RFC 3164 timestamps is inferred from the given time,
+// assuming timestamps more than a day in the future belong to the previous
+// year.
+func parseBSDTimestamp(s string, now time.Time) (time.Time, string, bool) {
+	if len(s) > len(bsdTimeLayout) && s[len(bsdTimeLayout)] == ' ' {
+		if t, err := time.ParseInLocation(bsdTimeLayout, s[:len(bsdTimeLayout)], now.Location()); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, s[len(bsdTimeLayout)+1:], true
+		}
+	}
+
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, s[:i]); err == nil {
+			return t, s[i+1:], true
+		}
+	}
+
+	return time.Time{}, s, false
+}
+
+// isTag returns true if the given field is a tag, i.e. ends with a colon or
+// contains a process ID, e.g. "vpxa:" or "sshd[123]:"
+func isTag(field string) bool {
+	return strings.HasSuffix(field, ":") || strings.Contains(field, "[")
+}
+
+// parseTag parses an optional tag with optional process ID, e.g. "sshd[123]:",
+// and returns the application name, process ID and remaining message
+func parseTag(s string) (string, string, string) {
+	end := strings.IndexByte(s, ':')
+	if end <= 0 || strings.ContainsAny(s[:end], " ") {
+		return "", "", s
+	}
+
+	tag, procID := s[:end], ""
+	if i := strings.IndexByte(tag, '['); i > 0 && strings.HasSuffix(tag, "]") {
+		tag, procID = tag[:i], tag[i+1:len(tag)-1]
+	}
+
+	return tag, procID, s[end+1:]
+}
+
+// nextField returns the next space separated header field and the remaining
+// message
+func nextField(b []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(b, ' ')
+	if i <= 0 {
+		return "", nil, false
+	}
+	return string(b[:i]), b[i+1:], true
+}
+
+// nilToEmpty returns an empty string for the RFC 5424 NILVALUE
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
+
+// toValidUTF8 returns the given bytes as string replacing invalid UTF-8
+// sequences
+func toValidUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return strings.ToValidUTF8(string(b), string(utf8.RuneError))
+}
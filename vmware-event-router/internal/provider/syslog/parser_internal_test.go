@@ -0,0 +1,211 @@
+//go:build unit
+// +build unit
+
+package syslog
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func Test_parse(t *testing.T) {
+	now := time.Date(2021, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		msg       string
+		format    config.SyslogFormat
+		want      *Message
+		errString string
+	}{
+		{
+			name:   "rfc5424 ESXi message with VMware structured data",
+			msg:    `<166>1 2021-09-01T12:00:00.123Z esx-01.corp.local Hostd 2099 - [Originator@6876 sub=Vimsvc.ha-eventmgr opID=esxui-1a2b] Event 123 : User root@10.0.0.1 logged in` + "\n",
+			format: config.SyslogFormatAuto,
+			want: &Message{
+				Format:       config.SyslogFormatRFC5424,
+				Facility:     20,
+				FacilityName: "local4",
+				Severity:     6,
+				SeverityName: "info",
+				Timestamp:    timePtr(time.Date(2021, time.September, 1, 12, 0, 0, 123000000, time.UTC)),
+				Hostname:     "esx-01.corp.local",
+				Appname:      "Hostd",
+				ProcID:       "2099",
+				StructuredData: map[string]map[string]string{
+					"Originator@6876": {"sub": "Vimsvc.ha-eventmgr", "opID": "esxui-1a2b"},
+				},
+				Message: "Event 123 : User root@10.0.0.1 logged in",
+			},
+		},
+		{
+			name:   "rfc5424 with multiple elements, escapes and BOM",
+			msg:    "<34>1 2021-09-01T12:00:00+02:00 vcsa-01 vpxd - ID47 [exampleSDID@32473 iut=\"3\" eventID=\"a \\\"b\\\" \\] c\\\\\"][meta seq=\"1\"] \xEF\xBB\xBFBOM message",
+			format: config.SyslogFormatRFC5424,
+			want: &Message{
+				Format:       config.SyslogFormatRFC5424,
+				Facility:     4,
+				FacilityName: "auth",
+				Severity:     2,
+				SeverityName: "crit",
+				Timestamp:    timePtr(time.Date(2021, time.September, 1, 10, 0, 0, 0, time.UTC)),
+				Hostname:     "vcsa-01",
+				Appname:      "vpxd",
+				MsgID:        "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473": {"iut": "3", "eventID": `a "b" ] c\`},
+					"meta":              {"seq": "1"},
+				},
+				Message: "BOM message",
+			},
+		},
+		{
+			name:   "rfc5424 with nil values and no message",
+			msg:    "<14>1 - - - - - -",
+			format: config.SyslogFormatAuto,
+			want: &Message{
+				Format:       config.SyslogFormatRFC5424,
+				Facility:     1,
+				FacilityName: "user",
+				Severity:     6,
+				SeverityName: "info",
+			},
+		},
+		{
+			name:   "rfc3164 with hostname and tag",
+			msg:    "<13>Dec 31 23:59:59 esx-01 vpxa[2101]: Message from vpxa",
+			format: config.SyslogFormatAuto,
+			want: &Message{
+				Format:       config.SyslogFormatRFC3164,
+				Facility:     1,
+				FacilityName: "user",
+				Severity:     5,
+				SeverityName: "notice",
+				Timestamp:    timePtr(time.Date(2020, time.December, 31, 23, 59, 59, 0, time.UTC)),
+				Hostname:     "esx-01",
+				Appname:      "vpxa",
+				ProcID:       "2101",
+				Message:      "Message from vpxa",
+			},
+		},
+		{
+			name:   "rfc3164 with rfc3339 timestamp and without hostname",
+			msg:    "<29>2021-01-01T09:00:00Z sshd: Accepted password",
+			format: config.SyslogFormatAuto,
+			want: &Message{
+				Format:       config.SyslogFormatRFC3164,
+				Facility:     3,
+				FacilityName: "daemon",
+				Severity:     5,
+				SeverityName: "notice",
+				Timestamp:    timePtr(time.Date(2021, time.January, 1, 9, 0, 0, 0, time.UTC)),
+				Appname:      "sshd",
+				Message:      "Accepted password",
+			},
+		},
+		{
+			name:   "rfc3164 without timestamp",
+			msg:    "<0>kernel panic",
+			format: config.SyslogFormatRFC3164,
+			want: &Message{
+				Format:       config.SyslogFormatRFC3164,
+				FacilityName: "kern",
+				SeverityName: "emerg",
+				Message:      "kernel panic",
+			},
+		},
+		{name: "missing priority", msg: "no priority", format: config.SyslogFormatAuto, errString: "missing priority"},
+		{name: "priority out of range", msg: "<192>1 - - - - - -", format: config.SyslogFormatAuto, errString: "invalid priority"},
+		{name: "unsupported version", msg: "<14>2 - - - - - -", format: config.SyslogFormatAuto, errString: "unsupported RFC 5424 version"},
+		{name: "incomplete header", msg: "<14>1 - host", format: config.SyslogFormatAuto, errString: "incomplete RFC 5424 header"},
+		{name: "invalid timestamp", msg: "<14>1 yesterday - - - - -", format: config.SyslogFormatAuto, errString: "invalid timestamp"},
+		{name: "unterminated structured data", msg: `<14>1 - - - - - [id a="b"`, format: config.SyslogFormatAuto, errString: "unterminated structured data element"},
+		{name: "unterminated parameter value", msg: `<14>1 - - - - - [id a="b]`, format: config.SyslogFormatAuto, errString: "unterminated parameter value"},
+		{name: "unterminated unquoted parameter value", msg: `<14>1 - - - - - [id a=b`, format: config.SyslogFormatAuto, errString: "unterminated parameter value"},
+		{name: "invalid parameter", msg: `<14>1 - - - - - [id a]`, format: config.SyslogFormatAuto, errString: "invalid structured data parameter"},
+		{name: "rfc3164 message parsed as rfc5424", msg: "<13>Dec 31 23:59:59 esx-01 vpxa: msg", format: config.SyslogFormatRFC5424, errString: "unsupported RFC 5424 version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse([]byte(tt.msg), tt.format, now)
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func Test_readFrame(t *testing.T) {
+	long := strings.Repeat("a", maxMessageSize+1)
+
+	tests := []struct {
+		name      string
+		stream    string
+		want      []string
+		errString string
+	}{
+		{
+			name:   "non-transparent framing",
+			stream: "<14>1 - - - - - - first\n<14>1 - - - - - - second\n",
+			want:   []string{"<14>1 - - - - - - first\n", "<14>1 - - - - - - second\n"},
+		},
+		{
+			name:   "octet counting",
+			stream: "23 <14>1 - - - - - - first24 <14>1 - - - - - - second",
+			want:   []string{"<14>1 - - - - - - first", "<14>1 - - - - - - second"},
+		},
+		{
+			name:   "last message without trailing LF",
+			stream: "<14>1 - - - - - - first\n<14>1 - - - - - - second",
+			want:   []string{"<14>1 - - - - - - first\n", "<14>1 - - - - - - second"},
+		},
+		{
+			name:      "message too large is discarded",
+			stream:    "<14>" + long + "\n<14>1 - - - - - - next\n",
+			want:      []string{"<14>1 - - - - - - next\n"},
+			errString: errMessageTooLarge.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(tt.stream), maxMessageSize)
+
+			var (
+				got  []string
+				errs []string
+			)
+			for {
+				b, err := readFrame(r)
+				if len(b) > 0 {
+					got = append(got, string(b))
+				}
+				if err != nil {
+					if err == errMessageTooLarge {
+						errs = append(errs, err.Error())
+						continue
+					}
+					break
+				}
+			}
+
+			assert.DeepEqual(t, got, tt.want)
+			if tt.errString != "" {
+				assert.DeepEqual(t, errs, []string{tt.errString})
+			}
+		})
+	}
+}
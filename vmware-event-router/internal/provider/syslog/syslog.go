@@ -0,0 +1,430 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/util"
+)
+
+const (
+	// maximum size of a single syslog message
+	maxMessageSize = 64 * 1024
+
+	sourceScheme = "syslog://"
+)
+
+var (
+	// errMessageTooLarge is returned when a framed message exceeds the maximum
+	// message size
+	errMessageTooLarge = errors.New("message exceeds maximum size")
+)
+
+// Server is a syslog event provider receiving messages via UDP, TCP or TLS
+type Server struct {
+	protocol config.SyslogProtocol
+	format   config.SyslogFormat
+	conn     net.PacketConn // udp
+	listener net.Listener   // tcp and tls
+	now      func() time.Time
+	logger.Logger
+
+	sync.RWMutex
+	stats metrics.EventStats
+}
+
+// NewServer returns a syslog event provider listening on the configured
+// address and protocol
+func NewServer(ctx context.Context, cfg *config.ProviderConfigSyslog, ms metrics.Receiver, log logger.Logger, opts ...Option) (*Server, error) {
+	if cfg == nil {
+		return nil, errors.New("syslog configuration must be provided")
+	}
+
+	if err := util.ValidateAddress(cfg.BindAddress); err != nil {
+		return nil, errors.Wrap(err, "invalid syslog config")
+	}
+
+	srv := Server{
+		protocol: cfg.Protocol,
+		format:   cfg.Format,
+		now:      time.Now,
+		Logger:   log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		prov := strings.ToUpper(string(config.ProviderSyslog))
+		srv.Logger = zapSugared.Named(fmt.Sprintf("[%s]", prov))
+	}
+
+	switch cfg.Format {
+	case "", config.SyslogFormatAuto, config.SyslogFormatRFC5424, config.SyslogFormatRFC3164:
+	default:
+		return nil, errors.Errorf("invalid syslog config: unsupported format %q", cfg.Format)
+	}
+
+	if cfg.TLS != nil && cfg.Protocol != config.SyslogTLS {
+		return nil, errors.Errorf("invalid syslog config: TLS settings require protocol %q", config.SyslogTLS)
+	}
+
+	switch cfg.Protocol {
+	case config.SyslogUDP:
+		conn, err := net.ListenPacket("udp", cfg.BindAddress)
+		if err != nil {
+			return nil, errors.Wrap(err, "start listener")
+		}
+		srv.conn = conn
+
+	case config.SyslogTCP, config.SyslogTLS:
+		var tlsCfg *tls.Config
+		if cfg.Protocol == config.SyslogTLS {
+			if cfg.TLS == nil {
+				return nil, errors.Errorf("invalid syslog config: TLS settings must be specified for protocol %q", config.SyslogTLS)
+			}
+
+			var err error
+			tlsCfg, err = util.NewTLSConfig(cfg.TLS, srv.Logger)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid syslog config")
+			}
+		}
+
+		l, err := net.Listen("tcp", cfg.BindAddress)
+		if err != nil {
+			return nil, errors.Wrap(err, "start listener")
+		}
+
+		if tlsCfg != nil {
+			srv.Infow("enabling TLS", "mutualTLS", cfg.TLS.ClientCAFile != "", "minVersion", cfg.TLS.MinVersion)
+			l = tls.NewListener(l, tlsCfg)
+		}
+		srv.listener = l
+
+	default:
+		return nil, errors.Errorf("invalid syslog config: unsupported protocol %q", cfg.Protocol)
+	}
+
+	srv.stats = metrics.EventStats{
+		Provider:    string(config.ProviderSyslog),
+		Type:        config.EventProvider,
+		Address:     cfg.BindAddress,
+		Started:     time.Now().UTC(),
+		EventsTotal: new(int),
+		EventsErr:   new(int),
+		EventsSec:   new(float64),
+	}
+
+	// apply options (use defaults otherwise)
+	for _, opt := range opts {
+		opt(&srv)
+	}
+
+	go srv.PushMetrics(ctx, ms)
+
+	return &srv, nil
+}
+
+// Address returns the listener address and port, e.g. "10.0.0.1:514"
+func (s *Server) Address() string {
+	if s.conn != nil {
+		return s.conn.LocalAddr().String()
+	}
+	return s.listener.Addr().String()
+}
+
+// PushMetrics pushes metrics to the configured metrics receiver
+func (s *Server) PushMetrics(ctx context.Context, ms metrics.Receiver) {
+	ticker := time.NewTicker(metrics.PushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Lock()
+			eventsSec := math.Round((float64(*s.stats.EventsTotal)/time.Since(s.stats.Started).Seconds())*100) / 100 // 0.2f syntax
+			s.stats.EventsSec = &eventsSec
+			ms.Receive(&s.stats)
+			s.Unlock()
+		}
+	}
+}
+
+// Stream starts the syslog server invoking the specified processor for every
+// received message. Messages are processed in the order they are received per
+// connection. Stream will return when the given context is cancelled.
+func (s *Server) Stream(ctx context.Context, proc processor.Processor) error {
+	s.Infow("starting syslog server", "protocol", s.protocol, "address", s.Address(), "format", s.format)
+
+	if s.conn != nil {
+		return s.serveUDP(ctx, proc)
+	}
+	return s.serveTCP(ctx, proc)
+}
+
+// serveUDP reads one message per datagram until the context is cancelled
+func (s *Server) serveUDP(ctx context.Context, proc processor.Processor) error {
+	go func() {
+		<-ctx.Done()
+		_ = s.conn.Close()
+	}()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				s.Info("stopping syslog server")
+				return ctx.Err()
+			}
+			return errors.Wrap(err, "read syslog message")
+		}
+
+		s.handle(ctx, proc, buf[:n], addr)
+	}
+}
+
+// serveTCP accepts connections and reads framed messages until the context is
+// cancelled
+func (s *Server) serveTCP(ctx context.Context, proc processor.Processor) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		conns = make(map[net.Conn]struct{})
+	)
+
+	go func() {
+		<-ctx.Done()
+		_ = s.listener.Close()
+
+		mu.Lock()
+		for c := range conns {
+			_ = c.Close()
+		}
+		mu.Unlock()
+	}()
+
+	defer wg.Wait()
+
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				s.Info("stopping syslog server")
+				return ctx.Err()
+			}
+
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				s.Warnw("could not accept connection", "error", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return errors.Wrap(err, "accept syslog connection")
+		}
+
+		mu.Lock()
+		if ctx.Err() != nil {
+			mu.Unlock()
+			_ = c.Close()
+			continue
+		}
+		conns[c] = struct{}{}
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				delete(conns, c)
+				mu.Unlock()
+				_ = c.Close()
+			}()
+
+			s.serveConn(ctx, proc, c)
+		}()
+	}
+}
+
+// serveConn reads framed messages from the given connection until the
+// connection is closed
+func (s *Server) serveConn(ctx context.Context, proc processor.Processor, c net.Conn) {
+	s.Debugw("accepted connection", "remote", c.RemoteAddr())
+	r := bufio.NewReaderSize(c, maxMessageSize)
+
+	for {
+		b, err := readFrame(r)
+		switch {
+		case errors.Is(err, errMessageTooLarge):
+			s.Warnw("discarding syslog message", "remote", c.RemoteAddr(), "error", err)
+			s.countError()
+			continue
+
+		case err != nil && len(b) == 0:
+			if err != io.EOF && ctx.Err() == nil {
+				s.Debugw("closing connection", "remote", c.RemoteAddr(), "error", err)
+			}
+			return
+		}
+
+		if len(b) > 0 {
+			s.handle(ctx, proc, b, c.RemoteAddr())
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readFrame reads the next message using octet counting, e.g. "57 <34>1 ...",
+// or non-transparent framing with a trailing LF (RFC 6587). The message read
+// before EOF is returned together with the error.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		return readOctetCounted(r)
+	}
+
+	b, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// discard the remainder of the message
+		for err == bufio.ErrBufferFull {
+			_, err = r.ReadSlice('\n')
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return nil, errMessageTooLarge
+	}
+
+	return b, err
+}
+
+// readOctetCounted reads a message prefixed with its length
+func readOctetCounted(r *bufio.Reader) ([]byte, error) {
+	prefix, err := r.ReadSlice(' ')
+	if err != nil {
+		if err == bufio.ErrBufferFull || err == io.EOF {
+			return nil, errors.New("invalid octet counting frame")
+		}
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+	if err != nil || n <= 0 {
+		return nil, errors.Errorf("invalid octet counting frame length %q", prefix[:len(prefix)-1])
+	}
+
+	if n > maxMessageSize {
+		if _, err = r.Discard(n); err != nil {
+			return nil, err
+		}
+		return nil, errMessageTooLarge
+	}
+
+	b := make([]byte, n)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// handle parses the given message and invokes the processor for the resulting
+// CloudEvent
+func (s *Server) handle(ctx context.Context, proc processor.Processor, b []byte, remote net.Addr) {
+	m, err := parse(b, s.format, s.now())
+	if err != nil {
+		s.Warnw("could not parse syslog message", "remote", remote, "error", err)
+		s.countError()
+		return
+	}
+
+	t := s.now().UTC()
+	if m.Timestamp != nil {
+		t = m.Timestamp.UTC()
+	}
+
+	info := events.SyslogEventInfo{
+		Hostname: m.Hostname,
+		Appname:  m.Appname,
+		Severity: m.SeverityName,
+		Time:     t,
+	}
+
+	e, err := events.NewFromSyslog(info, m, source(remote))
+	if err != nil {
+		s.Errorw("could not create cloud event for syslog message", "remote", remote, "error", err)
+		s.countError()
+		return
+	}
+
+	s.Debugw("processing syslog message", "remote", remote, "hostname", m.Hostname, "appname", m.Appname, "severity", m.SeverityName)
+
+	err = proc.Process(ctx, *e)
+	if err != nil {
+		s.Errorw("could not process event", "eventID", e.ID(), "error", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	*s.stats.EventsTotal++
+	if err != nil {
+		*s.stats.EventsErr++
+	}
+}
+
+// countError records a message which could not be converted into an event
+func (s *Server) countError() {
+	s.Lock()
+	defer s.Unlock()
+
+	*s.stats.EventsTotal++
+	*s.stats.EventsErr++
+}
+
+// source returns the CloudEvent source for the given remote address, e.g.
+// syslog://10.0.0.10
+func source(remote net.Addr) string {
+	host := remote.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	// enclose IPv6 addresses
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	return sourceScheme + host
+}
+
+// Shutdown is a no-op. The syslog server will shut down when the context in
+// Stream() is cancelled.
+func (s *Server) Shutdown(_ context.Context) error {
+	return nil
+}
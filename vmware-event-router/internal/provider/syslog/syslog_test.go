@@ -0,0 +1,148 @@
+//go:build unit
+// +build unit
+
+package syslog_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/syslog"
+)
+
+const (
+	esxMessage = `<166>1 2021-09-01T12:00:00.123Z esx-01.corp.local Hostd - - [Originator@6876 sub=Vimsvc.ha-eventmgr opID=esxui-1a2b] Event 123 : User root@127.0.0.1 logged in`
+	bsdMessage = `<13>Sep  1 12:00:00 vcsa-01 vpxd[2101]: Message from vpxd`
+)
+
+func Test_SyslogServer(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.DebugLevel))
+
+	t.Run("fails to start with invalid syslog config", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			cfg       *config.ProviderConfigSyslog
+			errString string
+		}{
+			{"no config", nil, "syslog configuration must be provided"},
+			{"invalid bind address", &config.ProviderConfigSyslog{BindAddress: "abc:0", Protocol: config.SyslogUDP}, "invalid syslog config: invalid character detected"},
+			{"unsupported protocol", &config.ProviderConfigSyslog{BindAddress: "127.0.0.1:0", Protocol: "sctp"}, `unsupported protocol "sctp"`},
+			{"unsupported format", &config.ProviderConfigSyslog{BindAddress: "127.0.0.1:0", Protocol: config.SyslogUDP, Format: "json"}, `unsupported format "json"`},
+			{"tls without certificate", &config.ProviderConfigSyslog{BindAddress: "127.0.0.1:0", Protocol: config.SyslogTLS}, "TLS settings must be specified"},
+			{"tls settings for tcp", &config.ProviderConfigSyslog{BindAddress: "127.0.0.1:0", Protocol: config.SyslogTCP, TLS: &config.TLSConfig{}}, "TLS settings require protocol"},
+			{"missing tls certificate", &config.ProviderConfigSyslog{BindAddress: "127.0.0.1:0", Protocol: config.SyslogTLS, TLS: &config.TLSConfig{CertFile: "/does/not/exist.crt", KeyFile: "/does/not/exist.key"}}, "invalid syslog config: stat file"},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				_, err := syslog.NewServer(context.TODO(), test.cfg, metricsStub{}, logger.Sugar())
+				assert.ErrorContains(t, err, test.errString)
+			})
+		}
+	})
+
+	t.Run("receives messages and emits cloud events", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			protocol config.SyslogProtocol
+			payload  string // raw data written to the connection
+		}{
+			{"udp", config.SyslogUDP, esxMessage},
+			{"tcp with octet counting", config.SyslogTCP, fmt.Sprintf("%d %s%d %s", len(esxMessage), esxMessage, len(bsdMessage), bsdMessage)},
+			{"tcp with non-transparent framing", config.SyslogTCP, esxMessage + "\n" + bsdMessage + "\n"},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				cfg := config.ProviderConfigSyslog{
+					BindAddress: "127.0.0.1:0",
+					Protocol:    test.protocol,
+				}
+
+				srv, err := syslog.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+				assert.NilError(t, err)
+
+				proc := &recordingProcessor{events: make(chan ce.Event, 2)}
+
+				var eg errgroup.Group
+				eg.Go(func() error {
+					return srv.Stream(ctx, proc)
+				})
+
+				network := "udp"
+				if test.protocol == config.SyslogTCP {
+					network = "tcp"
+				}
+
+				conn, err := net.Dial(network, srv.Address())
+				assert.NilError(t, err)
+				defer conn.Close()
+
+				_, err = conn.Write([]byte(test.payload))
+				assert.NilError(t, err)
+
+				e := <-proc.events
+				assert.Equal(t, e.Type(), "com.vmware.event.router/syslog")
+				assert.Equal(t, e.Source(), "syslog://127.0.0.1")
+				assert.Equal(t, e.Subject(), "Hostd")
+				assert.Equal(t, e.Time(), time.Date(2021, time.September, 1, 12, 0, 0, 123000000, time.UTC))
+				assert.DeepEqual(t, e.Extensions(), map[string]interface{}{
+					"hostname": "esx-01.corp.local",
+					"appname":  "Hostd",
+					"severity": "info",
+				})
+
+				var m syslog.Message
+				assert.NilError(t, json.Unmarshal(e.Data(), &m))
+				assert.Equal(t, m.StructuredData["Originator@6876"]["opID"], "esxui-1a2b")
+				assert.Equal(t, m.Message, "Event 123 : User root@127.0.0.1 logged in")
+
+				if test.protocol == config.SyslogTCP {
+					e = <-proc.events
+					assert.Equal(t, e.Subject(), "vpxd")
+					assert.Equal(t, e.Extensions()["hostname"], "vcsa-01")
+					assert.Equal(t, e.Extensions()["severity"], "notice")
+				}
+
+				cancel()
+				assert.Equal(t, eg.Wait(), context.Canceled)
+			})
+		}
+	})
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+type recordingProcessor struct {
+	events chan ce.Event
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.events <- e
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
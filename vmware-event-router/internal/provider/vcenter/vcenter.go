@@ -341,7 +341,7 @@ func (vc *EventStream) processEvents(ctx context.Context, baseEvents []types.Bas
 		}
 
 		vc.Infow("invoking processor", "eventID", ce.ID())
-		err = p.Process(ctx, *ce)
+		err = p.Process(events.WithVSphereEvent(ctx, e), *ce)
 		if err != nil {
 			// retry logic handled inside processor
 			vc.Errorw("could not process event", "event", ce, "error", err)
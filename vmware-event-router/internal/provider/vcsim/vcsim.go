@@ -142,7 +142,7 @@ func eventHandler(ctx context.Context, vcsim *EventStream, proc processor.Proces
 				continue
 			}
 
-			err = proc.Process(ctx, *ce)
+			err = proc.Process(events.WithVSphereEvent(ctx, e), *ce)
 			if err != nil {
 				vcsim.Errorw("could not process event", "event", ce, "error", err)
 				errCount++
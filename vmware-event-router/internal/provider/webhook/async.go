@@ -0,0 +1,419 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+const (
+	defaultQueueSize    = 1000
+	defaultQueueWorkers = 1
+	defaultStatusPath   = "webhook/status"
+
+	// maximum number of delivery status entries kept in memory
+	maxStatusEntries = 10000
+
+	queueFileExt    = ".json"
+	queueTmpExt     = ".tmp"
+	queueInvalidExt = ".invalid"
+)
+
+var (
+	// ErrQueueFull is returned when an event cannot be queued because the
+	// maximum queue size is reached
+	ErrQueueFull = errors.New("queue full")
+)
+
+// deliveryStatus is the delivery status of a queued event
+type deliveryStatus string
+
+const (
+	statusQueued     deliveryStatus = "queued"
+	statusProcessing deliveryStatus = "processing"
+	statusDelivered  deliveryStatus = "delivered"
+	statusFailed     deliveryStatus = "failed"
+)
+
+// delivery contains the delivery details of a queued event
+type delivery struct {
+	ID      string         `json:"id"`
+	Source  string         `json:"source"`
+	Type    string         `json:"type"`
+	Status  deliveryStatus `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Updated time.Time      `json:"updated"`
+}
+
+// deliveryKey identifies an event by its CloudEvent source and ID
+type deliveryKey struct {
+	source string
+	id     string
+}
+
+// queuedEvent is an event persisted in the queue directory
+type queuedEvent struct {
+	file  string
+	event ce.Event
+}
+
+// queue is a durable event queue backed by a directory. Every event is written
+// to its own file before it is acknowledged and removed once processed. Pending
+// events are loaded when the queue is created, e.g. after a restart.
+type queue struct {
+	dir     string
+	workers int
+	items   chan queuedEvent
+	logger  logger.Logger
+	now     func() time.Time
+
+	seq      int64 // last used file sequence number (atomic)
+	reserved int64 // queue slots reserved by inflight enqueues (atomic)
+
+	mu     sync.Mutex
+	status map[string]map[string]*delivery // event ID to source to delivery
+	order  []deliveryKey                   // status entries in insertion order for eviction
+}
+
+// newQueue returns a durable queue for the given configuration. Pending events
+// in the queue directory are queued for processing.
+func newQueue(cfg *config.WebhookAsync, log logger.Logger) (*queue, error) {
+	if cfg.QueueDir == "" {
+		return nil, errors.New("queue directory must be specified")
+	}
+
+	size, workers := defaultQueueSize, defaultQueueWorkers
+	switch {
+	case cfg.MaxQueueSize < 0:
+		return nil, errors.Errorf("invalid maximum queue size %d", cfg.MaxQueueSize)
+	case cfg.MaxQueueSize > 0:
+		size = cfg.MaxQueueSize
+	}
+
+	switch {
+	case cfg.Workers < 0:
+		return nil, errors.Errorf("invalid number of workers %d", cfg.Workers)
+	case cfg.Workers > 0:
+		workers = cfg.Workers
+	}
+
+	if err := os.MkdirAll(cfg.QueueDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "could not create queue directory")
+	}
+
+	q := queue{
+		dir:     cfg.QueueDir,
+		workers: workers,
+		logger:  log,
+		now:     time.Now,
+		status:  make(map[string]map[string]*delivery),
+	}
+
+	pending, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) > size {
+		size = len(pending)
+	}
+
+	q.items = make(chan queuedEvent, size)
+	for _, item := range pending {
+		q.setStatus(item.event, statusQueued, nil)
+		q.items <- item
+	}
+
+	if len(pending) > 0 {
+		q.logger.Infow("loaded pending events from queue directory", "events", len(pending), "dir", q.dir)
+	}
+
+	return &q, nil
+}
+
+// load returns the pending events in the queue directory sorted by the order
+// they were queued. Incomplete files are removed and invalid files are renamed
+// to prevent further loading.
+func (q *queue) load() ([]queuedEvent, error) {
+	entries, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read queue directory")
+	}
+
+	var pending []queuedEvent
+	for _, entry := range entries {
+		name := entry.Name()
+		file := filepath.Join(q.dir, name)
+
+		switch {
+		case entry.IsDir():
+			continue
+
+		case strings.HasSuffix(name, queueTmpExt):
+			// incomplete write, event was never acknowledged
+			_ = os.Remove(file)
+			continue
+
+		case !strings.HasSuffix(name, queueFileExt):
+			continue
+		}
+
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, queueFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read queued event %q", file)
+		}
+
+		var e ce.Event
+		if err = json.Unmarshal(b, &e); err != nil {
+			q.logger.Errorw("could not decode queued event, skipping", "file", file, "error", err)
+			_ = os.Rename(file, file+queueInvalidExt)
+			continue
+		}
+
+		if seq > q.seq {
+			q.seq = seq
+		}
+		pending = append(pending, queuedEvent{file: file, event: e})
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].file < pending[j].file
+	})
+
+	return pending, nil
+}
+
+// enqueue persists the given event and queues it for processing. ErrQueueFull
+// is returned if the maximum queue size is reached.
+func (q *queue) enqueue(e ce.Event) error {
+	// reserve a slot before persisting so the event can be queued without
+	// blocking. Reservations are released after the event was queued, i.e.
+	// queued and reserved events never exceed the queue capacity.
+	n := atomic.AddInt64(&q.reserved, 1)
+	defer atomic.AddInt64(&q.reserved, -1)
+
+	if len(q.items)+int(n) > cap(q.items) {
+		return ErrQueueFull
+	}
+
+	// persist without holding the lock to not block status updates on disk syncs
+	seq := atomic.AddInt64(&q.seq, 1)
+	file := filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, queueFileExt))
+	if err := writeFileSync(file, e); err != nil {
+		return errors.Wrap(err, "could not persist event")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.setStatusLocked(e, statusQueued, nil)
+	q.items <- queuedEvent{file: file, event: e}
+
+	return nil
+}
+
+// writeFileSync atomically writes the given event as JSON to file and syncs it
+// to disk
+func writeFileSync(file string, e ce.Event) (err error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + queueTmpExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if _, err = f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// run processes queued events with the configured number of workers using the
+// given process function until the context is cancelled. Events which are
+// interrupted by cancellation remain in the queue directory.
+func (q *queue) run(ctx context.Context, process func(ctx context.Context, e ce.Event) error) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, process)
+		}()
+	}
+	wg.Wait()
+}
+
+// work processes queued events until the context is cancelled
+func (q *queue) work(ctx context.Context, process func(ctx context.Context, e ce.Event) error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-q.items:
+			q.setStatus(item.event, statusProcessing, nil)
+
+			err := process(ctx, item.event)
+			if err != nil && ctx.Err() != nil {
+				// shutting down: keep event for processing after restart
+				q.setStatus(item.event, statusQueued, nil)
+				return
+			}
+
+			if rmErr := os.Remove(item.file); rmErr != nil {
+				q.logger.Errorw("could not remove processed event from queue directory", "file", item.file, "error", rmErr)
+			}
+
+			if err != nil {
+				q.logger.Errorw("could not process queued event", "eventID", item.event.ID(), "error", err)
+				q.setStatus(item.event, statusFailed, err)
+				continue
+			}
+			q.setStatus(item.event, statusDelivered, nil)
+		}
+	}
+}
+
+// setStatus sets the delivery status of the given event
+func (q *queue) setStatus(e ce.Event, status deliveryStatus, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.setStatusLocked(e, status, err)
+}
+
+// setStatusLocked sets the delivery status of the given event and evicts the
+// oldest entries if the maximum number of entries is exceeded. Must be called
+// with the lock held.
+func (q *queue) setStatusLocked(e ce.Event, status deliveryStatus, err error) {
+	sources, ok := q.status[e.ID()]
+	if !ok {
+		sources = make(map[string]*delivery)
+		q.status[e.ID()] = sources
+	}
+
+	d, ok := sources[e.Source()]
+	if !ok {
+		d = &delivery{ID: e.ID(), Source: e.Source()}
+		sources[e.Source()] = d
+		q.order = append(q.order, deliveryKey{source: e.Source(), id: e.ID()})
+	}
+
+	d.Type = e.Type()
+	d.Status = status
+	d.Error = ""
+	if err != nil {
+		d.Error = err.Error()
+	}
+	d.Updated = q.now().UTC()
+
+	for len(q.order) > maxStatusEntries {
+		oldest := q.order[0]
+		delete(q.status[oldest.id], oldest.source)
+		if len(q.status[oldest.id]) == 0 {
+			delete(q.status, oldest.id)
+		}
+		q.order = q.order[1:]
+	}
+}
+
+// delivery returns the delivery details for the given event ID and source. If
+// source is empty, any source matches. The number of matching events is
+// returned, i.e. the details are only valid if exactly one event matches.
+func (q *queue) delivery(id, source string) (delivery, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sources := q.status[id]
+	if source != "" {
+		d, ok := sources[source]
+		if !ok {
+			return delivery{}, 0
+		}
+		return *d, 1
+	}
+
+	if len(sources) != 1 {
+		return delivery{}, len(sources)
+	}
+
+	for _, d := range sources {
+		return *d, 1
+	}
+	return delivery{}, 0
+}
+
+// statusHandler returns an http handler responding with the delivery details of
+// the event ID following the given path prefix, e.g. /webhook/status/<id>. The
+// optional source query parameter is required if multiple sources sent events
+// with the same ID, e.g. /webhook/status/<id>?source=<source>.
+func (s *Server) statusHandler(prefix string, q *queue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		if id == "" || id == r.URL.Path {
+			http.Error(w, "event ID must be specified", http.StatusBadRequest)
+			return
+		}
+
+		d, n := q.delivery(id, r.URL.Query().Get("source"))
+		switch {
+		case n == 0:
+			http.Error(w, "event not found", http.StatusNotFound)
+			return
+		case n > 1:
+			http.Error(w, "multiple events with this ID found, source must be specified", http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d); err != nil {
+			s.Errorw("could not write status response", "error", err)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+//go:build unit
+// +build unit
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+func newTestEvent(t *testing.T, id string) ce.Event {
+	t.Helper()
+	e := ce.NewEvent()
+	e.SetID(id)
+	e.SetSource("https://example.com")
+	e.SetType("com.example.test")
+	assert.NilError(t, e.SetData(ce.ApplicationJSON, map[string]string{"id": id}))
+	return e
+}
+
+// queueFiles returns the names of all files in the given directory
+func queueFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+
+	files := []string{}
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	return files
+}
+
+func Test_newQueue(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	tests := []struct {
+		name      string
+		cfg       config.WebhookAsync
+		errString string
+	}{
+		{name: "no queue directory", cfg: config.WebhookAsync{}, errString: "queue directory must be specified"},
+		{name: "invalid queue size", cfg: config.WebhookAsync{QueueDir: t.TempDir(), MaxQueueSize: -1}, errString: "invalid maximum queue size"},
+		{name: "invalid workers", cfg: config.WebhookAsync{QueueDir: t.TempDir(), Workers: -1}, errString: "invalid number of workers"},
+		{name: "creates queue directory", cfg: config.WebhookAsync{QueueDir: filepath.Join(t.TempDir(), "a", "b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := newQueue(&tt.cfg, log)
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, cap(q.items), defaultQueueSize)
+			assert.Equal(t, q.workers, defaultQueueWorkers)
+
+			_, err = os.Stat(tt.cfg.QueueDir)
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func Test_queue_enqueue(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	t.Run("persists events and rejects when full", func(t *testing.T) {
+		dir := t.TempDir()
+		q, err := newQueue(&config.WebhookAsync{QueueDir: dir, MaxQueueSize: 2}, log)
+		assert.NilError(t, err)
+
+		assert.NilError(t, q.enqueue(newTestEvent(t, "1")))
+		assert.NilError(t, q.enqueue(newTestEvent(t, "2")))
+		assert.Equal(t, q.enqueue(newTestEvent(t, "3")), ErrQueueFull)
+
+		assert.DeepEqual(t, queueFiles(t, dir), []string{"00000000000000000001.json", "00000000000000000002.json"})
+
+		d, n := q.delivery("2", "")
+		assert.Equal(t, n, 1)
+		assert.Equal(t, d.Status, statusQueued)
+
+		_, n = q.delivery("3", "")
+		assert.Equal(t, n, 0)
+	})
+
+	t.Run("concurrent enqueues do not exceed queue size", func(t *testing.T) {
+		dir := t.TempDir()
+		q, err := newQueue(&config.WebhookAsync{QueueDir: dir, MaxQueueSize: 5}, log)
+		assert.NilError(t, err)
+
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			full int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				err := q.enqueue(newTestEvent(t, id))
+				if err == ErrQueueFull {
+					mu.Lock()
+					full++
+					mu.Unlock()
+					return
+				}
+				assert.NilError(t, err)
+			}(strconv.Itoa(i))
+		}
+		wg.Wait()
+
+		assert.Equal(t, full, 15)
+		assert.Equal(t, len(q.items), 5)
+		assert.Equal(t, len(queueFiles(t, dir)), 5)
+	})
+
+	t.Run("loads pending events in order after restart", func(t *testing.T) {
+		dir := t.TempDir()
+		q, err := newQueue(&config.WebhookAsync{QueueDir: dir}, log)
+		assert.NilError(t, err)
+
+		for _, id := range []string{"1", "2", "3"} {
+			assert.NilError(t, q.enqueue(newTestEvent(t, id)))
+		}
+
+		// incomplete and invalid files
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000004.json.tmp"), []byte("{"), 0600))
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000005.json"), []byte("{"), 0600))
+
+		restarted, err := newQueue(&config.WebhookAsync{QueueDir: dir, MaxQueueSize: 1}, log)
+		assert.NilError(t, err)
+		assert.Equal(t, len(restarted.items), 3)
+
+		for _, id := range []string{"1", "2", "3"} {
+			item := <-restarted.items
+			assert.Equal(t, item.event.ID(), id)
+
+			d, n := restarted.delivery(id, "")
+			assert.Equal(t, n, 1)
+			assert.Equal(t, d.Status, statusQueued)
+		}
+
+		assert.DeepEqual(t, queueFiles(t, dir), []string{
+			"00000000000000000001.json",
+			"00000000000000000002.json",
+			"00000000000000000003.json",
+			"00000000000000000005.json.invalid",
+		})
+
+		// sequence continues after pending events
+		assert.NilError(t, restarted.enqueue(newTestEvent(t, "4")))
+		item := <-restarted.items
+		assert.Equal(t, filepath.Base(item.file), "00000000000000000004.json")
+	})
+}
+
+func Test_queue_run(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+	dir := t.TempDir()
+
+	q, err := newQueue(&config.WebhookAsync{QueueDir: dir}, log)
+	assert.NilError(t, err)
+
+	for _, id := range []string{"ok", "fail", "block"} {
+		assert.NilError(t, q.enqueue(newTestEvent(t, id)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.run(ctx, func(ctx context.Context, e ce.Event) error {
+			mu.Lock()
+			processed = append(processed, e.ID())
+			mu.Unlock()
+
+			switch e.ID() {
+			case "fail":
+				return errors.New("function failed")
+			case "block":
+				// simulate slow processing interrupted by shutdown
+				cancel()
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for queue workers")
+	}
+
+	assert.DeepEqual(t, processed, []string{"ok", "fail", "block"})
+
+	d, _ := q.delivery("ok", "")
+	assert.Equal(t, d.Status, statusDelivered)
+
+	d, _ = q.delivery("fail", "")
+	assert.Equal(t, d.Status, statusFailed)
+	assert.Equal(t, d.Error, "function failed")
+
+	// interrupted event remains queued for processing after restart
+	d, _ = q.delivery("block", "")
+	assert.Equal(t, d.Status, statusQueued)
+	assert.DeepEqual(t, queueFiles(t, dir), []string{"00000000000000000003.json"})
+}
+
+func Test_queue_statusEviction(t *testing.T) {
+	q, err := newQueue(&config.WebhookAsync{QueueDir: t.TempDir()}, zaptest.NewLogger(t).Sugar())
+	assert.NilError(t, err)
+
+	for i := 0; i < maxStatusEntries+1; i++ {
+		e := ce.NewEvent()
+		e.SetID(strconv.Itoa(i))
+		q.setStatus(e, statusDelivered, nil)
+	}
+
+	assert.Equal(t, len(q.status), maxStatusEntries)
+	assert.Equal(t, len(q.order), maxStatusEntries)
+	_, n := q.delivery("0", "")
+	assert.Equal(t, n, 0, "oldest entry should be evicted")
+	_, n = q.delivery(strconv.Itoa(maxStatusEntries), "")
+	assert.Equal(t, n, 1)
+}
+
+func Test_queue_statusPerSource(t *testing.T) {
+	q, err := newQueue(&config.WebhookAsync{QueueDir: t.TempDir()}, zaptest.NewLogger(t).Sugar())
+	assert.NilError(t, err)
+
+	a := newTestEvent(t, "1")
+	b := newTestEvent(t, "1")
+	b.SetSource("https://other.example.com")
+
+	q.setStatus(a, statusDelivered, nil)
+	q.setStatus(b, statusFailed, errors.New("failed"))
+
+	d, n := q.delivery("1", "https://example.com")
+	assert.Equal(t, n, 1)
+	assert.Equal(t, d.Status, statusDelivered)
+
+	d, n = q.delivery("1", "https://other.example.com")
+	assert.Equal(t, n, 1)
+	assert.Equal(t, d.Status, statusFailed)
+
+	_, n = q.delivery("1", "")
+	assert.Equal(t, n, 2)
+
+	_, n = q.delivery("1", "https://unknown.example.com")
+	assert.Equal(t, n, 0)
+}
+
+func Test_Server_statusHandler(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+	q, err := newQueue(&config.WebhookAsync{QueueDir: t.TempDir()}, log)
+	assert.NilError(t, err)
+	assert.NilError(t, q.enqueue(newTestEvent(t, "abc-123")))
+
+	// same ID from different sources
+	for _, source := range []string{"https://a.example.com", "https://b.example.com"} {
+		e := newTestEvent(t, "dup")
+		e.SetSource(source)
+		assert.NilError(t, q.enqueue(e))
+	}
+
+	s := Server{Logger: log}
+	h := s.statusHandler("/webhook/status", q)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "known event", method: http.MethodGet, path: "/webhook/status/abc-123", wantCode: http.StatusOK},
+		{name: "known event with source", method: http.MethodGet, path: "/webhook/status/abc-123?source=https://example.com", wantCode: http.StatusOK},
+		{name: "unknown event", method: http.MethodGet, path: "/webhook/status/unknown", wantCode: http.StatusNotFound},
+		{name: "unknown source", method: http.MethodGet, path: "/webhook/status/abc-123?source=https://b.example.com", wantCode: http.StatusNotFound},
+		{name: "ambiguous event ID", method: http.MethodGet, path: "/webhook/status/dup", wantCode: http.StatusConflict},
+		{name: "no event ID", method: http.MethodGet, path: "/webhook/status/", wantCode: http.StatusBadRequest},
+		{name: "invalid method", method: http.MethodPost, path: "/webhook/status/abc-123", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, rec.Code, tt.wantCode)
+
+			if tt.wantCode == http.StatusOK {
+				var d delivery
+				assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &d))
+				assert.Equal(t, d.ID, "abc-123")
+				assert.Equal(t, d.Source, "https://example.com")
+				assert.Equal(t, d.Status, statusQueued)
+			}
+		})
+	}
+}
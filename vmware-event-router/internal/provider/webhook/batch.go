@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+)
+
+const (
+	// batch result status
+	batchAccepted = "accepted"
+	batchFailed   = "failed"
+)
+
+// batchResult is the processing result of a single event in a batch
+type batchResult struct {
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// batchResponse is the response body for a batch request
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// isBatch returns true if the request uses the CloudEvents batch content mode
+func isBatch(r *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mt == ce.ApplicationCloudEventsBatchJSON
+}
+
+// withBatch handles POST requests in CloudEvents batch content mode for the
+// given path as a middleware. All other requests are passed to next.
+func withBatch(next http.Handler, path string, batch http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == path && isBatch(r) {
+			batch.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// batchHandler returns an http handler processing the events of a batch in
+// order using the given process function. The result of every event is
+// reported in the response body. If any event fails, status 207 (Multi-Status)
+// is returned.
+func (s *Server) batchHandler(process func(r *http.Request, e ce.Event) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			http.Error(w, fmt.Sprintf("could not decode cloud event batch: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		s.Debugw("received cloud event batch", "events", len(raw))
+
+		resp := batchResponse{Results: make([]batchResult, 0, len(raw))}
+		failed := false
+
+		for _, b := range raw {
+			res := s.processBatchEvent(r, b, process)
+			if res.Status == batchFailed {
+				failed = true
+			}
+			resp.Results = append(resp.Results, res)
+		}
+
+		code := s.acceptedStatus()
+		if failed {
+			code = http.StatusMultiStatus
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.Errorw("could not write batch response", "error", err)
+		}
+	})
+}
+
+// processBatchEvent decodes, validates and processes a single event of a batch
+func (s *Server) processBatchEvent(r *http.Request, b json.RawMessage, process func(r *http.Request, e ce.Event) error) batchResult {
+	var e ce.Event
+	if err := json.Unmarshal(b, &e); err != nil {
+		return batchResult{ID: batchEventID(b), Status: batchFailed, Reason: fmt.Sprintf("invalid cloud event: %v", err)}
+	}
+
+	if err := e.Validate(); err != nil {
+		return batchResult{ID: e.ID(), Status: batchFailed, Reason: fmt.Sprintf("invalid cloud event: %v", err)}
+	}
+
+	if err := process(r, e); err != nil {
+		return batchResult{ID: e.ID(), Status: batchFailed, Reason: err.Error()}
+	}
+
+	return batchResult{ID: e.ID(), Status: batchAccepted}
+}
+
+// batchEventID returns the id of a raw (invalid) event if it can be retrieved
+func batchEventID(b json.RawMessage) string {
+	var e struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(b, &e)
+	return e.ID
+}
@@ -0,0 +1,150 @@
+//go:build unit
+// +build unit
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+)
+
+func Test_isBatch(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "application/cloudevents-batch+json", want: true},
+		{contentType: "application/cloudevents-batch+json; charset=utf-8", want: true},
+		{contentType: "application/cloudevents+json", want: false},
+		{contentType: "application/json", want: false},
+		{contentType: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+			r.Header.Set("Content-Type", tt.contentType)
+			assert.Equal(t, isBatch(r), tt.want)
+		})
+	}
+}
+
+func Test_withBatch(t *testing.T) {
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = "next" })
+	batch := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = "batch" })
+	h := withBatch(next, "/webhook", batch)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		want        string
+	}{
+		{name: "batch request", method: http.MethodPost, path: "/webhook", contentType: ce.ApplicationCloudEventsBatchJSON, want: "batch"},
+		{name: "structured request", method: http.MethodPost, path: "/webhook", contentType: ce.ApplicationCloudEventsJSON, want: "next"},
+		{name: "other path", method: http.MethodPost, path: "/other", contentType: ce.ApplicationCloudEventsBatchJSON, want: "next"},
+		{name: "options request", method: http.MethodOptions, path: "/webhook", contentType: ce.ApplicationCloudEventsBatchJSON, want: "next"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Content-Type", tt.contentType)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func Test_Server_batchHandler(t *testing.T) {
+	event := func(id string) string {
+		return `{"specversion":"1.0","id":"` + id + `","source":"https://example.com","type":"com.example.test","data":{"n":"` + id + `"}}`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    int
+		wantResults []batchResult
+		wantOrder   []string
+	}{
+		{
+			name:     "all events accepted in order",
+			body:     "[" + event("1") + "," + event("2") + "," + event("3") + "]",
+			wantCode: http.StatusOK,
+			wantResults: []batchResult{
+				{ID: "1", Status: batchAccepted},
+				{ID: "2", Status: batchAccepted},
+				{ID: "3", Status: batchAccepted},
+			},
+			wantOrder: []string{"1", "2", "3"},
+		},
+		{
+			name:     "invalid and failed events are reported",
+			body:     "[" + event("1") + `,{"specversion":"1.0","id":"2"},` + event("fail") + `,"not an event",` + event("4") + "]",
+			wantCode: http.StatusMultiStatus,
+			wantResults: []batchResult{
+				{ID: "1", Status: batchAccepted},
+				{ID: "2", Status: batchFailed, Reason: "invalid cloud event"},
+				{ID: "fail", Status: batchFailed, Reason: "processing failed"},
+				{Status: batchFailed, Reason: "invalid cloud event"},
+				{ID: "4", Status: batchAccepted},
+			},
+			wantOrder: []string{"1", "fail", "4"},
+		},
+		{
+			name:        "empty batch",
+			body:        "[]",
+			wantCode:    http.StatusOK,
+			wantResults: []batchResult{},
+		},
+		{
+			name:     "invalid batch",
+			body:     event("1"),
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Server{Logger: zaptest.NewLogger(t).Sugar()}
+
+			var order []string
+			h := s.batchHandler(func(r *http.Request, e ce.Event) error {
+				order = append(order, e.ID())
+				if e.ID() == "fail" {
+					return errors.New("processing failed")
+				}
+				return nil
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", ce.ApplicationCloudEventsBatchJSON)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			assert.Equal(t, rec.Code, tt.wantCode)
+			if tt.wantResults == nil {
+				return
+			}
+
+			var resp batchResponse
+			assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, len(resp.Results), len(tt.wantResults))
+			for i, want := range tt.wantResults {
+				got := resp.Results[i]
+				assert.Equal(t, got.ID, want.ID)
+				assert.Equal(t, got.Status, want.Status)
+				assert.Assert(t, strings.Contains(got.Reason, want.Reason), "reason %q does not contain %q", got.Reason, want.Reason)
+			}
+			assert.DeepEqual(t, order, tt.wantOrder)
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+const (
+	// interval to remove idle client limiters
+	limiterSweepInterval = time.Minute
+	// minimum time a client limiter must be idle before it is removed
+	limiterIdleTimeout = 3 * time.Minute
+)
+
+// clientLimiter is a token bucket rate limiter per client
+type clientLimiter struct {
+	limit   rate.Limit
+	burst   int
+	idle    time.Duration
+	now     func() time.Time
+	proxies []*net.IPNet // trusted reverse proxies
+	// user returns the authenticated user of a request (optional)
+	user func(r *http.Request) (string, bool)
+
+	mu        sync.Mutex
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+// clientBucket is the token bucket of a single client
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newClientLimiter returns a rate limiter per client for the given configuration
+func newClientLimiter(cfg *config.WebhookRateLimit) (*clientLimiter, error) {
+	if cfg == nil || cfg.RequestsPerSecond <= 0 {
+		return nil, errors.New("invalid rate limit: requestsPerSecond must be greater than 0")
+	}
+
+	if cfg.Burst < 0 {
+		return nil, errors.Errorf("invalid rate limit: invalid burst %d", cfg.Burst)
+	}
+
+	burst := cfg.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(cfg.RequestsPerSecond))
+	}
+
+	// idle limiters are removed only after their bucket has been refilled so
+	// removal does not grant additional requests
+	idle := time.Duration(float64(burst) / cfg.RequestsPerSecond * float64(time.Second))
+	if idle < limiterIdleTimeout {
+		idle = limiterIdleTimeout
+	}
+
+	proxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid rate limit")
+	}
+
+	return &clientLimiter{
+		limit:   rate.Limit(cfg.RequestsPerSecond),
+		burst:   burst,
+		idle:    idle,
+		now:     time.Now,
+		proxies: proxies,
+		clients: make(map[string]*clientBucket),
+	}, nil
+}
+
+// parseTrustedProxies returns the networks of the given IP addresses and CIDR
+// ranges
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy %q", p)
+			}
+
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			p = fmt.Sprintf("%s/%d", p, bits)
+		}
+
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.Errorf("invalid trusted proxy %q", p)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// allow reports whether a request from the given client is allowed. If not,
+// the duration after which the client may retry is returned.
+func (l *clientLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.clients[client]
+	if !ok {
+		b = &clientBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+
+	if delay := r.DelayFrom(now); delay > 0 {
+		// do not consume a token for rejected requests
+		r.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep removes idle client limiters. Must be called with the lock held.
+func (l *clientLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for c, b := range l.clients {
+		if now.Sub(b.lastSeen) > l.idle {
+			delete(l.clients, c)
+		}
+	}
+}
+
+// clientKey returns the rate limiting key for the given request. Requests are
+// limited before authentication so only requests with valid credentials are
+// identified by their user. Other requests are identified by the client IP
+// address which is taken from the X-Forwarded-For or X-Real-IP headers if the
+// request was sent by a trusted proxy.
+func (l *clientLimiter) clientKey(r *http.Request) string {
+	if l.user != nil {
+		if user, ok := l.user(r); ok {
+			return "user:" + user
+		}
+	}
+
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP returns the IP address of the client which sent the given request
+func (l *clientLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !l.trusted(host) {
+		return host
+	}
+
+	// proxies append the address they received the request from, i.e. the
+	// client is the last address not belonging to a trusted proxy
+	var forwarded []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(h, ",")...)
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+
+		host = ip
+		if !l.trusted(ip) {
+			return ip
+		}
+	}
+
+	if len(forwarded) == 0 {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+
+	return host
+}
+
+// trusted reports whether the given IP address belongs to a trusted proxy
+func (l *clientLimiter) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range l.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRateLimit enforces the given client rate limiter as a middleware.
+// Rejected requests are answered with 429 and a Retry-After header and reported
+// via onReject.
+func withRateLimit(next http.Handler, l *clientLimiter, onReject func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// do not limit CORS/abuse protection handshakes
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, retry := l.allow(l.clientKey(r))
+		if !ok {
+			onReject()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withMaxBodySize rejects requests with a body larger than max bytes with 413
+// as a middleware. Rejected requests are reported via onReject.
+func withMaxBodySize(next http.Handler, max int64, onReject func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			onReject()
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// content length might be unknown (chunked encoding)
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, max+1))
+		if err != nil {
+			http.Error(w, "could not read request body", http.StatusBadRequest)
+			return
+		}
+		_ = r.Body.Close()
+
+		if int64(len(body)) > max {
+			onReject()
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,236 @@
+//go:build unit
+// +build unit
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+)
+
+func Test_newClientLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.WebhookRateLimit
+		wantBurst int
+		errString string
+	}{
+		{name: "no configuration", cfg: nil, errString: "requestsPerSecond must be greater than 0"},
+		{name: "zero rate", cfg: &config.WebhookRateLimit{}, errString: "requestsPerSecond must be greater than 0"},
+		{name: "negative burst", cfg: &config.WebhookRateLimit{RequestsPerSecond: 1, Burst: -1}, errString: "invalid burst"},
+		{name: "default burst", cfg: &config.WebhookRateLimit{RequestsPerSecond: 2.5}, wantBurst: 3},
+		{name: "custom burst", cfg: &config.WebhookRateLimit{RequestsPerSecond: 1, Burst: 10}, wantBurst: 10},
+		{name: "invalid trusted proxy", cfg: &config.WebhookRateLimit{RequestsPerSecond: 1, TrustedProxies: []string{"envoy"}}, errString: `invalid trusted proxy "envoy"`},
+		{name: "invalid trusted proxy range", cfg: &config.WebhookRateLimit{RequestsPerSecond: 1, TrustedProxies: []string{"10.0.0.0/33"}}, errString: "invalid trusted proxy"},
+		{name: "trusted proxies", cfg: &config.WebhookRateLimit{RequestsPerSecond: 1, TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "::1"}}, wantBurst: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newClientLimiter(tt.cfg)
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, l.burst, tt.wantBurst)
+		})
+	}
+}
+
+func Test_clientLimiter_allow(t *testing.T) {
+	now := time.Date(2021, 8, 17, 12, 0, 0, 0, time.UTC)
+
+	l, err := newClientLimiter(&config.WebhookRateLimit{RequestsPerSecond: 1, Burst: 2})
+	assert.NilError(t, err)
+	l.now = func() time.Time { return now }
+
+	t.Run("allows burst per client", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			ok, _ := l.allow("client-a")
+			assert.Assert(t, ok, "request %d", i)
+		}
+
+		ok, retry := l.allow("client-a")
+		assert.Assert(t, !ok)
+		assert.Equal(t, retry, time.Second)
+
+		// other clients are not affected
+		ok, _ = l.allow("client-b")
+		assert.Assert(t, ok)
+	})
+
+	t.Run("rejected requests do not consume tokens", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			ok, _ := l.allow("client-a")
+			assert.Assert(t, !ok)
+		}
+
+		now = now.Add(time.Second)
+		ok, _ := l.allow("client-a")
+		assert.Assert(t, ok)
+	})
+
+	t.Run("removes idle clients", func(t *testing.T) {
+		assert.Equal(t, len(l.clients), 2)
+
+		now = now.Add(limiterIdleTimeout + limiterSweepInterval)
+		ok, _ := l.allow("client-c")
+		assert.Assert(t, ok)
+		assert.Equal(t, len(l.clients), 1)
+	})
+}
+
+func Test_clientLimiter_clientKey(t *testing.T) {
+	newRequest := func(remote string, header http.Header) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+		r.RemoteAddr = remote
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		return r
+	}
+
+	t.Run("identifies clients by IP address", func(t *testing.T) {
+		l, err := newClientLimiter(&config.WebhookRateLimit{RequestsPerSecond: 1})
+		assert.NilError(t, err)
+
+		r := newRequest("10.0.0.1:51234", nil)
+		assert.Equal(t, l.clientKey(r), "ip:10.0.0.1")
+
+		// credentials are ignored if authentication is disabled
+		r.SetBasicAuth("user", "pass")
+		assert.Equal(t, l.clientKey(r), "ip:10.0.0.1")
+	})
+
+	t.Run("identifies authenticated clients by user", func(t *testing.T) {
+		l, err := newClientLimiter(&config.WebhookRateLimit{RequestsPerSecond: 1})
+		assert.NilError(t, err)
+		l.user = func(r *http.Request) (string, bool) {
+			return basicAuthUser(r, "user", "pass")
+		}
+
+		r := newRequest("10.0.0.1:51234", nil)
+		r.SetBasicAuth("user", "pass")
+		assert.Equal(t, l.clientKey(r), "user:user")
+
+		// invalid credentials are not trusted
+		r.SetBasicAuth("user", "wrong")
+		assert.Equal(t, l.clientKey(r), "ip:10.0.0.1")
+	})
+
+	t.Run("uses forwarded client IP address from trusted proxies", func(t *testing.T) {
+		l, err := newClientLimiter(&config.WebhookRateLimit{
+			RequestsPerSecond: 1,
+			TrustedProxies:    []string{"10.244.0.0/16", "192.168.1.1"},
+		})
+		assert.NilError(t, err)
+
+		tests := []struct {
+			name   string
+			remote string
+			header http.Header
+			want   string
+		}{
+			{name: "untrusted remote", remote: "10.0.0.1:51234", header: http.Header{"X-Forwarded-For": {"1.2.3.4"}}, want: "ip:10.0.0.1"},
+			{name: "trusted remote without headers", remote: "10.244.1.5:51234", want: "ip:10.244.1.5"},
+			{name: "forwarded for", remote: "10.244.1.5:51234", header: http.Header{"X-Forwarded-For": {"1.2.3.4"}}, want: "ip:1.2.3.4"},
+			{name: "spoofed forwarded for", remote: "10.244.1.5:51234", header: http.Header{"X-Forwarded-For": {"5.6.7.8, 1.2.3.4"}}, want: "ip:1.2.3.4"},
+			{name: "chained trusted proxies", remote: "10.244.1.5:51234", header: http.Header{"X-Forwarded-For": {"1.2.3.4, 192.168.1.1"}}, want: "ip:1.2.3.4"},
+			{name: "multiple forwarded for headers", remote: "10.244.1.5:51234", header: http.Header{"X-Forwarded-For": {"1.2.3.4", "192.168.1.1"}}, want: "ip:1.2.3.4"},
+			{name: "invalid forwarded for", remote: "10.244.1.5:51234", header: http.Header{"X-Forwarded-For": {"unknown"}}, want: "ip:10.244.1.5"},
+			{name: "real ip", remote: "10.244.1.5:51234", header: http.Header{"X-Real-Ip": {"1.2.3.4"}}, want: "ip:1.2.3.4"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.Equal(t, l.clientKey(newRequest(tt.remote, tt.header)), tt.want)
+			})
+		}
+	})
+}
+
+func Test_withRateLimit(t *testing.T) {
+	l, err := newClientLimiter(&config.WebhookRateLimit{RequestsPerSecond: 0.5, Burst: 1})
+	assert.NilError(t, err)
+
+	rejected := 0
+	h := withRateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), l, func() { rejected++ })
+
+	send := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/webhook", nil))
+		return rec
+	}
+
+	assert.Equal(t, send(http.MethodPost).Code, http.StatusOK)
+
+	rec := send(http.MethodPost)
+	assert.Equal(t, rec.Code, http.StatusTooManyRequests)
+	assert.Equal(t, rec.Header().Get("Retry-After"), "2")
+	assert.Equal(t, rejected, 1)
+
+	// OPTIONS requests are not limited
+	assert.Equal(t, send(http.MethodOptions).Code, http.StatusOK)
+}
+
+func Test_withMaxBodySize(t *testing.T) {
+	const max = 10
+
+	tests := []struct {
+		name         string
+		body         string
+		chunked      bool
+		wantCode     int
+		wantRejected int
+	}{
+		{name: "body within limit", body: "0123456789", wantCode: http.StatusOK},
+		{name: "body exceeds limit", body: "0123456789a", wantCode: http.StatusRequestEntityTooLarge, wantRejected: 1},
+		{name: "chunked body within limit", body: "0123456789", chunked: true, wantCode: http.StatusOK},
+		{name: "chunked body exceeds limit", body: "0123456789a", chunked: true, wantCode: http.StatusRequestEntityTooLarge, wantRejected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejected := 0
+			var got string
+			h := withMaxBodySize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NilError(t, err)
+				got = string(b)
+			}), max, func() { rejected++ })
+
+			r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(tt.body))
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			assert.Equal(t, rec.Code, tt.wantCode)
+			assert.Equal(t, rejected, tt.wantRejected)
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, got, tt.body)
+			}
+		})
+	}
+}
+
+func Test_Server_rejected(t *testing.T) {
+	s := Server{stats: metrics.EventStats{Rejected: new(metrics.RejectionDetails)}}
+
+	s.rejected(func(r *metrics.RejectionDetails) { r.RateLimited++ })()
+	s.rejected(func(r *metrics.RejectionDetails) { r.RateLimited++ })()
+	s.rejected(func(r *metrics.RejectionDetails) { r.BodyTooLarge++ })()
+
+	assert.DeepEqual(t, *s.stats.Rejected, metrics.RejectionDetails{RateLimited: 2, BodyTooLarge: 1})
+}
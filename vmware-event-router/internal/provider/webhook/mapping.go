@@ -0,0 +1,359 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+var (
+	// ErrInvalidMapping is returned on an invalid JSON mapping configuration
+	ErrInvalidMapping = errors.New("invalid JSON mapping")
+)
+
+// jsonPayload is a decoded JSON request payload
+type jsonPayload struct {
+	raw   []byte
+	value interface{}
+}
+
+// mapper converts arbitrary JSON payloads into CloudEvents using mapping rules
+type mapper struct {
+	typ     config.WebhookMappingRule
+	source  *config.WebhookMappingRule
+	subject *config.WebhookMappingRule
+	id      *config.WebhookMappingRule
+	time    *config.WebhookMappingRule
+	now     func() time.Time
+}
+
+// newMapper returns a mapper for the given configuration
+func newMapper(cfg *config.WebhookJSONMapping) (*mapper, error) {
+	if cfg == nil {
+		return nil, errors.Wrap(ErrInvalidMapping, "no mapping configuration specified")
+	}
+
+	if isEmptyRule(&cfg.Type) {
+		return nil, errors.Wrap(ErrInvalidMapping, "type mapping rule must be specified")
+	}
+
+	rules := map[string]*config.WebhookMappingRule{
+		"type":    &cfg.Type,
+		"source":  cfg.Source,
+		"subject": cfg.Subject,
+		"id":      cfg.ID,
+		"time":    cfg.Time,
+	}
+
+	for name, r := range rules {
+		if r == nil || r.JSONPath == "" {
+			continue
+		}
+
+		if _, err := parseJSONPath(r.JSONPath); err != nil {
+			return nil, errors.Wrapf(ErrInvalidMapping, "%s: %v", name, err)
+		}
+	}
+
+	return &mapper{
+		typ:     cfg.Type,
+		source:  cfg.Source,
+		subject: cfg.Subject,
+		id:      cfg.ID,
+		time:    cfg.Time,
+		now:     time.Now,
+	}, nil
+}
+
+// isEmptyRule returns true if the given rule does not specify any value source
+func isEmptyRule(r *config.WebhookMappingRule) bool {
+	return r.Header == "" && r.JSONPath == "" && r.Value == ""
+}
+
+// toEvent converts the given JSON request into a CloudEvent. The JSON payload
+// is used as CloudEvent data.
+func (m *mapper) toEvent(r *http.Request, body []byte) (*ce.Event, error) {
+	p, err := decodePayload(body)
+	if err != nil {
+		return nil, err
+	}
+
+	typ, err := m.resolve(r, p, &m.typ)
+	if err != nil {
+		return nil, errors.Wrap(err, "type")
+	}
+
+	if typ == "" {
+		return nil, errors.New("type: no value found")
+	}
+
+	source, err := m.resolve(r, p, m.source)
+	if err != nil {
+		return nil, errors.Wrap(err, "source")
+	}
+
+	if source == "" {
+		source = requestURL(r)
+	}
+
+	subject, err := m.resolve(r, p, m.subject)
+	if err != nil {
+		return nil, errors.Wrap(err, "subject")
+	}
+
+	id, err := m.resolve(r, p, m.id)
+	if err != nil {
+		return nil, errors.Wrap(err, "id")
+	}
+
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	t := m.now().UTC()
+	ts, err := m.resolve(r, p, m.time)
+	if err != nil {
+		return nil, errors.Wrap(err, "time")
+	}
+
+	if ts != "" {
+		if t, err = parseEventTime(ts); err != nil {
+			return nil, errors.Wrap(err, "time")
+		}
+	}
+
+	e := ce.NewEvent()
+	e.SetID(id)
+	e.SetSource(source)
+	e.SetType(typ)
+	e.SetTime(t)
+
+	if subject != "" {
+		e.SetSubject(subject)
+	}
+
+	if err = e.SetData(ce.ApplicationJSON, json.RawMessage(p.raw)); err != nil {
+		return nil, errors.Wrap(err, "set data")
+	}
+
+	if err = e.Validate(); err != nil {
+		return nil, errors.Wrap(err, "validate event")
+	}
+
+	return &e, nil
+}
+
+// resolve returns the value for the given rule. An empty string is returned if
+// the rule is nil or no value was found.
+func (m *mapper) resolve(r *http.Request, p *jsonPayload, rule *config.WebhookMappingRule) (string, error) {
+	if rule == nil {
+		return "", nil
+	}
+
+	if rule.Header != "" {
+		if v := r.Header.Get(rule.Header); v != "" {
+			return v, nil
+		}
+	}
+
+	if rule.JSONPath != "" {
+		v, err := lookupJSONPath(p.value, rule.JSONPath)
+		if err != nil {
+			return "", err
+		}
+
+		if v != "" {
+			return v, nil
+		}
+	}
+
+	return rule.Value, nil
+}
+
+// decodePayload decodes the given JSON body
+func decodePayload(body []byte) (*jsonPayload, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "decode JSON payload")
+	}
+
+	if dec.More() {
+		return nil, errors.New("decode JSON payload: unexpected data after JSON value")
+	}
+
+	return &jsonPayload{raw: body, value: v}, nil
+}
+
+// jsonPathSegment is a single object key or array index in a JSON path
+type jsonPathSegment struct {
+	key   string
+	index int // -1 if segment is an object key
+}
+
+// parseJSONPath parses a simple JSON path in dot notation with optional array
+// indices, e.g. "$.alerts[0].labels.severity" or "alerts.0.labels.severity"
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, errors.New("empty JSON path")
+	}
+
+	var segments []jsonPathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indices []string
+
+		if i := strings.Index(part, "["); i >= 0 {
+			key = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if !strings.HasPrefix(rest, "[") || end < 0 {
+					return nil, fmt.Errorf("invalid JSON path %q", path)
+				}
+				indices = append(indices, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		if key == "" && len(indices) == 0 {
+			return nil, fmt.Errorf("invalid JSON path %q: empty segment", path)
+		}
+
+		if key != "" {
+			if idx, err := strconv.Atoi(key); err == nil {
+				segments = append(segments, jsonPathSegment{key: key, index: idx})
+			} else {
+				segments = append(segments, jsonPathSegment{key: key, index: -1})
+			}
+		}
+
+		for _, idx := range indices {
+			n, err := strconv.Atoi(idx)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: invalid array index %q", path, idx)
+			}
+			segments = append(segments, jsonPathSegment{index: n})
+		}
+	}
+
+	return segments, nil
+}
+
+// lookupJSONPath returns the string representation of the scalar value at the
+// given path. An empty string is returned if the path does not exist.
+func lookupJSONPath(v interface{}, path string) (string, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, s := range segments {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if s.key == "" {
+				return "", nil
+			}
+			v = val[s.key]
+		case []interface{}:
+			if s.index < 0 || s.index >= len(val) {
+				return "", nil
+			}
+			v = val[s.index]
+		default:
+			return "", nil
+		}
+	}
+
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	default:
+		return "", fmt.Errorf("value at JSON path %q is not a scalar", path)
+	}
+}
+
+// parseEventTime parses an RFC3339 timestamp or a unix timestamp in seconds or
+// milliseconds
+func parseEventTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// heuristic: values beyond year 33658 in seconds are interpreted as milliseconds
+		if n > 1e12 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+
+	t, err := types.ParseTime(s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid timestamp %q", s)
+	}
+
+	return t.UTC(), nil
+}
+
+// requestURL returns the URL of the given request
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path)
+}
+
+// jsonHandler returns an http handler converting incoming JSON requests into
+// CloudEvents which are passed to the given process function
+func (s *Server) jsonHandler(m *mapper, process func(r *http.Request, e ce.Event) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "could not read request body", http.StatusBadRequest)
+			return
+		}
+
+		e, err := m.toEvent(r, body)
+		if err != nil {
+			s.Debugw("could not map JSON payload to cloud event", "remote", r.RemoteAddr, "error", err)
+			http.Error(w, fmt.Sprintf("could not map JSON payload to cloud event: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if err = process(r, *e); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrQueueFull) {
+				code = http.StatusServiceUnavailable
+			}
+			http.Error(w, fmt.Sprintf("could not process event: %v", err), code)
+			return
+		}
+
+		w.WriteHeader(s.acceptedStatus())
+	})
+}
@@ -0,0 +1,255 @@
+//go:build unit
+// +build unit
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+const alertPayload = `{
+  "receiver": "veba",
+  "status": "firing",
+  "alerts": [
+    {
+      "fingerprint": "c0ffee",
+      "startsAt": "2021-08-17T12:00:00Z",
+      "labels": {"alertname": "HighCPU", "instance": "esx-01.corp.local", "severity": 3}
+    }
+  ],
+  "resolved": false
+}`
+
+func Test_newMapper(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.WebhookJSONMapping
+		errString string
+	}{
+		{name: "no configuration", cfg: nil, errString: "no mapping configuration specified"},
+		{name: "no type rule", cfg: &config.WebhookJSONMapping{Path: "/json"}, errString: "type mapping rule must be specified"},
+		{
+			name: "invalid JSON path",
+			cfg: &config.WebhookJSONMapping{
+				Type:    config.WebhookMappingRule{Value: "alert"},
+				Subject: &config.WebhookMappingRule{JSONPath: "alerts[x]"},
+			},
+			errString: "subject: invalid JSON path",
+		},
+		{name: "valid configuration", cfg: &config.WebhookJSONMapping{Type: config.WebhookMappingRule{JSONPath: "$.status"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newMapper(tt.cfg)
+			if tt.errString == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errString)
+			assert.ErrorContains(t, err, ErrInvalidMapping.Error())
+		})
+	}
+}
+
+func Test_mapper_toEvent(t *testing.T) {
+	now := time.Date(2021, 8, 17, 13, 0, 0, 0, time.UTC)
+
+	type want struct {
+		id      string
+		source  string
+		typ     string
+		subject string
+		time    time.Time
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.WebhookJSONMapping
+		headers   map[string]string
+		body      string
+		want      want
+		errString string
+	}{
+		{
+			name: "all attributes from JSON paths",
+			cfg: config.WebhookJSONMapping{
+				Type:    config.WebhookMappingRule{JSONPath: "$.alerts[0].labels.alertname"},
+				Source:  &config.WebhookMappingRule{JSONPath: "receiver"},
+				Subject: &config.WebhookMappingRule{JSONPath: "alerts.0.labels.instance"},
+				ID:      &config.WebhookMappingRule{JSONPath: "alerts[0].fingerprint"},
+				Time:    &config.WebhookMappingRule{JSONPath: "alerts[0].startsAt"},
+			},
+			body: alertPayload,
+			want: want{
+				id:      "c0ffee",
+				source:  "veba",
+				typ:     "HighCPU",
+				subject: "esx-01.corp.local",
+				time:    time.Date(2021, 8, 17, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "headers take precedence over JSON paths and defaults are used",
+			cfg: config.WebhookJSONMapping{
+				Type:    config.WebhookMappingRule{Header: "X-Event-Type", JSONPath: "status"},
+				Subject: &config.WebhookMappingRule{JSONPath: "alerts[0].labels.severity"},
+			},
+			headers: map[string]string{"X-Event-Type": "com.example.alert"},
+			body:    alertPayload,
+			want: want{
+				source:  "http://example.com/webhook/json",
+				typ:     "com.example.alert",
+				subject: "3",
+				time:    now,
+			},
+		},
+		{
+			name: "static fallback value for missing header and path",
+			cfg: config.WebhookJSONMapping{
+				Type:    config.WebhookMappingRule{Header: "X-Event-Type", JSONPath: "does.not.exist", Value: "com.example.fallback"},
+				Subject: &config.WebhookMappingRule{JSONPath: "resolved"},
+				Time:    &config.WebhookMappingRule{Value: "1629201600000"},
+			},
+			body: alertPayload,
+			want: want{
+				source:  "http://example.com/webhook/json",
+				typ:     "com.example.fallback",
+				subject: "false",
+				time:    time.Date(2021, 8, 17, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:      "no type value found",
+			cfg:       config.WebhookJSONMapping{Type: config.WebhookMappingRule{JSONPath: "does.not.exist"}},
+			body:      alertPayload,
+			errString: "type: no value found",
+		},
+		{
+			name:      "JSON path to object",
+			cfg:       config.WebhookJSONMapping{Type: config.WebhookMappingRule{JSONPath: "alerts[0].labels"}},
+			body:      alertPayload,
+			errString: "is not a scalar",
+		},
+		{
+			name: "invalid time",
+			cfg: config.WebhookJSONMapping{
+				Type: config.WebhookMappingRule{Value: "alert"},
+				Time: &config.WebhookMappingRule{JSONPath: "status"},
+			},
+			body:      alertPayload,
+			errString: `invalid timestamp "firing"`,
+		},
+		{
+			name:      "invalid JSON",
+			cfg:       config.WebhookJSONMapping{Type: config.WebhookMappingRule{Value: "alert"}},
+			body:      `{"status": `,
+			errString: "decode JSON payload",
+		},
+		{
+			name:      "trailing data after JSON",
+			cfg:       config.WebhookJSONMapping{Type: config.WebhookMappingRule{Value: "alert"}},
+			body:      `{"status": "firing"} {}`,
+			errString: "unexpected data after JSON value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newMapper(&tt.cfg)
+			assert.NilError(t, err)
+			m.now = func() time.Time { return now }
+
+			r := httptest.NewRequest("POST", "http://example.com/webhook/json", strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			e, err := m.toEvent(r, []byte(tt.body))
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+			assert.NilError(t, err)
+
+			if tt.want.id != "" {
+				assert.Equal(t, e.ID(), tt.want.id)
+			} else {
+				assert.Assert(t, e.ID() != "", "expected generated id")
+			}
+			assert.Equal(t, e.Source(), tt.want.source)
+			assert.Equal(t, e.Type(), tt.want.typ)
+			assert.Equal(t, e.Subject(), tt.want.subject)
+			assert.Equal(t, e.Time(), tt.want.time)
+			assert.Equal(t, e.DataContentType(), "application/json")
+
+			var data map[string]interface{}
+			assert.NilError(t, json.Unmarshal(e.Data(), &data))
+			assert.Equal(t, data["status"], "firing")
+		})
+	}
+}
+
+func Test_parseJSONPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		want      []jsonPathSegment
+		errString string
+	}{
+		{name: "dot notation", path: "a.b", want: []jsonPathSegment{{key: "a", index: -1}, {key: "b", index: -1}}},
+		{name: "root prefix", path: "$.a", want: []jsonPathSegment{{key: "a", index: -1}}},
+		{name: "numeric segment", path: "a.1", want: []jsonPathSegment{{key: "a", index: -1}, {key: "1", index: 1}}},
+		{name: "array index", path: "a[0][2].b", want: []jsonPathSegment{{key: "a", index: -1}, {index: 0}, {index: 2}, {key: "b", index: -1}}},
+		{name: "top-level array", path: "$[1]", want: []jsonPathSegment{{index: 1}}},
+		{name: "empty path", path: "$", errString: "empty JSON path"},
+		{name: "empty segment", path: "a..b", errString: "empty segment"},
+		{name: "unterminated index", path: "a[0", errString: "invalid JSON path"},
+		{name: "negative index", path: "a[-1]", errString: "invalid array index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Assert(t, reflect.DeepEqual(got, tt.want), "got %+v, want %+v", got, tt.want)
+		})
+	}
+}
+
+func Test_parseEventTime(t *testing.T) {
+	want := time.Date(2021, 8, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "RFC3339", value: "2021-08-17T12:00:00Z"},
+		{name: "RFC3339 with offset", value: "2021-08-17T14:00:00+02:00"},
+		{name: "unix seconds", value: "1629201600"},
+		{name: "unix milliseconds", value: "1629201600000"},
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEventTime(tt.value)
+			if tt.wantErr {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, got, want)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+const (
+	defaultSignatureHeader    = "X-Signature"
+	defaultSignatureAlgorithm = "sha256"
+	defaultTimestampHeader    = "X-Signature-Timestamp"
+	defaultSignatureTolerance = 5 * time.Minute
+)
+
+var (
+	errMissingSignature = errors.New("missing signature")
+	errInvalidSignature = errors.New("invalid signature")
+	errMissingTimestamp = errors.New("missing timestamp")
+	errExpiredTimestamp = errors.New("timestamp outside of tolerance window")
+)
+
+// signatureVerifier verifies HMAC request signatures
+type signatureVerifier struct {
+	header    string
+	algorithm string
+	hash      func() hash.Hash
+	secret    []byte
+	// empty if replay protection is disabled
+	timestampHeader string
+	tolerance       time.Duration
+	now             func() time.Time
+}
+
+// newSignatureVerifier returns a signature verifier for the given configuration
+func newSignatureVerifier(cfg *config.HMACSignatureAuthMethod) (*signatureVerifier, error) {
+	if cfg == nil || cfg.Secret == "" {
+		return nil, fmt.Errorf("invalid %s details: secret must be set", config.HMACSignatureAuth)
+	}
+
+	v := signatureVerifier{
+		header:    cfg.Header,
+		algorithm: strings.ToLower(cfg.Algorithm),
+		secret:    []byte(cfg.Secret),
+		tolerance: defaultSignatureTolerance,
+		now:       time.Now,
+	}
+
+	if v.header == "" {
+		v.header = defaultSignatureHeader
+	}
+
+	if v.algorithm == "" {
+		v.algorithm = defaultSignatureAlgorithm
+	}
+
+	// without replay protection only the body is signed
+	if !cfg.DisableReplayProtection {
+		v.timestampHeader = cfg.TimestampHeader
+		if v.timestampHeader == "" {
+			v.timestampHeader = defaultTimestampHeader
+		}
+	}
+
+	switch v.algorithm {
+	case "sha256":
+		v.hash = sha256.New
+	case "sha512":
+		v.hash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm: %q", cfg.Algorithm)
+	}
+
+	if cfg.ToleranceSeconds < 0 {
+		return nil, fmt.Errorf("invalid signature timestamp tolerance: %d", cfg.ToleranceSeconds)
+	}
+
+	if cfg.ToleranceSeconds > 0 {
+		v.tolerance = time.Duration(cfg.ToleranceSeconds) * time.Second
+	}
+
+	return &v, nil
+}
+
+// verify verifies the signature of the given request and body
+func (v *signatureVerifier) verify(r *http.Request, body []byte) error {
+	sig := r.Header.Get(v.header)
+	if sig == "" {
+		return errMissingSignature
+	}
+
+	// strip optional algorithm prefix, e.g. "sha256="
+	if i := strings.Index(sig, "="); i >= 0 && strings.EqualFold(sig[:i], v.algorithm) {
+		sig = sig[i+1:]
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(v.hash, v.secret)
+
+	if v.timestampHeader != "" {
+		ts := r.Header.Get(v.timestampHeader)
+		if ts == "" {
+			return errMissingTimestamp
+		}
+
+		if err = v.checkTimestamp(ts); err != nil {
+			return err
+		}
+
+		mac.Write([]byte(ts + "."))
+	}
+
+	mac.Write(body)
+
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errInvalidSignature
+	}
+
+	return nil
+}
+
+// checkTimestamp returns an error if the given timestamp (seconds since unix
+// epoch) is invalid or outside the tolerance window
+func (v *signatureVerifier) checkTimestamp(ts string) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "invalid timestamp")
+	}
+
+	diff := v.now().Sub(time.Unix(sec, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+
+	if diff > v.tolerance {
+		return errExpiredTimestamp
+	}
+
+	return nil
+}
+
+// withSignatureAuth enforces HMAC signature verification as a middleware using
+// the given verifier
+func withSignatureAuth(log logger.Logger, next http.Handler, v *signatureVerifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "could not read request body", http.StatusBadRequest)
+			return
+		}
+		_ = r.Body.Close()
+
+		if err = v.verify(r, body); err != nil {
+			log.Debugw("signature verification failed", "remote", r.RemoteAddr, "error", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// restore body for the next handler
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"crypto/subtle"
+	"crypto/tls"
 	"fmt"
 	"math"
 	"net"
@@ -33,12 +34,9 @@ const (
 	allowedOrigins = "*"
 	allowedMethod  = "POST"
 
-	// TODO: make configurable
-	pollConcurrency = 1 // goroutines polling in receive
-
-	// TODO: currently not implemented in CE SDK
-	// TODO: make configurable
-	allowedRate = 1000
+	// defaults if not configured
+	defaultPollConcurrency = 1    // goroutines polling in receive
+	defaultAllowedRate     = 1000 // advertised in OPTIONS responses, not enforced by CE SDK
 )
 
 var (
@@ -50,6 +48,19 @@ var (
 type Server struct {
 	ceclient ce.Client
 	listener net.Listener // holds net.Listener
+	mux      *http.ServeMux
+
+	// optional JSON mapping endpoint
+	jsonPath string
+	mapper   *mapper
+
+	// optional asynchronous processing
+	queue      *queue
+	statusPath string
+
+	// processor set when the server is started, used by handlers outside of the
+	// cloud event client, e.g. batch requests
+	proc processor.Processor
 	logger.Logger
 
 	sync.RWMutex
@@ -75,11 +86,87 @@ func NewServer(ctx context.Context, cfg *config.ProviderConfigWebhook, ms metric
 		ctx = logging.WithLogger(ctx, srv.Logger.(*zap.SugaredLogger))
 	}
 
+	if cfg.JSONMapping != nil {
+		jsonPath, err := validatePath(cfg.JSONMapping.Path)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook config")
+		}
+
+		if jsonPath == path {
+			return nil, errors.Wrap(ErrInvalidPath, "invalid webhook config: JSON mapping path must differ from webhook path")
+		}
+
+		m, err := newMapper(cfg.JSONMapping)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook config")
+		}
+
+		srv.jsonPath = "/" + jsonPath
+		srv.mapper = m
+	}
+
+	if cfg.Async != nil {
+		statusPath := defaultStatusPath
+		if cfg.Async.StatusPath != "" {
+			statusPath, err = validatePath(cfg.Async.StatusPath)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid webhook config")
+			}
+		}
+
+		if statusPath == path || "/"+statusPath == srv.jsonPath {
+			return nil, errors.Wrap(ErrInvalidPath, "invalid webhook config: status path must differ from webhook and JSON mapping path")
+		}
+
+		q, err := newQueue(cfg.Async, srv.Logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook config")
+		}
+
+		srv.statusPath = "/" + statusPath
+		srv.queue = q
+	}
+
+	pollConcurrency, allowedRate, err := validateConcurrency(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid webhook config")
+	}
+
+	if cfg.MaxBodyBytes < 0 {
+		return nil, errors.Errorf("invalid webhook config: invalid maximum body size %d", cfg.MaxBodyBytes)
+	}
+
+	var limiter *clientLimiter
+	if cfg.RateLimit != nil {
+		limiter, err = newClientLimiter(cfg.RateLimit)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook config")
+		}
+	}
+
+	authMW, err := srv.authMiddleware(ctx, cfg.Auth)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid webhook config")
+	}
+
+	var tlsCfg *tls.Config
+	if cfg.TLS != nil {
+		tlsCfg, err = util.NewTLSConfig(cfg.TLS, srv.Logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook config")
+		}
+	}
+
 	l, err := net.Listen("tcp", cfg.BindAddress)
 	if err != nil {
 		return nil, errors.Wrap(err, "start listener")
 	}
 
+	if tlsCfg != nil {
+		srv.Infow("enabling TLS", "mutualTLS", cfg.TLS.ClientCAFile != "", "minVersion", cfg.TLS.MinVersion)
+		l = tls.NewListener(l, tlsCfg)
+	}
+
 	// default client options
 	ceOpts := []cehttp.Option{
 		ce.WithListener(l),
@@ -87,31 +174,55 @@ func NewServer(ctx context.Context, cfg *config.ProviderConfigWebhook, ms metric
 		ce.WithDefaultOptionsHandlerFunc([]string{allowedMethod}, allowedRate, []string{allowedOrigins}, true),
 	}
 
-	// middleware options (executed in reverse order)
+	// middleware options (executed in reverse order, i.e. logger, body size
+	// limit, rate limit, auth and batch)
 	mwOpts := []cehttp.Option{
 		ce.WithMiddleware(func(next http.Handler) http.Handler {
-			return withLogger(srv.Logger, next)
+			return withBatch(next, "/"+path, srv.batchHandler(srv.processRequest))
 		}),
 	}
 
-	if cfg.Auth == nil || cfg.Auth.BasicAuth == nil {
-		srv.Warnf("disabling basic auth: no authentication data provided")
-	} else {
-		srv.Info("enabling endpoint authentication with basic auth")
+	if authMW != nil {
+		mwOpts = append(mwOpts, ce.WithMiddleware(authMW))
+	}
+
+	// limit requests before authentication to also limit credential guessing
+	if limiter != nil {
+		// authenticated senders are limited per user instead of per IP address
+		if authMW != nil && cfg.Auth.Type != config.HMACSignatureAuth {
+			ba := cfg.Auth.BasicAuth
+			limiter.user = func(r *http.Request) (string, bool) {
+				return basicAuthUser(r, ba.Username, ba.Password)
+			}
+		}
+
+		srv.Infow("enabling rate limiting", "requestsPerSecond", cfg.RateLimit.RequestsPerSecond, "burst", limiter.burst, "trustedProxies", cfg.RateLimit.TrustedProxies)
+		mwOpts = append(mwOpts, ce.WithMiddleware(func(next http.Handler) http.Handler {
+			return withRateLimit(next, limiter, srv.rejected(func(r *metrics.RejectionDetails) { r.RateLimited++ }))
+		}))
+	}
 
-		// hack: prepend the auth middleware so other mw are run before (mw executed in reverse order)
-		// TODO: optimize for less allocations
-		mwOpts = append([]cehttp.Option{ce.WithMiddleware(func(next http.Handler) http.Handler {
-			return withBasicAuth(ctx, next, cfg.Auth.BasicAuth.Username, cfg.Auth.BasicAuth.Password)
-		})}, mwOpts...)
+	if cfg.MaxBodyBytes > 0 {
+		mwOpts = append(mwOpts, ce.WithMiddleware(func(next http.Handler) http.Handler {
+			return withMaxBodySize(next, cfg.MaxBodyBytes, srv.rejected(func(r *metrics.RejectionDetails) { r.BodyTooLarge++ }))
+		}))
 	}
 
+	mwOpts = append(mwOpts, ce.WithMiddleware(func(next http.Handler) http.Handler {
+		return withLogger(srv.Logger, next)
+	}))
+
 	ceOpts = append(ceOpts, mwOpts...)
 	p, err := ce.NewHTTP(ceOpts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "create cloud event protocol")
 	}
 
+	// share the handler so additional endpoints use the same listener and
+	// middleware
+	srv.mux = http.NewServeMux()
+	p.Handler = srv.mux
+
 	client, err := ce.NewClient(p, ceclient.WithPollGoroutines(pollConcurrency))
 	if err != nil {
 		return nil, errors.Wrap(err, "create cloud event client")
@@ -127,6 +238,7 @@ func NewServer(ctx context.Context, cfg *config.ProviderConfigWebhook, ms metric
 		EventsTotal: new(int),
 		EventsErr:   new(int),
 		EventsSec:   new(float64),
+		Rejected:    new(metrics.RejectionDetails),
 	}
 
 	// apply options (use defaults otherwise)
@@ -134,13 +246,35 @@ func NewServer(ctx context.Context, cfg *config.ProviderConfigWebhook, ms metric
 		opt(&srv)
 	}
 
-	srv.Debugw("cloud event protocol configured", "port", p.GetListeningPort(), "path", p.GetPath())
+	srv.Debugw("cloud event protocol configured", "port", p.GetListeningPort(), "path", p.GetPath(), "pollConcurrency", pollConcurrency, "allowedRate", allowedRate, "maxBodyBytes", cfg.MaxBodyBytes)
 
 	go srv.PushMetrics(ctx, ms)
 
 	return &srv, nil
 }
 
+// validateConcurrency returns the poll concurrency and allowed rate from the
+// given configuration or the defaults if not set
+func validateConcurrency(cfg *config.ProviderConfigWebhook) (int, int, error) {
+	pollConcurrency, allowedRate := defaultPollConcurrency, defaultAllowedRate
+
+	switch {
+	case cfg.PollConcurrency < 0:
+		return 0, 0, errors.Errorf("invalid poll concurrency %d", cfg.PollConcurrency)
+	case cfg.PollConcurrency > 0:
+		pollConcurrency = cfg.PollConcurrency
+	}
+
+	switch {
+	case cfg.AllowedRate < 0:
+		return 0, 0, errors.Errorf("invalid allowed rate %d", cfg.AllowedRate)
+	case cfg.AllowedRate > 0:
+		allowedRate = cfg.AllowedRate
+	}
+
+	return pollConcurrency, allowedRate, nil
+}
+
 // validatePath removes any leading and trailing slashes and then validates the
 // given webhook endpoint path. If the path is empty, the default path will be
 // returned. Root path "/" is not allowed
@@ -162,25 +296,44 @@ func validatePath(path string) (string, error) {
 	return path, nil
 }
 
+// authMiddleware returns the authentication middleware for the given auth
+// configuration. If authentication is disabled, nil is returned.
+func (s *Server) authMiddleware(ctx context.Context, auth *config.AuthMethod) (func(next http.Handler) http.Handler, error) {
+	switch {
+	case auth == nil:
+		s.Warnf("disabling authentication: no authentication data provided")
+		return nil, nil
+
+	case auth.Type == config.HMACSignatureAuth:
+		v, err := newSignatureVerifier(auth.HMACSignatureAuth)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Infow("enabling endpoint authentication with hmac signature", "header", v.header, "algorithm", v.algorithm, "replayProtection", v.timestampHeader != "")
+		return func(next http.Handler) http.Handler {
+			return withSignatureAuth(s.Logger, next, v)
+		}, nil
+
+	case auth.BasicAuth == nil:
+		s.Warnf("disabling basic auth: no authentication data provided")
+		return nil, nil
+
+	default:
+		s.Info("enabling endpoint authentication with basic auth")
+		return func(next http.Handler) http.Handler {
+			return withBasicAuth(ctx, next, auth.BasicAuth.Username, auth.BasicAuth.Password)
+		}, nil
+	}
+}
+
 // withBasicAuth enforces basic auth as a middleware for the given username and
 // password
 func withBasicAuth(_ context.Context, next http.Handler, u, p string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		username, password, ok := r.BasicAuth()
-		if ok {
-			// reduce brute-force guessing attacks with constant-time comparisons
-			usernameHash := sha256.Sum256([]byte(username))
-			passwordHash := sha256.Sum256([]byte(password))
-			expectedUsernameHash := sha256.Sum256([]byte(u))
-			expectedPasswordHash := sha256.Sum256([]byte(p))
-
-			usernameMatch := subtle.ConstantTimeCompare(usernameHash[:], expectedUsernameHash[:]) == 1
-			passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], expectedPasswordHash[:]) == 1
-
-			if usernameMatch && passwordMatch {
-				next.ServeHTTP(w, r)
-				return
-			}
+		if _, ok := basicAuthUser(r, u, p); ok {
+			next.ServeHTTP(w, r)
+			return
 		}
 
 		w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
@@ -188,6 +341,26 @@ func withBasicAuth(_ context.Context, next http.Handler, u, p string) http.Handl
 	})
 }
 
+// basicAuthUser returns the basic auth user of the given request and whether
+// the request credentials match the given username and password
+func basicAuthUser(r *http.Request, u, p string) (string, bool) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	// reduce brute-force guessing attacks with constant-time comparisons
+	usernameHash := sha256.Sum256([]byte(username))
+	passwordHash := sha256.Sum256([]byte(password))
+	expectedUsernameHash := sha256.Sum256([]byte(u))
+	expectedPasswordHash := sha256.Sum256([]byte(p))
+
+	usernameMatch := subtle.ConstantTimeCompare(usernameHash[:], expectedUsernameHash[:]) == 1
+	passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], expectedPasswordHash[:]) == 1
+
+	return username, usernameMatch && passwordMatch
+}
+
 // withLogger logs the incoming http request in DEBUG level
 func withLogger(log logger.Logger, next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -196,6 +369,16 @@ func withLogger(log logger.Logger, next http.Handler) http.HandlerFunc {
 	}
 }
 
+// rejected returns a function recording a rejected request in the stats using
+// the given update function
+func (s *Server) rejected(update func(r *metrics.RejectionDetails)) func() {
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+		update(s.stats.Rejected)
+	}
+}
+
 // Address returns the listener address and port, e.g. "10.0.0.1:8080"
 func (s *Server) Address() string {
 	return s.listener.Addr().String()
@@ -224,7 +407,35 @@ func (s *Server) PushMetrics(ctx context.Context, ms metrics.Receiver) {
 // every incoming valid CloudEvent. Stream will return when the given context is cancelled.
 func (s *Server) Stream(ctx context.Context, proc processor.Processor) error {
 	s.Info("starting webhook server")
-	if err := s.ceclient.StartReceiver(ctx, s.processEvent(proc)); err != nil {
+
+	s.Lock()
+	s.proc = proc
+	s.Unlock()
+
+	if s.mapper != nil {
+		s.Infow("enabling JSON mapping endpoint", "path", s.jsonPath)
+		s.mux.Handle(s.jsonPath, s.jsonHandler(s.mapper, s.processRequest))
+	}
+
+	done := make(chan struct{})
+	if s.queue != nil {
+		s.Infow("enabling asynchronous processing", "queueDir", s.queue.dir, "workers", s.queue.workers, "statusPath", s.statusPath)
+		s.mux.Handle(s.statusPath+"/", s.statusHandler(s.statusPath, s.queue))
+
+		go func() {
+			defer close(done)
+			s.queue.run(ctx, func(ctx context.Context, e ce.Event) error {
+				return s.process(ctx, proc, e)
+			})
+		}()
+	} else {
+		close(done)
+	}
+
+	err := s.ceclient.StartReceiver(ctx, s.processEvent(proc))
+	<-done // wait for queue workers
+
+	if err != nil {
 		return errors.Wrap(err, "start webhook server")
 	}
 	return nil
@@ -236,17 +447,73 @@ type receiveFunc func(ctx context.Context, e ce.Event) ce.Result
 // processEvent injects a processor into a receiveFunc
 func (s *Server) processEvent(p processor.Processor) receiveFunc {
 	return func(ctx context.Context, e ce.Event) ce.Result {
-		err := p.Process(ctx, e)
+		if s.queue != nil {
+			return s.enqueue(e)
+		}
+		return s.process(ctx, p, e)
+	}
+}
 
-		s.Lock()
-		defer s.Unlock()
+// enqueue queues the event for asynchronous processing and returns the http
+// result, i.e. 202 if the event was queued
+func (s *Server) enqueue(e ce.Event) ce.Result {
+	err := s.queue.enqueue(e)
+	switch {
+	case err == nil:
+		return cehttp.NewResult(http.StatusAccepted, "event queued")
+	case errors.Is(err, ErrQueueFull):
+		s.rejected(func(r *metrics.RejectionDetails) { r.QueueFull++ })()
+		return cehttp.NewResult(http.StatusServiceUnavailable, "%v", err)
+	default:
+		s.Errorw("could not queue event", "eventID", e.ID(), "error", err)
+		return err
+	}
+}
 
-		*s.stats.EventsTotal++
-		if err != nil {
-			*s.stats.EventsErr++
+// acceptedStatus returns the http status code for successfully accepted events
+func (s *Server) acceptedStatus() int {
+	if s.queue != nil {
+		return http.StatusAccepted
+	}
+	return http.StatusOK
+}
+
+// processRequest invokes the processor of the started server for an event
+// received outside of the cloud event client
+func (s *Server) processRequest(r *http.Request, e ce.Event) error {
+	s.RLock()
+	p := s.proc
+	s.RUnlock()
+
+	if p == nil {
+		return errors.New("webhook server not started")
+	}
+
+	if s.queue != nil {
+		if err := s.queue.enqueue(e); err != nil {
+			if errors.Is(err, ErrQueueFull) {
+				s.rejected(func(r *metrics.RejectionDetails) { r.QueueFull++ })()
+			}
+			return err
 		}
-		return err
+		return nil
+	}
+
+	return s.process(r.Context(), p, e)
+}
+
+// process invokes the given processor for the event and updates the stats
+func (s *Server) process(ctx context.Context, p processor.Processor, e ce.Event) error {
+	err := p.Process(ctx, e)
+
+	s.Lock()
+	defer s.Unlock()
+
+	*s.stats.EventsTotal++
+	if err != nil {
+		*s.stats.EventsErr++
 	}
+	return err
 }
 
 // Shutdown is a no-op. The webhook server will shut down when the context in
@@ -4,7 +4,19 @@
 package webhook
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
 )
 
 func Test_validatePath(t *testing.T) {
@@ -31,3 +43,205 @@ func Test_validatePath(t *testing.T) {
 		})
 	}
 }
+
+func Test_newSignatureVerifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       *config.HMACSignatureAuthMethod
+		errString string
+	}{
+		{name: "no config", cfg: nil, errString: "secret must be set"},
+		{name: "no secret", cfg: &config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256"}, errString: "secret must be set"},
+		{name: "unsupported algorithm", cfg: &config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "md5", Secret: "s"}, errString: "unsupported signature algorithm"},
+		{name: "negative tolerance", cfg: &config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: "s", ToleranceSeconds: -1}, errString: "invalid signature timestamp tolerance"},
+		{name: "valid config", cfg: &config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "SHA512", Secret: "s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSignatureVerifier(tt.cfg)
+			if tt.errString == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errString)
+		})
+	}
+
+	v, err := newSignatureVerifier(&config.HMACSignatureAuthMethod{Secret: "s"})
+	assert.NilError(t, err)
+	assert.Equal(t, v.header, defaultSignatureHeader)
+	assert.Equal(t, v.algorithm, defaultSignatureAlgorithm)
+	assert.Equal(t, v.timestampHeader, defaultTimestampHeader)
+	assert.Equal(t, v.tolerance, defaultSignatureTolerance)
+
+	v, err = newSignatureVerifier(&config.HMACSignatureAuthMethod{Algorithm: "sha256", Secret: "s", TimestampHeader: "X-Timestamp", DisableReplayProtection: true})
+	assert.NilError(t, err)
+	assert.Equal(t, v.timestampHeader, "")
+}
+
+func Test_signatureVerifier_verify(t *testing.T) {
+	const (
+		secret = "s3cr3t"
+		body   = `{"id":1}`
+	)
+
+	now := time.Unix(1629200000, 0)
+	ts := "1629200000"
+
+	sign := func(h func() hash.Hash, msg string) string {
+		mac := hmac.New(h, []byte(secret))
+		mac.Write([]byte(msg))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.HMACSignatureAuthMethod
+		headers   map[string]string
+		wantErr   error
+		errString string
+	}{
+		{
+			name: "valid sha256 signature",
+			cfg:  config.HMACSignatureAuthMethod{Algorithm: "sha256", Secret: secret},
+			headers: map[string]string{
+				"X-Signature":           sign(sha256.New, ts+"."+body),
+				"X-Signature-Timestamp": ts,
+			},
+		},
+		{
+			name: "valid sha256 signature with algorithm prefix",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Hub-Signature-256", Algorithm: "sha256", Secret: secret},
+			headers: map[string]string{
+				"X-Hub-Signature-256":   "sha256=" + sign(sha256.New, ts+"."+body),
+				"X-Signature-Timestamp": ts,
+			},
+		},
+		{
+			name: "valid sha512 signature",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha512", Secret: secret},
+			headers: map[string]string{
+				"X-Signature":           sign(sha512.New, ts+"."+body),
+				"X-Signature-Timestamp": ts,
+			},
+		},
+		{
+			name:    "missing signature",
+			cfg:     config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret},
+			headers: map[string]string{"X-Signature-Timestamp": ts},
+			wantErr: errMissingSignature,
+		},
+		{
+			name: "wrong algorithm",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha512", Secret: secret},
+			headers: map[string]string{
+				"X-Signature":           sign(sha256.New, ts+"."+body),
+				"X-Signature-Timestamp": ts,
+			},
+			wantErr: errInvalidSignature,
+		},
+		{
+			name: "not hex-encoded",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret},
+			headers: map[string]string{
+				"X-Signature":           "not-hex",
+				"X-Signature-Timestamp": ts,
+			},
+			wantErr: errInvalidSignature,
+		},
+		{
+			name:    "missing default timestamp",
+			cfg:     config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret},
+			headers: map[string]string{"X-Signature": sign(sha256.New, body)},
+			wantErr: errMissingTimestamp,
+		},
+		{
+			name: "replayed request outside default tolerance",
+			cfg:  config.HMACSignatureAuthMethod{Algorithm: "sha256", Secret: secret},
+			headers: map[string]string{
+				"X-Signature":           sign(sha256.New, "1629199000."+body),
+				"X-Signature-Timestamp": "1629199000",
+			},
+			wantErr: errExpiredTimestamp,
+		},
+		{
+			name: "valid signature with timestamp",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret, TimestampHeader: "X-Timestamp"},
+			headers: map[string]string{
+				"X-Signature": sign(sha256.New, ts+"."+body),
+				"X-Timestamp": ts,
+			},
+		},
+		{
+			name:    "missing timestamp",
+			cfg:     config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret, TimestampHeader: "X-Timestamp"},
+			headers: map[string]string{"X-Signature": sign(sha256.New, ts+"."+body)},
+			wantErr: errMissingTimestamp,
+		},
+		{
+			name: "signature does not include timestamp",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret, TimestampHeader: "X-Timestamp"},
+			headers: map[string]string{
+				"X-Signature": sign(sha256.New, body),
+				"X-Timestamp": ts,
+			},
+			wantErr: errInvalidSignature,
+		},
+		{
+			name: "replayed request outside tolerance",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret, TimestampHeader: "X-Timestamp", ToleranceSeconds: 60},
+			headers: map[string]string{
+				"X-Signature": sign(sha256.New, "1629199900."+body),
+				"X-Timestamp": "1629199900",
+			},
+			wantErr: errExpiredTimestamp,
+		},
+		{
+			name: "valid body signature without replay protection",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Hub-Signature-256", Algorithm: "sha256", Secret: secret, DisableReplayProtection: true},
+			headers: map[string]string{
+				"X-Hub-Signature-256": "sha256=" + sign(sha256.New, body),
+			},
+		},
+		{
+			name: "timestamped signature without replay protection",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret, DisableReplayProtection: true},
+			headers: map[string]string{
+				"X-Signature":           sign(sha256.New, ts+"."+body),
+				"X-Signature-Timestamp": ts,
+			},
+			wantErr: errInvalidSignature,
+		},
+		{
+			name: "invalid timestamp",
+			cfg:  config.HMACSignatureAuthMethod{Header: "X-Signature", Algorithm: "sha256", Secret: secret, TimestampHeader: "X-Timestamp"},
+			headers: map[string]string{
+				"X-Signature": sign(sha256.New, "abc."+body),
+				"X-Timestamp": "abc",
+			},
+			errString: "invalid timestamp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := newSignatureVerifier(&tt.cfg)
+			assert.NilError(t, err)
+			v.now = func() time.Time { return now }
+
+			r := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+			for k, val := range tt.headers {
+				r.Header.Set(k, val)
+			}
+
+			err = v.verify(r, []byte(body))
+			switch {
+			case tt.wantErr != nil:
+				assert.Equal(t, err, tt.wantErr)
+			case tt.errString != "":
+				assert.ErrorContains(t, err, tt.errString)
+			default:
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
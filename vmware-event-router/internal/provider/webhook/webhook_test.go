@@ -5,9 +5,17 @@ package webhook_test
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	ce "github.com/cloudevents/sdk-go/v2"
 	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
@@ -29,10 +37,12 @@ func Test_WebhookServer(t *testing.T) {
 			name      string
 			address   string
 			path      string
+			tls       *config.TLSConfig
 			errString string
 		}{
-			{"root path is not allowed", "127.0.0.1:0", "/", webhook.ErrInvalidPath.Error()},
-			{"invalid bind address", "abc:0", "", "invalid webhook config: invalid character detected"},
+			{"root path is not allowed", "127.0.0.1:0", "/", nil, webhook.ErrInvalidPath.Error()},
+			{"invalid bind address", "abc:0", "", nil, "invalid webhook config: invalid character detected"},
+			{"missing tls certificate", "127.0.0.1:0", "", &config.TLSConfig{CertFile: "/does/not/exist.crt", KeyFile: "/does/not/exist.key"}, "invalid webhook config: stat file"},
 		}
 
 		for _, tt := range tests {
@@ -41,6 +51,7 @@ func Test_WebhookServer(t *testing.T) {
 				cfg := config.ProviderConfigWebhook{
 					BindAddress: test.address,
 					Path:        test.path,
+					TLS:         test.tls,
 				}
 
 				_, err := webhook.NewServer(context.TODO(), &cfg, metricsStub{}, logger.Sugar())
@@ -231,6 +242,93 @@ func Test_WebhookServer(t *testing.T) {
 	})
 }
 
+func Test_WebhookServerSignatureAuth(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.DebugLevel))
+
+	const (
+		secret = "s3cr3t"
+		event  = `{"specversion":"1.0","id":"1","source":"https://example.com","type":"com.ce.sample.sent","datacontenttype":"application/json","data":{"message":"Hello, World!"}}`
+	)
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sign := func(msg string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "." + msg))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		wantCode  int
+	}{
+		{name: "valid signature", signature: sign(event), wantCode: http.StatusOK},
+		{name: "invalid signature", signature: sign("tampered"), wantCode: http.StatusUnauthorized},
+		{name: "missing signature", signature: "", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.ProviderConfigWebhook{
+				BindAddress: "127.0.0.1:0",
+				Auth: &config.AuthMethod{
+					Type: config.HMACSignatureAuth,
+					HMACSignatureAuth: &config.HMACSignatureAuthMethod{
+						Algorithm: "sha256",
+						Secret:    secret,
+					},
+				},
+			}
+
+			ctx := logging.WithLogger(context.Background(), logger.Sugar())
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+			assert.NilError(t, err, "run server")
+
+			var eg errgroup.Group
+			eg.Go(func() error {
+				defer cancel()
+
+				target := fmt.Sprintf("http://%s/webhook", srv.Address())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(event))
+				assert.NilError(t, err)
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+				req.Header.Set("X-Signature-Timestamp", ts)
+				if test.signature != "" {
+					req.Header.Set("X-Signature", test.signature)
+				}
+
+				res, err := http.DefaultClient.Do(req)
+				assert.NilError(t, err)
+				defer res.Body.Close()
+				assert.Equal(t, res.StatusCode, test.wantCode)
+				return nil
+			})
+
+			err = srv.Stream(ctx, &fakeProcessor{logger.Sugar()})
+			assert.NilError(t, err, "run server")
+
+			err = eg.Wait()
+			assert.NilError(t, err, "http client")
+		})
+	}
+
+	t.Run("fails to start with invalid signature config", func(t *testing.T) {
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			Auth: &config.AuthMethod{
+				Type: config.HMACSignatureAuth,
+			},
+		}
+
+		_, err := webhook.NewServer(context.TODO(), &cfg, metricsStub{}, logger.Sugar())
+		assert.ErrorContains(t, err, "invalid webhook config")
+	})
+}
+
 func sendEvent(ctx context.Context, t *testing.T, target string, creds string) error {
 	ctx = ce.ContextWithTarget(ctx, target)
 
@@ -265,6 +363,476 @@ func basicAuth(user, pass string) string {
 	return base64.StdEncoding.EncodeToString([]byte(auth))
 }
 
+func Test_WebhookServerJSONMapping(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.DebugLevel))
+
+	t.Run("fails to start with same path as webhook", func(t *testing.T) {
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			Path:        "/webhook",
+			JSONMapping: &config.WebhookJSONMapping{
+				Path: "/webhook/",
+				Type: config.WebhookMappingRule{Value: "alert"},
+			},
+		}
+
+		_, err := webhook.NewServer(context.TODO(), &cfg, metricsStub{}, logger.Sugar())
+		assert.ErrorContains(t, err, "JSON mapping path must differ from webhook path")
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantCode int
+		wantType string
+	}{
+		{name: "maps JSON payload", method: http.MethodPost, body: `{"alert":{"name":"HighCPU","host":"esx-01"}}`, wantCode: http.StatusOK, wantType: "HighCPU"},
+		{name: "rejects invalid JSON", method: http.MethodPost, body: `{"alert":`, wantCode: http.StatusBadRequest},
+		{name: "rejects unmapped type", method: http.MethodPost, body: `{"status":"firing"}`, wantCode: http.StatusBadRequest},
+		{name: "rejects other methods", method: http.MethodPut, body: `{}`, wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.ProviderConfigWebhook{
+				BindAddress: "127.0.0.1:0",
+				Path:        "/webhook",
+				JSONMapping: &config.WebhookJSONMapping{
+					Path:    "/alerts",
+					Type:    config.WebhookMappingRule{JSONPath: "alert.name"},
+					Subject: &config.WebhookMappingRule{JSONPath: "alert.host"},
+				},
+			}
+
+			ctx := logging.WithLogger(context.Background(), logger.Sugar())
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+			assert.NilError(t, err, "run server")
+
+			proc := &recordingProcessor{events: make(chan ce.Event, 1)}
+
+			var eg errgroup.Group
+			eg.Go(func() error {
+				defer cancel()
+
+				target := fmt.Sprintf("http://%s/alerts", srv.Address())
+				req, err := http.NewRequestWithContext(ctx, test.method, target, strings.NewReader(test.body))
+				assert.NilError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+
+				res, err := http.DefaultClient.Do(req)
+				assert.NilError(t, err)
+				defer res.Body.Close()
+				assert.Equal(t, res.StatusCode, test.wantCode)
+
+				if test.wantType != "" {
+					e := <-proc.events
+					assert.Equal(t, e.Type(), test.wantType)
+					assert.Equal(t, e.Subject(), "esx-01")
+				}
+				return nil
+			})
+
+			err = srv.Stream(ctx, proc)
+			assert.NilError(t, err, "run server")
+
+			err = eg.Wait()
+			assert.NilError(t, err, "http client")
+		})
+	}
+}
+
+func Test_WebhookServerLimits(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.DebugLevel))
+
+	t.Run("fails to start with invalid limits", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			cfg       config.ProviderConfigWebhook
+			errString string
+		}{
+			{"negative poll concurrency", config.ProviderConfigWebhook{PollConcurrency: -1}, "invalid poll concurrency"},
+			{"negative allowed rate", config.ProviderConfigWebhook{AllowedRate: -1}, "invalid allowed rate"},
+			{"negative max body size", config.ProviderConfigWebhook{MaxBodyBytes: -1}, "invalid maximum body size"},
+			{"invalid rate limit", config.ProviderConfigWebhook{RateLimit: &config.WebhookRateLimit{}}, "requestsPerSecond must be greater than 0"},
+		}
+
+		for _, tt := range tests {
+			test := tt
+			t.Run(test.name, func(t *testing.T) {
+				test.cfg.BindAddress = "127.0.0.1:0"
+				_, err := webhook.NewServer(context.TODO(), &test.cfg, metricsStub{}, logger.Sugar())
+				assert.ErrorContains(t, err, "invalid webhook config")
+				assert.ErrorContains(t, err, test.errString)
+			})
+		}
+	})
+
+	t.Run("rejects requests exceeding limits", func(t *testing.T) {
+		const event = `{"specversion":"1.0","id":"1","source":"https://example.com","type":"com.ce.sample.sent","datacontenttype":"application/json","data":{"message":"Hello, World!"}}`
+
+		cfg := config.ProviderConfigWebhook{
+			BindAddress:     "127.0.0.1:0",
+			PollConcurrency: 2,
+			MaxBodyBytes:    int64(len(event)),
+			RateLimit: &config.WebhookRateLimit{
+				RequestsPerSecond: 0.1,
+				Burst:             1,
+			},
+		}
+
+		ctx := logging.WithLogger(context.Background(), logger.Sugar())
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err, "run server")
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			defer cancel()
+
+			send := func(body string) *http.Response {
+				target := fmt.Sprintf("http://%s/webhook", srv.Address())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+				assert.NilError(t, err)
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+
+				res, err := http.DefaultClient.Do(req)
+				assert.NilError(t, err)
+				_ = res.Body.Close()
+				return res
+			}
+
+			// body size is checked before rate limit
+			res := send(event + " ")
+			assert.Equal(t, res.StatusCode, http.StatusRequestEntityTooLarge)
+
+			res = send(event)
+			assert.Equal(t, res.StatusCode, http.StatusOK)
+
+			res = send(event)
+			assert.Equal(t, res.StatusCode, http.StatusTooManyRequests)
+			assert.Equal(t, res.Header.Get("Retry-After"), "10")
+			return nil
+		})
+
+		err = srv.Stream(ctx, &fakeProcessor{logger.Sugar()})
+		assert.NilError(t, err, "run server")
+
+		err = eg.Wait()
+		assert.NilError(t, err, "http client")
+	})
+
+	t.Run("rate limits clients behind a trusted proxy separately", func(t *testing.T) {
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			RateLimit: &config.WebhookRateLimit{
+				RequestsPerSecond: 0.1,
+				Burst:             1,
+				TrustedProxies:    []string{"127.0.0.1"},
+			},
+		}
+
+		ctx := logging.WithLogger(context.Background(), logger.Sugar())
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err, "run server")
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			defer cancel()
+
+			const event = `{"specversion":"1.0","id":"1","source":"https://example.com","type":"com.ce.sample.sent"}`
+			for _, tc := range []struct {
+				client string
+				want   int
+			}{
+				{client: "1.2.3.4", want: http.StatusOK},
+				{client: "1.2.3.4", want: http.StatusTooManyRequests},
+				{client: "5.6.7.8", want: http.StatusOK},
+			} {
+				target := fmt.Sprintf("http://%s/webhook", srv.Address())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(event))
+				assert.NilError(t, err)
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+				req.Header.Set("X-Forwarded-For", tc.client)
+
+				res, err := http.DefaultClient.Do(req)
+				assert.NilError(t, err)
+				_ = res.Body.Close()
+				assert.Equal(t, res.StatusCode, tc.want, "client %s", tc.client)
+			}
+			return nil
+		})
+
+		err = srv.Stream(ctx, &fakeProcessor{logger.Sugar()})
+		assert.NilError(t, err, "run server")
+
+		err = eg.Wait()
+		assert.NilError(t, err, "http client")
+	})
+
+	t.Run("rate limits authenticated requests per user", func(t *testing.T) {
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			RateLimit: &config.WebhookRateLimit{
+				RequestsPerSecond: 0.1,
+				Burst:             1,
+			},
+			Auth: &config.AuthMethod{
+				Type: config.BasicAuth,
+				BasicAuth: &config.BasicAuthMethod{
+					Username: "user",
+					Password: "pass",
+				},
+			},
+		}
+
+		ctx := logging.WithLogger(context.Background(), logger.Sugar())
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err, "run server")
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			defer cancel()
+
+			const event = `{"specversion":"1.0","id":"1","source":"https://example.com","type":"com.ce.sample.sent"}`
+			for _, tc := range []struct {
+				password string
+				want     int
+			}{
+				{password: "pass", want: http.StatusOK},
+				// failed authentication does not count against the user limit
+				{password: "wrong", want: http.StatusUnauthorized},
+				{password: "pass", want: http.StatusTooManyRequests},
+			} {
+				target := fmt.Sprintf("http://%s/webhook", srv.Address())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(event))
+				assert.NilError(t, err)
+				req.Header.Set("Content-Type", "application/cloudevents+json")
+				req.SetBasicAuth("user", tc.password)
+
+				res, err := http.DefaultClient.Do(req)
+				assert.NilError(t, err)
+				_ = res.Body.Close()
+				assert.Equal(t, res.StatusCode, tc.want)
+			}
+			return nil
+		})
+
+		err = srv.Stream(ctx, &fakeProcessor{logger.Sugar()})
+		assert.NilError(t, err, "run server")
+
+		err = eg.Wait()
+		assert.NilError(t, err, "http client")
+	})
+
+	t.Run("rate limits requests failing authentication", func(t *testing.T) {
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			RateLimit: &config.WebhookRateLimit{
+				RequestsPerSecond: 0.1,
+				Burst:             2,
+			},
+			Auth: &config.AuthMethod{
+				Type: config.BasicAuth,
+				BasicAuth: &config.BasicAuthMethod{
+					Username: "user",
+					Password: "pass",
+				},
+			},
+		}
+
+		ctx := logging.WithLogger(context.Background(), logger.Sugar())
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err, "run server")
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			defer cancel()
+
+			// guessed credentials with a different username per request
+			for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+				target := fmt.Sprintf("http://%s/webhook", srv.Address())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader("{}"))
+				assert.NilError(t, err)
+				req.SetBasicAuth(fmt.Sprintf("guess-%d", i), "wrong")
+
+				res, err := http.DefaultClient.Do(req)
+				assert.NilError(t, err)
+				_ = res.Body.Close()
+				assert.Equal(t, res.StatusCode, want)
+			}
+			return nil
+		})
+
+		err = srv.Stream(ctx, &fakeProcessor{logger.Sugar()})
+		assert.NilError(t, err, "run server")
+
+		err = eg.Wait()
+		assert.NilError(t, err, "http client")
+	})
+}
+
+func Test_WebhookServerBatch(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.DebugLevel))
+
+	const batch = `[
+  {"specversion":"1.0","id":"1","source":"https://example.com","type":"com.ce.sample.sent","data":{"message":"first"}},
+  {"specversion":"1.0","id":"2","source":"https://example.com","type":"com.ce.sample.sent","data":{"message":"second"}}
+]`
+
+	cfg := config.ProviderConfigWebhook{
+		BindAddress: "127.0.0.1:0",
+		Path:        "/webhook",
+	}
+
+	ctx := logging.WithLogger(context.Background(), logger.Sugar())
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+	assert.NilError(t, err, "run server")
+
+	proc := &recordingProcessor{events: make(chan ce.Event, 2)}
+
+	var eg errgroup.Group
+	eg.Go(func() error {
+		defer cancel()
+
+		target := fmt.Sprintf("http://%s/webhook", srv.Address())
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(batch))
+		assert.NilError(t, err)
+		req.Header.Set("Content-Type", "application/cloudevents-batch+json")
+
+		res, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, res.StatusCode, http.StatusOK)
+
+		var body struct {
+			Results []struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+			} `json:"results"`
+		}
+		assert.NilError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, len(body.Results), 2)
+
+		for i, id := range []string{"1", "2"} {
+			assert.Equal(t, body.Results[i].ID, id)
+			assert.Equal(t, body.Results[i].Status, "accepted")
+			assert.Equal(t, (<-proc.events).ID(), id)
+		}
+		return nil
+	})
+
+	err = srv.Stream(ctx, proc)
+	assert.NilError(t, err, "run server")
+
+	err = eg.Wait()
+	assert.NilError(t, err, "http client")
+}
+
+func Test_WebhookServerAsync(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.DebugLevel))
+
+	t.Run("fails to start with same status path as webhook", func(t *testing.T) {
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			Path:        "/webhook",
+			Async: &config.WebhookAsync{
+				QueueDir:   t.TempDir(),
+				StatusPath: "/webhook",
+			},
+		}
+
+		_, err := webhook.NewServer(context.TODO(), &cfg, metricsStub{}, logger.Sugar())
+		assert.ErrorContains(t, err, "status path must differ")
+	})
+
+	t.Run("accepts event and reports delivery status", func(t *testing.T) {
+		const event = `{"specversion":"1.0","id":"async-1","source":"https://example.com","type":"com.ce.sample.sent","datacontenttype":"application/json","data":{"message":"Hello, World!"}}`
+
+		cfg := config.ProviderConfigWebhook{
+			BindAddress: "127.0.0.1:0",
+			Path:        "/webhook",
+			Async: &config.WebhookAsync{
+				QueueDir: t.TempDir(),
+			},
+		}
+
+		ctx := logging.WithLogger(context.Background(), logger.Sugar())
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		srv, err := webhook.NewServer(ctx, &cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err, "run server")
+
+		// processing is blocked until the client received the response
+		proc := &recordingProcessor{events: make(chan ce.Event)}
+
+		var eg errgroup.Group
+		eg.Go(func() error {
+			defer cancel()
+
+			target := fmt.Sprintf("http://%s/webhook", srv.Address())
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(event))
+			assert.NilError(t, err)
+			req.Header.Set("Content-Type", "application/cloudevents+json")
+
+			res, err := http.DefaultClient.Do(req)
+			assert.NilError(t, err)
+			_ = res.Body.Close()
+			assert.Equal(t, res.StatusCode, http.StatusAccepted)
+
+			e := <-proc.events
+			assert.Equal(t, e.ID(), "async-1")
+
+			status := func() string {
+				target := fmt.Sprintf("http://%s/webhook/status/async-1", srv.Address())
+				res, err := http.Get(target)
+				assert.NilError(t, err)
+				defer res.Body.Close()
+				assert.Equal(t, res.StatusCode, http.StatusOK)
+
+				var d struct {
+					Status string `json:"status"`
+				}
+				assert.NilError(t, json.NewDecoder(res.Body).Decode(&d))
+				return d.Status
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for status() != "delivered" {
+				assert.Assert(t, time.Now().Before(deadline), "timed out waiting for delivery")
+				time.Sleep(10 * time.Millisecond)
+			}
+			return nil
+		})
+
+		err = srv.Stream(ctx, proc)
+		assert.NilError(t, err, "run server")
+
+		err = eg.Wait()
+		assert.NilError(t, err, "http client")
+	})
+}
+
 type metricsStub struct{}
 
 func (m metricsStub) Receive(stats *metrics.EventStats) {}
@@ -283,3 +851,18 @@ func (f fakeProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
 func (f fakeProcessor) Shutdown(ctx context.Context) error {
 	return nil
 }
+
+type recordingProcessor struct {
+	events chan ce.Event
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	r.events <- e
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
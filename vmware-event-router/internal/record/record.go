@@ -0,0 +1,194 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+)
+
+const (
+	defaultMaxFileSize = 10 * 1024 * 1024
+	defaultMaxFiles    = 10
+)
+
+// verify that Recorder implements the processor interface
+var _ processor.Processor = (*Recorder)(nil)
+
+// Entry is a recorded event written as one line to a recording file
+type Entry struct {
+	// Recorded is the time the event was recorded
+	Recorded time.Time `json:"recorded"`
+	// CloudEvent is the event emitted by the event provider
+	CloudEvent ce.Event `json:"cloudEvent"`
+	// VSphereEvent is the vSphere event the CloudEvent was created from
+	// including its govmomi type name in the _typeName attribute (vSphere events
+	// only)
+	VSphereEvent json.RawMessage `json:"vsphereEvent,omitempty"`
+}
+
+// Event returns the CloudEvent for the recorded event. For vSphere events the
+// CloudEvent is created again from the recorded vSphere event with the
+// recorded ID, source and extension attributes, i.e. the conversion into a
+// CloudEvent is reproduced, and the vSphere event is returned. Otherwise the
+// recorded CloudEvent is returned.
+func (e *Entry) Event() (*ce.Event, types.BaseEvent, error) {
+	if len(e.VSphereEvent) == 0 {
+		if err := e.CloudEvent.Validate(); err != nil {
+			return nil, nil, errors.Wrap(err, "invalid cloud event")
+		}
+		return &e.CloudEvent, nil, nil
+	}
+
+	event, err := UnmarshalVSphereEvent(e.VSphereEvent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attrs := make(map[string]string)
+	for k, v := range e.CloudEvent.Extensions() {
+		s, err := cetypes.Format(v)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "convert extension attribute %q", k)
+		}
+		attrs[k] = s
+	}
+
+	ev, err := events.NewFromVSphere(event, e.CloudEvent.Source(), events.WithID(e.CloudEvent.ID()), events.WithAttributes(attrs))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ev, event, nil
+}
+
+// Recorder is an event processor recording every event into rotating JSONL
+// files before passing it to the next processor
+type Recorder struct {
+	next processor.Processor
+	logger.Logger
+
+	mu     sync.Mutex
+	w      *rotatingWriter
+	closed bool
+}
+
+// NewRecorder returns a recorder for the given configuration passing events to
+// the given processor
+func NewRecorder(cfg *config.Record, next processor.Processor, log logger.Logger) (*Recorder, error) {
+	if cfg == nil {
+		return nil, errors.New("record configuration must be provided")
+	}
+
+	if next == nil {
+		return nil, errors.New("processor must be provided")
+	}
+
+	if cfg.Dir == "" {
+		return nil, errors.New("invalid record config: dir must be specified")
+	}
+
+	maxFileSize := cfg.MaxFileSize
+	switch {
+	case maxFileSize == 0:
+		maxFileSize = defaultMaxFileSize
+	case maxFileSize < 0:
+		return nil, errors.Errorf("invalid record config: maxFileSize must not be negative: %d", cfg.MaxFileSize)
+	}
+
+	maxFiles := cfg.MaxFiles
+	switch {
+	case maxFiles == 0:
+		maxFiles = defaultMaxFiles
+	case maxFiles < 0:
+		return nil, errors.Errorf("invalid record config: maxFiles must not be negative: %d", cfg.MaxFiles)
+	}
+
+	w, err := newRotatingWriter(cfg.Dir, maxFileSize, maxFiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid record config")
+	}
+
+	r := Recorder{
+		next:   next,
+		Logger: log,
+		w:      w,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		r.Logger = zapSugared.Named("[RECORD]")
+	}
+
+	return &r, nil
+}
+
+// Process records the given event and passes it to the next processor. Events
+// which cannot be recorded are still processed. The vSphere event a CloudEvent
+// was created from is recorded if set in the context (see
+// events.WithVSphereEvent).
+func (r *Recorder) Process(ctx context.Context, e ce.Event) error {
+	if err := r.record(ctx, e); err != nil {
+		r.Errorw("could not record event", "eventID", e.ID(), "error", err)
+	}
+
+	return r.next.Process(ctx, e)
+}
+
+// record writes the given event to the current recording file
+func (r *Recorder) record(ctx context.Context, e ce.Event) error {
+	entry := Entry{
+		Recorded:   time.Now().UTC(),
+		CloudEvent: e,
+	}
+
+	if event, ok := events.VSphereEventFromContext(ctx); ok {
+		raw, err := vSphereEvent(event)
+		if err != nil {
+			r.Warnw("recording event without vSphere event", "eventID", e.ID(), "subject", e.Subject(), "error", err)
+		} else {
+			entry.VSphereEvent = raw
+		}
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "encode event")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errors.New("recorder is shut down")
+	}
+	return r.w.writeLine(b)
+}
+
+// PushMetrics is a no-op, the next processor pushes its own metrics
+func (r *Recorder) PushMetrics(context.Context, metrics.Receiver) {}
+
+// Shutdown shuts down the next processor and closes the current recording file
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	err := r.next.Shutdown(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if closeErr := r.w.close(); closeErr != nil && err == nil {
+		err = errors.Wrap(closeErr, "close recording file")
+	}
+
+	return err
+}
@@ -0,0 +1,238 @@
+//go:build unit
+// +build unit
+
+package record_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/events"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/record"
+)
+
+// readEntries returns all entries from the recording files in the given
+// directory
+func readEntries(t *testing.T, dir string) []record.Entry {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+	assert.NilError(t, err)
+
+	var entries []record.Entry
+	for _, file := range files {
+		f, err := os.Open(file)
+		assert.NilError(t, err)
+
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			var entry record.Entry
+			assert.NilError(t, json.Unmarshal(s.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+		assert.NilError(t, s.Err())
+		assert.NilError(t, f.Close())
+	}
+
+	return entries
+}
+
+func Test_NewRecorder(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		name      string
+		cfg       *config.Record
+		errString string
+	}{
+		{"no config", nil, "record configuration must be provided"},
+		{"no dir", &config.Record{}, "dir must be specified"},
+		{"negative max file size", &config.Record{Dir: t.TempDir(), MaxFileSize: -1}, "maxFileSize must not be negative"},
+		{"negative max files", &config.Record{Dir: t.TempDir(), MaxFiles: -1}, "maxFiles must not be negative"},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			_, err := record.NewRecorder(test.cfg, &countingProcessor{}, logger.Sugar())
+			assert.ErrorContains(t, err, test.errString)
+		})
+	}
+}
+
+func Test_Recorder(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	vmEvent := &types.VmPoweredOnEvent{
+		VmEvent: types.VmEvent{
+			Event: types.Event{
+				Key:         42,
+				CreatedTime: time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC),
+				UserName:    "VSPHERE.LOCAL\\Administrator",
+				Vm: &types.VmEventArgument{
+					EntityEventArgument: types.EntityEventArgument{Name: "Test VM"},
+					Vm:                  types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-42"},
+				},
+				FullFormattedMessage: "Test VM on esx-01 is powered on",
+			},
+		},
+	}
+
+	eventEx := &types.EventEx{
+		Event: types.Event{
+			Key:         43,
+			CreatedTime: time.Date(2021, time.September, 1, 12, 0, 1, 0, time.UTC),
+		},
+		EventTypeId: "com.vmware.vc.HA.DasHostFailedEvent",
+		Arguments: []types.KeyAnyValue{
+			{Key: "hostName", Value: "esx-01"},
+		},
+	}
+
+	attrs := events.WithAttributes(map[string]string{"vsphereapiversion": "7.0.2.0"})
+
+	vmCE, err := events.NewFromVSphere(vmEvent, "https://vcenter-01/sdk", attrs)
+	assert.NilError(t, err)
+	exCE, err := events.NewFromVSphere(eventEx, "https://vcenter-01/sdk", attrs)
+	assert.NilError(t, err)
+
+	// interface-typed fields cannot be decoded from JSON
+	faultEvent := &types.VmFailedToPowerOnEvent{
+		VmEvent: types.VmEvent{
+			Event: types.Event{
+				Key:         44,
+				CreatedTime: time.Date(2021, time.September, 1, 12, 0, 2, 0, time.UTC),
+			},
+		},
+		Reason: types.LocalizedMethodFault{Fault: &types.InvalidState{}},
+	}
+
+	faultCE, err := events.NewFromVSphere(faultEvent, "https://vcenter-01/sdk", attrs)
+	assert.NilError(t, err)
+
+	syslogCE, err := events.NewFromSyslog(events.SyslogEventInfo{Appname: "Hostd", Time: time.Now()}, map[string]string{"message": "test"}, "syslog://10.0.0.1")
+	assert.NilError(t, err)
+
+	t.Run("records events and reproduces the conversion of vSphere events", func(t *testing.T) {
+		dir := t.TempDir()
+		next := &countingProcessor{}
+
+		r, err := record.NewRecorder(&config.Record{Dir: dir}, next, logger.Sugar())
+		assert.NilError(t, err)
+
+		assert.NilError(t, r.Process(events.WithVSphereEvent(ctx, vmEvent), *vmCE))
+		assert.NilError(t, r.Process(events.WithVSphereEvent(ctx, eventEx), *exCE))
+		assert.NilError(t, r.Process(ctx, *syslogCE))
+		assert.NilError(t, r.Shutdown(ctx))
+		assert.Equal(t, next.invocations, 3)
+		assert.Equal(t, next.shutdown, true)
+
+		entries := readEntries(t, dir)
+		assert.Equal(t, len(entries), 3)
+
+		var typeName struct {
+			TypeName string `json:"_typeName"`
+		}
+		assert.NilError(t, json.Unmarshal(entries[0].VSphereEvent, &typeName))
+		assert.Equal(t, typeName.TypeName, "VmPoweredOnEvent")
+		assert.NilError(t, json.Unmarshal(entries[1].VSphereEvent, &typeName))
+		assert.Equal(t, typeName.TypeName, "EventEx")
+		assert.Assert(t, entries[2].VSphereEvent == nil)
+
+		for i, want := range []*ce.Event{vmCE, exCE, syslogCE} {
+			got, _, err := entries[i].Event()
+			assert.NilError(t, err)
+			assert.Equal(t, got.String(), want.String())
+		}
+
+		be, err := record.UnmarshalVSphereEvent(entries[0].VSphereEvent)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, be, types.BaseEvent(vmEvent))
+	})
+
+	t.Run("warns if vSphere event cannot be recorded", func(t *testing.T) {
+		dir := t.TempDir()
+		core, logs := observer.New(zap.WarnLevel)
+
+		r, err := record.NewRecorder(&config.Record{Dir: dir}, &countingProcessor{}, zap.New(core).Sugar())
+		assert.NilError(t, err)
+
+		assert.NilError(t, r.Process(events.WithVSphereEvent(ctx, faultEvent), *faultCE))
+		assert.NilError(t, r.Shutdown(ctx))
+
+		entries := readEntries(t, dir)
+		assert.Equal(t, len(entries), 1)
+		assert.Assert(t, entries[0].VSphereEvent == nil)
+		assert.Equal(t, entries[0].CloudEvent.ID(), faultCE.ID())
+		assert.Equal(t, logs.FilterMessage("recording event without vSphere event").Len(), 1)
+	})
+
+	t.Run("rotates recording files", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// one entry per file, keeps two files
+		cfg := config.Record{
+			Dir:         dir,
+			MaxFileSize: 1,
+			MaxFiles:    2,
+		}
+
+		r, err := record.NewRecorder(&cfg, &countingProcessor{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		for i := 0; i < 5; i++ {
+			assert.NilError(t, r.Process(ctx, *vmCE))
+		}
+		assert.NilError(t, r.Shutdown(ctx))
+
+		files, err := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(files), 2)
+		assert.Equal(t, len(readEntries(t, dir)), 2, "oldest files are deleted")
+	})
+
+	t.Run("does not record after shutdown", func(t *testing.T) {
+		dir := t.TempDir()
+		next := &countingProcessor{}
+
+		r, err := record.NewRecorder(&config.Record{Dir: dir}, next, logger.Sugar())
+		assert.NilError(t, err)
+
+		assert.NilError(t, r.Shutdown(ctx))
+		assert.NilError(t, r.Process(ctx, *vmCE))
+		assert.Equal(t, next.invocations, 1)
+		assert.Equal(t, len(readEntries(t, dir)), 0)
+	})
+}
+
+type countingProcessor struct {
+	invocations int
+	shutdown    bool
+}
+
+func (c *countingProcessor) Process(ctx context.Context, e ce.Event) error {
+	c.invocations++
+	return nil
+}
+
+func (c *countingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (c *countingProcessor) Shutdown(ctx context.Context) error {
+	c.shutdown = true
+	return nil
+}
@@ -0,0 +1,100 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const (
+	// TypeNameKey is the JSON attribute containing the concrete govmomi type
+	// name of a vSphere event, e.g. VmPoweredOnEvent (vSphere JSON API type
+	// discriminator)
+	TypeNameKey = "_typeName"
+)
+
+// UnmarshalVSphereEvent decodes a JSON encoded vSphere event into the type
+// named in the _typeName attribute
+func UnmarshalVSphereEvent(b []byte) (types.BaseEvent, error) {
+	var probe struct {
+		TypeName string `json:"_typeName"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, err
+	}
+
+	if probe.TypeName == "" {
+		return nil, errors.Errorf("%s must be specified", TypeNameKey)
+	}
+
+	kind, ok := types.TypeFunc()(probe.TypeName)
+	if !ok {
+		return nil, errors.Errorf("unknown vSphere type %q", probe.TypeName)
+	}
+
+	v := reflect.New(kind).Interface()
+	event, ok := v.(types.BaseEvent)
+	if !ok {
+		return nil, errors.Errorf("vSphere type %q is not an event", probe.TypeName)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, errors.Wrapf(err, "decode vSphere event %q", probe.TypeName)
+	}
+
+	return event, nil
+}
+
+// vSphereEvent returns the JSON encoded vSphere event including its govmomi
+// type name in the _typeName attribute. An error is returned if the encoded
+// event cannot be decoded again, e.g. events with interface-typed fields such
+// as faults.
+func vSphereEvent(event types.BaseEvent) ([]byte, error) {
+	t := reflect.TypeOf(event)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encode vSphere event %q", t.Name())
+	}
+
+	raw, err := withTypeName(t.Name(), b)
+	if err != nil {
+		return nil, err
+	}
+
+	// only record data which can be decoded again
+	if _, err = UnmarshalVSphereEvent(raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// withTypeName adds the _typeName attribute to the given JSON object
+func withTypeName(name string, b []byte) ([]byte, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || b[0] != '{' {
+		return nil, errors.New("vSphere event must be a JSON object")
+	}
+
+	typeName, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"` + TypeNameKey + `":`)
+	buf.Write(typeName)
+	if rest := bytes.TrimSpace(b[1:]); len(rest) > 0 && rest[0] != '}' {
+		buf.WriteByte(',')
+	}
+	buf.Write(b[1:])
+
+	return buf.Bytes(), nil
+}
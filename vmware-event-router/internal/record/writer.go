@@ -0,0 +1,142 @@
+package record
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	filePrefix = "events-"
+	fileSuffix = ".jsonl"
+
+	// file name timestamp format, sorts lexically in chronological order
+	fileTimeFormat = "20060102T150405.000000000Z"
+)
+
+// rotatingWriter writes lines to JSONL files in a directory. A new file is
+// started when the current file would exceed the maximum size and the oldest
+// files are deleted when more than the maximum number of files exist.
+type rotatingWriter struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+	now      func() time.Time
+
+	file    *os.File
+	size    int64
+	created time.Time // creation time of the current file
+}
+
+// newRotatingWriter returns a writer for the given directory, creating it if
+// it does not exist
+func newRotatingWriter(dir string, maxSize int64, maxFiles int) (*rotatingWriter, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, errors.Wrap(err, "create recording directory")
+	}
+
+	return &rotatingWriter{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		now:      time.Now,
+	}, nil
+}
+
+// writeLine writes the given line followed by a newline. A line is never split
+// across files, i.e. a file can exceed the maximum size if a single line is
+// larger than the maximum size.
+func (w *rotatingWriter) writeLine(line []byte) error {
+	n := int64(len(line)) + 1
+	if w.file == nil || (w.size > 0 && w.size+n > w.maxSize) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	written, err := w.file.Write(append(line, '\n'))
+	w.size += int64(written)
+	return err
+}
+
+// rotate closes the current file, starts a new file and deletes the oldest
+// files exceeding the maximum number of files
+func (w *rotatingWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	var (
+		f   *os.File
+		err error
+	)
+
+	t := w.now().UTC()
+	if !t.After(w.created) {
+		t = w.created.Add(time.Nanosecond)
+	}
+
+	// bump the timestamp on name collisions to retain the order of files
+	for ; ; t = t.Add(time.Nanosecond) {
+		name := filepath.Join(w.dir, filePrefix+t.Format(fileTimeFormat)+fileSuffix)
+		f, err = os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "create recording file")
+	}
+
+	w.file = f
+	w.size = 0
+	w.created = t
+
+	return w.prune()
+}
+
+// prune deletes the oldest recording files exceeding the maximum number of
+// files
+func (w *rotatingWriter) prune() error {
+	entries, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return errors.Wrap(err, "list recording files")
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.Mode().IsRegular() && strings.HasPrefix(e.Name(), filePrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			files = append(files, e.Name())
+		}
+	}
+
+	if len(files) <= w.maxFiles {
+		return nil
+	}
+
+	sort.Strings(files)
+	for _, f := range files[:len(files)-w.maxFiles] {
+		if err = os.Remove(filepath.Join(w.dir, f)); err != nil {
+			return errors.Wrap(err, "delete recording file")
+		}
+	}
+
+	return nil
+}
+
+// close closes the current file
+func (w *rotatingWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
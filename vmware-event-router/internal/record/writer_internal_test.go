@@ -0,0 +1,49 @@
+//go:build unit
+// +build unit
+
+package record
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_rotatingWriter(t *testing.T) {
+	dir := t.TempDir()
+
+	// unrelated files are never deleted
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "events.txt"), nil, 0600))
+
+	w, err := newRotatingWriter(dir, 10, 2)
+	assert.NilError(t, err)
+
+	// constant clock to verify file name collisions
+	now := time.Date(2021, time.September, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	for _, line := range []string{"aaaa", "bbbb", "cccc", "dddddddddddddddd", "e"} {
+		assert.NilError(t, w.writeLine([]byte(line)))
+	}
+	assert.NilError(t, w.close())
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+
+	var got []string
+	for _, f := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		assert.NilError(t, err)
+		got = append(got, f.Name()+": "+strings.TrimSpace(string(b)))
+	}
+
+	assert.DeepEqual(t, got, []string{
+		"events-20210901T120000.000000002Z.jsonl: dddddddddddddddd",
+		"events-20210901T120000.000000003Z.jsonl: e",
+		"events.txt: ",
+	})
+}
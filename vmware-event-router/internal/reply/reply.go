@@ -0,0 +1,155 @@
+package reply
+
+import (
+	"context"
+	"sync"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/processor"
+)
+
+const (
+	// HopCountExtension is the CloudEvent extension attribute counting how
+	// often events of a reply chain have been fed back into the router
+	HopCountExtension = "hopcount"
+
+	defaultMaxHops   = 3
+	defaultQueueSize = 100
+)
+
+// verify that Reply implements the processor reply handler
+var _ processor.ReplyHandler = (*Router)(nil).Reply
+
+// Router feeds reply events returned by the sinks of the event processor back
+// into the event processor. Events of a reply chain are counted with the hop
+// count extension attribute and dropped when the maximum hop count is
+// exceeded to prevent loops.
+type Router struct {
+	maxHops int
+	queue   chan ce.Event
+	logger.Logger
+
+	mu   sync.RWMutex
+	next processor.Processor
+}
+
+// NewRouter returns a reply router for the given configuration. The processor
+// reply events are passed to is set with Forward.
+func NewRouter(cfg *config.Replies, log logger.Logger) (*Router, error) {
+	if cfg == nil {
+		return nil, errors.New("replies configuration must be provided")
+	}
+
+	maxHops := cfg.MaxHops
+	switch {
+	case maxHops == 0:
+		maxHops = defaultMaxHops
+	case maxHops < 0:
+		return nil, errors.Errorf("invalid replies config: maxHops must not be negative: %d", cfg.MaxHops)
+	}
+
+	queueSize := cfg.QueueSize
+	switch {
+	case queueSize == 0:
+		queueSize = defaultQueueSize
+	case queueSize < 0:
+		return nil, errors.Errorf("invalid replies config: queueSize must not be negative: %d", cfg.QueueSize)
+	}
+
+	r := Router{
+		maxHops: maxHops,
+		queue:   make(chan ce.Event, queueSize),
+		Logger:  log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		r.Logger = zapSugared.Named("[REPLY]")
+	}
+
+	return &r, nil
+}
+
+// Forward sets the processor reply events are passed to, e.g. the event
+// processor the reply handler is configured for
+func (r *Router) Forward(next processor.Processor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next = next
+}
+
+// MaxHops returns the maximum number of times events of a reply chain are fed
+// back
+func (r *Router) MaxHops() int {
+	return r.maxHops
+}
+
+// Reply queues the reply event returned for the given event. The reply is
+// dropped if it exceeds the maximum hop count or the queue is full.
+func (r *Router) Reply(_ context.Context, event, reply ce.Event) {
+	hops := HopCount(event) + 1
+	if h := HopCount(reply); h > hops {
+		hops = h
+	}
+
+	if hops > r.maxHops {
+		r.Warnw("dropping reply event: maximum hop count exceeded", "eventID", event.ID(), "replyID", reply.ID(), "hops", hops, "maxHops", r.maxHops)
+		return
+	}
+	reply = reply.Clone()
+	reply.SetExtension(HopCountExtension, hops)
+
+	select {
+	case r.queue <- reply:
+		r.Debugw("queued reply event", "eventID", event.ID(), "replyID", reply.ID(), "hops", hops)
+	default:
+		r.Warnw("dropping reply event: queue is full", "eventID", event.ID(), "replyID", reply.ID(), "queueSize", cap(r.queue))
+	}
+}
+
+// Run passes queued reply events to the processor until the given context is
+// cancelled
+func (r *Router) Run(ctx context.Context) error {
+	r.mu.RLock()
+	next := r.next
+	r.mu.RUnlock()
+
+	if next == nil {
+		return errors.New("no processor to forward replies to")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if n := len(r.queue); n > 0 {
+				r.Warnw("dropping queued reply events during shutdown", "count", n)
+			}
+			return nil
+		case reply := <-r.queue:
+			r.Infow("processing reply event", "replyID", reply.ID(), "type", reply.Type(), "source", reply.Source())
+			if err := next.Process(ctx, reply); err != nil {
+				r.Errorw("could not process reply event", "replyID", reply.ID(), "error", err)
+			}
+		}
+	}
+}
+
+// HopCount returns the hop count extension attribute of the given event. Events
+// without (valid) hop count return 0.
+func HopCount(event ce.Event) int {
+	v, ok := event.Extensions()[HopCountExtension]
+	if !ok {
+		return 0
+	}
+
+	hops, err := cetypes.ToInteger(v)
+	if err != nil || hops < 0 {
+		return 0
+	}
+	return int(hops)
+}
@@ -0,0 +1,156 @@
+//go:build unit
+// +build unit
+
+package reply
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+)
+
+// fakeProcessor records processed events and returns err
+type fakeProcessor struct {
+	mu     sync.Mutex
+	events []ce.Event
+	err    error
+}
+
+func (p *fakeProcessor) Process(_ context.Context, e ce.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+	return p.err
+}
+
+func (p *fakeProcessor) processed() []ce.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ce.Event(nil), p.events...)
+}
+
+func (p *fakeProcessor) PushMetrics(context.Context, metrics.Receiver) {}
+
+func (p *fakeProcessor) Shutdown(context.Context) error { return nil }
+
+func newEvent(id string, hops interface{}) ce.Event {
+	e := ce.NewEvent()
+	e.SetID(id)
+	e.SetSource("https://vcenter-01:443/sdk")
+	e.SetType("com.vmware.event.router/event")
+	if hops != nil {
+		e.SetExtension(HopCountExtension, hops)
+	}
+	return e
+}
+
+func TestNewRouter(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	tests := []struct {
+		name      string
+		cfg       *config.Replies
+		errString string
+	}{
+		{"no configuration", nil, "replies configuration must be provided"},
+		{"negative max hops", &config.Replies{MaxHops: -1}, "maxHops must not be negative: -1"},
+		{"negative queue size", &config.Replies{QueueSize: -1}, "queueSize must not be negative: -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouter(tt.cfg, log)
+			assert.ErrorContains(t, err, tt.errString)
+		})
+	}
+
+	r, err := NewRouter(&config.Replies{}, log)
+	assert.NilError(t, err)
+	assert.Equal(t, r.MaxHops(), defaultMaxHops)
+	assert.Equal(t, cap(r.queue), defaultQueueSize)
+}
+
+func TestHopCount(t *testing.T) {
+	tests := []struct {
+		name string
+		hops interface{}
+		want int
+	}{
+		{"no extension", nil, 0},
+		{"integer", 2, 2},
+		{"string from HTTP header", "3", 3},
+		{"invalid string", "many", 0},
+		{"negative", -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, HopCount(newEvent("1", tt.hops)), tt.want)
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	log := zaptest.NewLogger(t).Sugar()
+
+	t.Run("replies are processed with increased hop count", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		r, err := NewRouter(&config.Replies{MaxHops: 2}, log)
+		assert.NilError(t, err)
+
+		proc := fakeProcessor{err: errors.New("processing errors are logged")}
+		r.Forward(&proc)
+
+		done := make(chan error)
+		go func() {
+			done <- r.Run(ctx)
+		}()
+
+		r.Reply(ctx, newEvent("1", nil), newEvent("reply-1", nil))
+		r.Reply(ctx, newEvent("2", 1), newEvent("reply-2", nil))
+		r.Reply(ctx, newEvent("3", 2), newEvent("reply-3", nil))       // exceeds max hops
+		r.Reply(ctx, newEvent("4", nil), newEvent("reply-4", "5"))     // reply exceeds max hops
+		r.Reply(ctx, newEvent("5", "invalid"), newEvent("reply-5", 0)) // invalid hop count
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(proc.processed()) < 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+
+		cancel()
+		assert.NilError(t, <-done)
+
+		processed := proc.processed()
+		assert.Equal(t, len(processed), 3)
+
+		hops := make(map[string]int)
+		for _, e := range processed {
+			hops[e.ID()] = HopCount(e)
+		}
+		assert.DeepEqual(t, hops, map[string]int{"reply-1": 1, "reply-2": 2, "reply-5": 1})
+	})
+
+	t.Run("replies are dropped if queue is full", func(t *testing.T) {
+		r, err := NewRouter(&config.Replies{QueueSize: 1}, log)
+		assert.NilError(t, err)
+
+		r.Reply(context.Background(), newEvent("1", nil), newEvent("reply-1", nil))
+		r.Reply(context.Background(), newEvent("2", nil), newEvent("reply-2", nil))
+		assert.Equal(t, len(r.queue), 1)
+	})
+
+	t.Run("no processor", func(t *testing.T) {
+		r, err := NewRouter(&config.Replies{}, log)
+		assert.NilError(t, err)
+		assert.ErrorContains(t, r.Run(context.Background()), "no processor to forward replies to")
+	})
+}
@@ -0,0 +1,221 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/event"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const (
+	cloneName = "scenario-clone"
+
+	// the simulator does not implement an alarm manager, alarm events refer to
+	// this (non-existing) alarm
+	alarmName = "Virtual machine CPU usage"
+	alarmRef  = "alarm-6"
+)
+
+// step is a single scenario action against the simulator inventory
+type step struct {
+	name string
+	run  func(ctx context.Context, vm *object.VirtualMachine) error
+}
+
+// scenario cycles through steps generating realistic vCenter events, i.e.
+// power operations, reconfiguration, clones and alarm status changes
+type scenario struct {
+	client *govmomi.Client
+	steps  []step
+	next   int
+	count  int
+}
+
+// newScenario returns a scenario using the given simulator client
+func newScenario(client *govmomi.Client) *scenario {
+	sc := scenario{client: client}
+	sc.steps = []step{
+		{name: "power off virtual machine", run: sc.powerOff},
+		{name: "reconfigure virtual machine", run: sc.reconfigure},
+		{name: "power on virtual machine", run: sc.powerOn},
+		{name: "trigger alarm", run: sc.alarm(types.ManagedEntityStatusGreen, types.ManagedEntityStatusRed)},
+		{name: "clone virtual machine", run: sc.clone},
+		{name: "destroy cloned virtual machine", run: sc.destroyClone},
+		{name: "clear alarm", run: sc.alarm(types.ManagedEntityStatusRed, types.ManagedEntityStatusGreen)},
+	}
+	return &sc
+}
+
+// step performs the next scenario step and returns its name
+func (sc *scenario) step(ctx context.Context) (string, error) {
+	s := sc.steps[sc.next]
+	sc.next = (sc.next + 1) % len(sc.steps)
+
+	vm, err := sc.vm(ctx)
+	if err != nil {
+		return s.name, err
+	}
+
+	return s.name, s.run(ctx, vm)
+}
+
+// finder returns an inventory finder scoped to the default datacenter
+func (sc *scenario) finder(ctx context.Context) (*find.Finder, *object.Datacenter, error) {
+	finder := find.NewFinder(sc.client.Client, true)
+	dc, err := finder.DefaultDatacenter(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "find datacenter")
+	}
+
+	finder.SetDatacenter(dc)
+	return finder, dc, nil
+}
+
+// vm returns the virtual machine the scenario operates on, i.e. the first
+// virtual machine of the default datacenter
+func (sc *scenario) vm(ctx context.Context) (*object.VirtualMachine, error) {
+	finder, _, err := sc.finder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vms, err := finder.VirtualMachineList(ctx, "*")
+	if err != nil {
+		return nil, errors.Wrap(err, "find virtual machines")
+	}
+
+	for _, vm := range vms {
+		if vm.Name() != cloneName {
+			return vm, nil
+		}
+	}
+
+	return nil, errors.New("no virtual machine found")
+}
+
+func (sc *scenario) powerOff(ctx context.Context, vm *object.VirtualMachine) error {
+	return sc.setPowerState(ctx, vm, types.VirtualMachinePowerStatePoweredOff)
+}
+
+func (sc *scenario) powerOn(ctx context.Context, vm *object.VirtualMachine) error {
+	return sc.setPowerState(ctx, vm, types.VirtualMachinePowerStatePoweredOn)
+}
+
+// setPowerState changes the power state of the given virtual machine if it is
+// not already in the desired state
+func (sc *scenario) setPowerState(ctx context.Context, vm *object.VirtualMachine, state types.VirtualMachinePowerState) error {
+	current, err := vm.PowerState(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get power state")
+	}
+
+	if current == state {
+		return nil
+	}
+
+	var task *object.Task
+	if state == types.VirtualMachinePowerStatePoweredOn {
+		task, err = vm.PowerOn(ctx)
+	} else {
+		task, err = vm.PowerOff(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+// reconfigure changes the annotation of the given virtual machine
+func (sc *scenario) reconfigure(ctx context.Context, vm *object.VirtualMachine) error {
+	sc.count++
+	spec := types.VirtualMachineConfigSpec{
+		Annotation: fmt.Sprintf("reconfigured by simulator scenario (%d)", sc.count),
+	}
+
+	task, err := vm.Reconfigure(ctx, spec)
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+// clone clones the given virtual machine into the virtual machine folder of
+// the default datacenter
+func (sc *scenario) clone(ctx context.Context, vm *object.VirtualMachine) error {
+	_, dc, err := sc.finder(ctx)
+	if err != nil {
+		return err
+	}
+
+	folders, err := dc.Folders(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get datacenter folders")
+	}
+
+	task, err := vm.Clone(ctx, folders.VmFolder, cloneName, types.VirtualMachineCloneSpec{})
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+// destroyClone destroys the virtual machine created by clone
+func (sc *scenario) destroyClone(ctx context.Context, _ *object.VirtualMachine) error {
+	finder, _, err := sc.finder(ctx)
+	if err != nil {
+		return err
+	}
+
+	clone, err := finder.VirtualMachine(ctx, cloneName)
+	if err != nil {
+		return errors.Wrap(err, "find cloned virtual machine")
+	}
+
+	task, err := clone.Destroy(ctx)
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+// alarm returns a step posting an alarm status change event for the given
+// virtual machine
+func (sc *scenario) alarm(from, to types.ManagedEntityStatus) func(context.Context, *object.VirtualMachine) error {
+	return func(ctx context.Context, vm *object.VirtualMachine) error {
+		entity := types.ManagedEntityEventArgument{
+			EntityEventArgument: types.EntityEventArgument{Name: vm.Name()},
+			Entity:              vm.Reference(),
+		}
+
+		ev := types.AlarmStatusChangedEvent{
+			AlarmEvent: types.AlarmEvent{
+				Event: types.Event{
+					Vm: &types.VmEventArgument{
+						EntityEventArgument: types.EntityEventArgument{Name: vm.Name()},
+						Vm:                  vm.Reference(),
+					},
+					FullFormattedMessage: fmt.Sprintf("Alarm '%s' on %s changed from %s to %s", alarmName, vm.Name(), from, to),
+				},
+				Alarm: types.AlarmEventArgument{
+					EntityEventArgument: types.EntityEventArgument{Name: alarmName},
+					Alarm:               types.ManagedObjectReference{Type: "Alarm", Value: alarmRef},
+				},
+			},
+			Source: entity,
+			Entity: entity,
+			From:   string(from),
+			To:     string(to),
+		}
+
+		return event.NewManager(sc.client.Client).PostEvent(ctx, &ev)
+	}
+}
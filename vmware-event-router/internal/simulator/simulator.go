@@ -0,0 +1,127 @@
+package simulator
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/simulator"
+	"go.uber.org/zap"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+// Simulator is an in-process vCenter simulator (vcsim) running a scenario
+// which generates events, e.g. for demos and local development. Only one
+// simulator can run per process.
+type Simulator struct {
+	model  *simulator.Model
+	server *simulator.Server
+	logger.Logger
+}
+
+// New starts a vCenter simulator using the default vCenter (VPX) inventory
+// model, i.e. one datacenter with a cluster, hosts and virtual machines
+func New(log logger.Logger) (*Simulator, error) {
+	sim := Simulator{
+		model:  simulator.VPX(),
+		Logger: log,
+	}
+
+	if zapSugared, ok := log.(*zap.SugaredLogger); ok {
+		sim.Logger = zapSugared.Named("[SIMULATOR]")
+	}
+
+	if err := sim.model.Create(); err != nil {
+		return nil, errors.Wrap(err, "create simulator inventory")
+	}
+
+	sim.server = sim.model.Service.NewServer()
+	sim.Infow("started vCenter simulator", "address", sim.address())
+
+	return &sim, nil
+}
+
+// address returns the simulator SDK URL without credentials
+func (s *Simulator) address() string {
+	u := *s.server.URL
+	u.User = nil
+	return u.String()
+}
+
+// ProviderConfig returns a vCenter event provider configuration connecting to
+// the simulator with checkpointing enabled. An empty checkpoint directory uses
+// the vCenter provider default.
+func (s *Simulator) ProviderConfig(checkpointDir string) *config.ProviderConfigVCenter {
+	password, _ := s.server.URL.User.Password()
+
+	return &config.ProviderConfigVCenter{
+		Address:       s.address(),
+		InsecureSSL:   true,
+		Checkpoint:    true,
+		CheckpointDir: checkpointDir,
+		Auth: &config.AuthMethod{
+			Type: config.BasicAuth,
+			BasicAuth: &config.BasicAuthMethod{
+				Username: s.server.URL.User.Username(),
+				Password: password,
+			},
+		},
+	}
+}
+
+// Run runs the scenario performing one step per interval until the given
+// context is cancelled. Failed steps are logged and skipped.
+func (s *Simulator) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.Errorf("scenario interval must be greater than 0: %v", interval)
+	}
+
+	client, err := govmomi.NewClient(ctx, s.clientURL(), true)
+	if err != nil {
+		return errors.Wrap(err, "connect to simulator")
+	}
+	defer func() {
+		_ = client.Logout(context.Background())
+	}()
+
+	sc := newScenario(client)
+	s.Infow("running scenario", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Info("stopping scenario")
+			return ctx.Err()
+		case <-ticker.C:
+			step, err := sc.step(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				s.Warnw("scenario step failed", "step", step, "error", err)
+				continue
+			}
+			s.Debugw("performed scenario step", "step", step)
+		}
+	}
+}
+
+// clientURL returns the simulator SDK URL with credentials
+func (s *Simulator) clientURL() *url.URL {
+	u := *s.server.URL
+	return &u
+}
+
+// Close stops the simulator and removes temporary files created by the
+// inventory model
+func (s *Simulator) Close() {
+	s.server.Close()
+	s.model.Remove()
+}
@@ -0,0 +1,104 @@
+//go:build unit
+// +build unit
+
+package simulator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ce "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
+	"gotest.tools/assert"
+
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/metrics"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/provider/vcenter"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/simulator"
+)
+
+func Test_Simulator(t *testing.T) {
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.InfoLevel))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sim, err := simulator.New(logger.Sugar())
+	assert.NilError(t, err)
+	defer sim.Close()
+
+	t.Run("fails to run scenario with invalid interval", func(t *testing.T) {
+		err := sim.Run(ctx, 0)
+		assert.ErrorContains(t, err, "scenario interval must be greater than 0")
+	})
+
+	t.Run("streams scenario events with the vCenter provider", func(t *testing.T) {
+		streamCtx, streamCancel := context.WithCancel(ctx)
+		defer streamCancel()
+
+		cfg := sim.ProviderConfig(t.TempDir())
+		assert.Equal(t, cfg.Checkpoint, true)
+		assert.Equal(t, cfg.Auth.BasicAuth.Username, "user")
+
+		vc, err := vcenter.NewEventStream(streamCtx, cfg, metricsStub{}, logger.Sugar())
+		assert.NilError(t, err)
+
+		proc := &recordingProcessor{events: make(chan ce.Event, 100)}
+
+		eg, egCtx := errgroup.WithContext(streamCtx)
+		eg.Go(func() error {
+			return vc.Stream(egCtx, proc)
+		})
+		eg.Go(func() error {
+			return sim.Run(egCtx, 50*time.Millisecond)
+		})
+
+		want := map[string]bool{
+			"VmPoweredOffEvent":       false,
+			"VmReconfiguredEvent":     false,
+			"VmPoweredOnEvent":        false,
+			"AlarmStatusChangedEvent": false,
+			"VmClonedEvent":           false,
+			"VmRemovedEvent":          false,
+		}
+
+		for missing := len(want); missing > 0; {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for scenario events: %v", want)
+			case e := <-proc.events:
+				if seen, ok := want[e.Subject()]; ok && !seen {
+					want[e.Subject()] = true
+					missing--
+				}
+			}
+		}
+
+		streamCancel()
+		assert.Equal(t, eg.Wait(), context.Canceled)
+	})
+}
+
+type metricsStub struct{}
+
+func (m metricsStub) Receive(stats *metrics.EventStats) {}
+
+type recordingProcessor struct {
+	events chan ce.Event
+}
+
+func (r *recordingProcessor) Process(ctx context.Context, e ce.Event) error {
+	select {
+	case r.events <- e:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (r *recordingProcessor) PushMetrics(ctx context.Context, ms metrics.Receiver) {}
+
+func (r *recordingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,229 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+	"github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/logger"
+)
+
+// NewTLSConfig returns a server TLS configuration for the given settings. The
+// certificate, key and client CA files are checked for changes on every TLS
+// handshake and reloaded if modified. If reloading fails, the previously loaded
+// files continue to be used until the files change again. If a client CA file
+// is set, clients must present a certificate signed by one of the CAs (mutual
+// TLS).
+func NewTLSConfig(cfg *config.TLSConfig, log logger.Logger) (*tls.Config, error) {
+	if cfg == nil {
+		return nil, errors.New("no TLS configuration specified")
+	}
+
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("TLS certificate and key file must be specified")
+	}
+
+	minVersion, err := tlsVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r := certReloader{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+		caFile:   cfg.ClientCAFile,
+		logger:   log,
+	}
+
+	// initial load must succeed
+	if err = r.reload(); err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion: minVersion,
+		NextProtos: []string{"http/1.1"},
+	}
+
+	base.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _ := r.current()
+		return cert, nil
+	}
+
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		if err := r.reloadIfModified(); err != nil {
+			r.logger.Errorw("could not reload TLS files, using previous configuration", "error", err)
+		}
+
+		_, pool := r.current()
+		c := base.Clone()
+		c.GetConfigForClient = nil
+
+		if pool != nil {
+			c.ClientCAs = pool
+			c.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		return c, nil
+	}
+
+	return base, nil
+}
+
+// tlsVersion returns the crypto/tls version for the given configuration value
+func tlsVersion(v config.TLSVersion) (uint16, error) {
+	switch v {
+	case config.TLSVersion10:
+		return tls.VersionTLS10, nil
+	case config.TLSVersion11:
+		return tls.VersionTLS11, nil
+	case "", config.TLSVersion12:
+		return tls.VersionTLS12, nil
+	case config.TLSVersion13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, errors.Errorf("unsupported minimum TLS version: %q", v)
+	}
+}
+
+// certReloader holds the currently loaded certificate and client CAs and
+// reloads them when the underlying files change
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	logger   logger.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time // file modification times at last reload attempt
+}
+
+// current returns the currently loaded certificate and client CA pool
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// reloadIfModified reloads all files if any of them changed since the last
+// reload attempt. A failed reload is not retried until the files change again
+// so an invalid file is reported only once.
+func (r *certReloader) reloadIfModified() error {
+	modTime, statErr := r.stat()
+
+	r.mu.Lock()
+	modified := !equalModTimes(modTime, r.modTime)
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	if !modified {
+		return nil
+	}
+
+	if statErr != nil {
+		return statErr
+	}
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	r.logger.Infow("reloaded TLS configuration", "certFile", r.certFile, "clientCAFile", r.caFile)
+	return nil
+}
+
+// reload loads the certificate, key and client CA files
+func (r *certReloader) reload() error {
+	modTime, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	return r.load()
+}
+
+// stat returns the modification times of all files. Files which cannot be
+// accessed have a zero modification time and the first error is returned.
+func (r *certReloader) stat() (map[string]time.Time, error) {
+	var statErr error
+
+	modTime := make(map[string]time.Time)
+	for _, f := range r.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			if statErr == nil {
+				statErr = errors.Wrapf(err, "stat file %q", f)
+			}
+			modTime[f] = time.Time{}
+			continue
+		}
+		modTime[f] = fi.ModTime()
+	}
+
+	return modTime, statErr
+}
+
+// load loads the certificate, key and client CA files
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "load TLS certificate and key")
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		b, err := ioutil.ReadFile(r.caFile)
+		if err != nil {
+			return errors.Wrap(err, "read TLS client CA file")
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.Errorf("no valid certificates found in TLS client CA file %q", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.pool = pool
+
+	return nil
+}
+
+// equalModTimes reports whether the modification times of both sets of files
+// are equal
+func equalModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for f, t := range a {
+		if !t.Equal(b[f]) {
+			return false
+		}
+	}
+	return true
+}
+
+// files returns the list of watched files
+func (r *certReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	return files
+}
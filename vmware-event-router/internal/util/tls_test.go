@@ -0,0 +1,317 @@
+//go:build unit
+// +build unit
+
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+	"gotest.tools/assert"
+
+	config "github.com/vmware-samples/vcenter-event-broker-appliance/vmware-event-router/internal/config/v1alpha1"
+)
+
+// testCert is a generated certificate with its private key
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert returns a certificate signed by parent (self-signed if parent is
+// nil)
+func newTestCert(t *testing.T, cn string, parent *testCert) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NilError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.NilError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeFile writes data to the given file and sets its modification time to
+// mtime to reliably trigger a reload
+func writeFile(t *testing.T, path string, data []byte, mtime time.Time) {
+	t.Helper()
+	assert.NilError(t, ioutil.WriteFile(path, data, 0600))
+	assert.NilError(t, os.Chtimes(path, mtime, mtime))
+}
+
+// startTLSServer starts an https test server using the given TLS configuration
+func startTLSServer(t *testing.T, cfg *tls.Config) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = cfg
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// tlsClient returns an http client trusting ca and presenting the optional
+// client certificate
+func tlsClient(ca testCert, client *testCert, maxVersion uint16) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	cfg := &tls.Config{
+		RootCAs:    pool,
+		MaxVersion: maxVersion,
+	}
+
+	if client != nil {
+		cfg.Certificates = []tls.Certificate{{
+			Certificate: [][]byte{client.cert.Raw},
+			PrivateKey:  client.key,
+		}}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: cfg},
+		Timeout:   5 * time.Second,
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", &ca)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	invalidFile := filepath.Join(dir, "invalid.crt")
+
+	now := time.Now()
+	writeFile(t, certFile, server.certPEM, now)
+	writeFile(t, keyFile, server.keyPEM, now)
+	writeFile(t, caFile, ca.certPEM, now)
+	writeFile(t, invalidFile, []byte("invalid"), now)
+
+	tests := []struct {
+		name      string
+		cfg       *config.TLSConfig
+		errString string
+	}{
+		{name: "no configuration", cfg: nil, errString: "no TLS configuration specified"},
+		{name: "no certificate", cfg: &config.TLSConfig{KeyFile: keyFile}, errString: "must be specified"},
+		{name: "certificate does not exist", cfg: &config.TLSConfig{CertFile: filepath.Join(dir, "missing"), KeyFile: keyFile}, errString: "stat file"},
+		{name: "invalid certificate", cfg: &config.TLSConfig{CertFile: invalidFile, KeyFile: keyFile}, errString: "load TLS certificate and key"},
+		{name: "invalid client CA", cfg: &config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: invalidFile}, errString: "no valid certificates found"},
+		{name: "invalid min version", cfg: &config.TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.4"}, errString: "unsupported minimum TLS version"},
+		{name: "valid configuration", cfg: &config.TLSConfig{CertFile: certFile, KeyFile: keyFile}},
+		{name: "valid mutual TLS configuration", cfg: &config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, MinVersion: config.TLSVersion13}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := NewTLSConfig(tt.cfg, zaptest.NewLogger(t).Sugar())
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Assert(t, cfg.GetCertificate != nil)
+		})
+	}
+}
+
+func TestNewTLSConfig_Serving(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", &ca)
+	client := newTestCert(t, "client", &ca)
+	untrusted := newTestCert(t, "untrusted", nil)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	now := time.Now()
+	writeFile(t, certFile, server.certPEM, now)
+	writeFile(t, keyFile, server.keyPEM, now)
+	writeFile(t, caFile, ca.certPEM, now)
+
+	t.Run("enforces minimum TLS version", func(t *testing.T) {
+		cfg, err := NewTLSConfig(&config.TLSConfig{
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			MinVersion: config.TLSVersion13,
+		}, zaptest.NewLogger(t).Sugar())
+		assert.NilError(t, err)
+
+		srv := startTLSServer(t, cfg)
+
+		res, err := tlsClient(ca, nil, 0).Get(srv.URL)
+		assert.NilError(t, err)
+		assert.Equal(t, res.TLS.Version, uint16(tls.VersionTLS13))
+		_ = res.Body.Close()
+
+		_, err = tlsClient(ca, nil, tls.VersionTLS12).Get(srv.URL)
+		assert.ErrorContains(t, err, "protocol version")
+	})
+
+	t.Run("requires valid client certificate with client CA", func(t *testing.T) {
+		cfg, err := NewTLSConfig(&config.TLSConfig{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			ClientCAFile: caFile,
+		}, zaptest.NewLogger(t).Sugar())
+		assert.NilError(t, err)
+
+		srv := startTLSServer(t, cfg)
+
+		res, err := tlsClient(ca, &client, 0).Get(srv.URL)
+		assert.NilError(t, err)
+		assert.Equal(t, res.StatusCode, http.StatusOK)
+		_ = res.Body.Close()
+
+		_, err = tlsClient(ca, nil, 0).Get(srv.URL)
+		assert.Assert(t, err != nil, "expected error without client certificate")
+
+		_, err = tlsClient(ca, &untrusted, 0).Get(srv.URL)
+		assert.Assert(t, err != nil, "expected error with untrusted client certificate")
+	})
+
+	t.Run("reloads certificate on change", func(t *testing.T) {
+		reloadDir := t.TempDir()
+		reloadCert := filepath.Join(reloadDir, "tls.crt")
+		reloadKey := filepath.Join(reloadDir, "tls.key")
+		writeFile(t, reloadCert, server.certPEM, now)
+		writeFile(t, reloadKey, server.keyPEM, now)
+
+		cfg, err := NewTLSConfig(&config.TLSConfig{
+			CertFile: reloadCert,
+			KeyFile:  reloadKey,
+		}, zaptest.NewLogger(t).Sugar())
+		assert.NilError(t, err)
+
+		srv := startTLSServer(t, cfg)
+		c := tlsClient(ca, nil, 0)
+
+		res, err := c.Get(srv.URL)
+		assert.NilError(t, err)
+		assert.Equal(t, res.TLS.PeerCertificates[0].Subject.CommonName, "server")
+		_ = res.Body.Close()
+
+		// invalid files keep the previous certificate
+		later := now.Add(time.Minute)
+		writeFile(t, reloadCert, []byte("invalid"), later)
+		c.CloseIdleConnections()
+
+		res, err = c.Get(srv.URL)
+		assert.NilError(t, err)
+		assert.Equal(t, res.TLS.PeerCertificates[0].Subject.CommonName, "server")
+		_ = res.Body.Close()
+
+		rotated := newTestCert(t, "rotated", &ca)
+		later = later.Add(time.Minute)
+		writeFile(t, reloadCert, rotated.certPEM, later)
+		writeFile(t, reloadKey, rotated.keyPEM, later)
+		c.CloseIdleConnections()
+
+		res, err = c.Get(srv.URL)
+		assert.NilError(t, err)
+		assert.Equal(t, res.TLS.PeerCertificates[0].Subject.CommonName, "rotated")
+		_ = res.Body.Close()
+	})
+}
+
+func Test_certReloaderReloadIfModified(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", &ca)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	now := time.Now()
+	writeFile(t, certFile, server.certPEM, now)
+	writeFile(t, keyFile, server.keyPEM, now)
+
+	r := certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   zaptest.NewLogger(t).Sugar(),
+	}
+	assert.NilError(t, r.reload())
+	assert.NilError(t, r.reloadIfModified())
+
+	commonName := func() string {
+		cert, _ := r.current()
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NilError(t, err)
+		return leaf.Subject.CommonName
+	}
+
+	// failed reloads are not retried until the files change again
+	later := now.Add(time.Minute)
+	writeFile(t, certFile, []byte("invalid"), later)
+	assert.ErrorContains(t, r.reloadIfModified(), "load TLS certificate and key")
+	assert.NilError(t, r.reloadIfModified())
+	assert.Equal(t, commonName(), "server")
+
+	assert.NilError(t, os.Remove(keyFile))
+	assert.ErrorContains(t, r.reloadIfModified(), "stat file")
+	assert.NilError(t, r.reloadIfModified())
+	assert.Equal(t, commonName(), "server")
+
+	rotated := newTestCert(t, "rotated", &ca)
+	later = later.Add(time.Minute)
+	writeFile(t, certFile, rotated.certPEM, later)
+	writeFile(t, keyFile, rotated.keyPEM, later)
+	assert.NilError(t, r.reloadIfModified())
+	assert.Equal(t, commonName(), "rotated")
+}